@@ -6,6 +6,9 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/cors"
@@ -25,9 +28,11 @@ func main() {
 		port = "8080"
 	}
 
-	// Optional password for backend access
+	// Optional password for backend access, plus extra accepted passwords
+	// (with optional expiry) for rotating it without logging everyone out
 	syncPassword := os.Getenv("SYNC_PASSWORD")
-	if syncPassword != "" {
+	extraPasswords := api.ParsePasswordList(splitList(os.Getenv("SYNC_PASSWORDS")))
+	if syncPassword != "" || len(extraPasswords) > 0 {
 		log.Println("Password authentication enabled")
 	} else {
 		log.Println("Warning: No SYNC_PASSWORD set - backend is open to anyone")
@@ -54,11 +59,103 @@ func main() {
 	}
 	defer s.Close()
 
-	// Initialize rate limiter
+	// Optional in-process cache for session lookups
+	if v := os.Getenv("SESSION_CACHE_SIZE"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size < 1 {
+			log.Fatalf("Invalid SESSION_CACHE_SIZE: %q", v)
+		}
+		ttl := time.Minute
+		if v := os.Getenv("SESSION_CACHE_TTL"); v != "" {
+			if ttl, err = time.ParseDuration(v); err != nil {
+				log.Fatalf("Invalid SESSION_CACHE_TTL: %v", err)
+			}
+		}
+		s = store.NewSessionCache(s, size, ttl)
+		log.Printf("Session cache enabled (%d sessions, %s)\n", size, ttl)
+	}
+
+	// Initialize rate limiter, with optional overrides such as
+	// RATE_LIMITS="login=0.5/2,sync=10/20,sync@alice=50/100"
 	rl := api.NewRateLimiter()
+	limits, err := api.ParseRateLimits(splitList(os.Getenv("RATE_LIMITS")))
+	if err != nil {
+		log.Fatalf("Invalid RATE_LIMITS: %v", err)
+	}
+	rl.SetLimits(limits)
 
 	// Initialize handlers with optional password
 	handler := api.NewHandler(s, rl, syncPassword)
+	handler.AcceptPasswords(extraPasswords)
+
+	// Reverse proxies whose Fly-Client-IP / X-Forwarded-For headers identify clients
+	trustedProxies, err := api.ParseCIDRs(splitList(os.Getenv("TRUSTED_PROXIES")))
+	if err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+	handler.TrustProxies(trustedProxies)
+
+	// Optionally refuse workout writes that do not name the version they change
+	if v := os.Getenv("REQUIRE_IF_MATCH"); v != "" {
+		require, err := strconv.ParseBool(v)
+		if err != nil {
+			log.Fatalf("Invalid REQUIRE_IF_MATCH: %v", err)
+		}
+		handler.RequireIfMatch(require)
+	}
+
+	// Optional stateless signed sessions, verified without a database lookup
+	if key := os.Getenv("SESSION_SIGNING_KEY"); key != "" {
+		var ttl time.Duration
+		if v := os.Getenv("SESSION_TTL"); v != "" {
+			if ttl, err = time.ParseDuration(v); err != nil {
+				log.Fatalf("Invalid SESSION_TTL: %v", err)
+			}
+		}
+		if err := handler.EnableSignedSessions(api.SignedSessionConfig{Key: []byte(key), TTL: ttl}); err != nil {
+			log.Fatalf("Invalid SESSION_SIGNING_KEY: %v", err)
+		}
+		log.Println("Signed sessions enabled")
+	}
+
+	// Optional admin API
+	if adminToken := os.Getenv("ADMIN_TOKEN"); adminToken != "" {
+		handler.EnableAdmin(api.AdminConfig{
+			Token:     adminToken,
+			BackupDir: os.Getenv("BACKUP_DIR"),
+		})
+		log.Println("Admin API enabled")
+	}
+
+	// Optional trusted reverse-proxy header authentication
+	if header := os.Getenv("AUTH_PROXY_HEADER"); header != "" {
+		proxies, err := api.ParseCIDRs(splitList(os.Getenv("AUTH_PROXY_CIDRS")))
+		if err != nil {
+			log.Fatalf("Invalid AUTH_PROXY_CIDRS: %v", err)
+		}
+		if len(proxies) == 0 {
+			log.Fatal("AUTH_PROXY_HEADER requires AUTH_PROXY_CIDRS")
+		}
+		handler.EnableProxyAuth(api.ProxyAuthConfig{Header: header, Proxies: proxies})
+		log.Printf("Proxy header authentication enabled (%s)\n", header)
+	}
+
+	// Optional OpenID Connect login
+	if issuer := os.Getenv("OIDC_ISSUER"); issuer != "" {
+		provider, err := api.NewOIDCProvider(api.OIDCConfig{
+			IssuerURL:    issuer,
+			ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+			ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+			ProfileClaim: os.Getenv("OIDC_PROFILE_CLAIM"),
+			ReturnURLs:   splitList(os.Getenv("OIDC_RETURN_URLS")),
+		})
+		if err != nil {
+			log.Fatalf("Failed to initialize OIDC: %v", err)
+		}
+		handler.EnableOIDC(provider)
+		log.Println("OIDC login enabled:", issuer)
+	}
 
 	// Create router
 	r := chi.NewRouter()
@@ -66,7 +163,7 @@ func main() {
 	// CORS middleware
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"*"},
 		ExposedHeaders:   []string{"*"},
 		AllowCredentials: false,
@@ -81,18 +178,105 @@ func main() {
 			r.Post("/test", handler.TestConnection)
 			r.Post("/init", handler.AuthInit)
 			r.Post("/logout", handler.Logout)
+			r.Get("/oidc/login", handler.OIDCLogin)
+			r.Get("/oidc/callback", handler.OIDCCallback)
 		})
 
-		r.Post("/sync", handler.Sync)
+		r.With(handler.RequireScope(
+			api.ScopeWorkoutsRead, api.ScopeWorkoutsWrite,
+			api.ScopeCompletionsRead, api.ScopeCompletionsWrite,
+		)).Post("/sync", handler.Sync)
 		r.Post("/profiles", handler.GetProfiles)
 
 		r.Route("/workouts", func(r chi.Router) {
-			r.Get("/{id}", handler.GetWorkout)
-			r.Delete("/{id}", handler.DeleteWorkout)
+			r.Group(func(r chi.Router) {
+				r.Use(handler.RequireScope(api.ScopeWorkoutsRead))
+				r.Get("/", handler.ListWorkouts)
+				r.Get("/{id}", handler.GetWorkout)
+				r.Get("/{id}/intervals", handler.ListIntervals)
+				r.Get("/{id}/timeline", handler.GetTimeline)
+				r.Get("/{id}/revisions", handler.ListWorkoutRevisions)
+				r.Get("/{id}/revisions/diff", handler.DiffWorkoutRevisions)
+				r.Get("/{id}/revisions/{version}", handler.GetWorkoutRevision)
+			})
+			r.Group(func(r chi.Router) {
+				r.Use(handler.RequireScope(api.ScopeWorkoutsWrite))
+				r.Post("/", handler.CreateWorkout)
+				r.Put("/{id}", handler.UpdateWorkout)
+				r.Patch("/{id}", handler.PatchWorkout)
+				r.Delete("/{id}", handler.DeleteWorkout)
+				r.Post("/{id}/intervals", handler.AddInterval)
+				r.Patch("/{id}/intervals/{intervalId}", handler.PatchInterval)
+				r.Delete("/{id}/intervals/{intervalId}", handler.DeleteInterval)
+				r.Post("/{id}/revisions/{version}/restore", handler.RestoreWorkoutRevision)
+			})
 		})
 
 		r.Route("/completions", func(r chi.Router) {
-			r.Delete("/{id}", handler.DeleteCompletion)
+			r.With(handler.RequireScope(api.ScopeCompletionsRead)).Get("/", handler.ListCompletions)
+			r.With(handler.RequireScope(api.ScopeCompletionsRead)).Get("/{id}", handler.GetCompletion)
+			r.With(handler.RequireScope(api.ScopeCompletionsWrite)).Patch("/{id}", handler.PatchCompletion)
+			r.With(handler.RequireScope(api.ScopeCompletionsWrite)).Delete("/{id}", handler.DeleteCompletion)
+		})
+
+		r.With(handler.RequireScope(api.ScopeCompletionsRead)).Get("/stats/focus", handler.FocusStats)
+		r.With(handler.RequireScope(api.ScopeCompletionsRead)).Get("/reports/time", handler.TimeReport)
+
+		// Projects and tasks completions can be tagged with
+		r.Route("/projects", func(r chi.Router) {
+			r.With(handler.RequireScope(api.ScopeCompletionsRead)).Get("/", handler.ListProjects)
+			r.With(handler.RequireScope(api.ScopeCompletionsRead)).Get("/{id}", handler.GetProject)
+			r.With(handler.RequireScope(api.ScopeCompletionsWrite)).Post("/", handler.CreateProject)
+			r.With(handler.RequireScope(api.ScopeCompletionsWrite)).Patch("/{id}", handler.UpdateProject)
+			r.With(handler.RequireScope(api.ScopeCompletionsWrite)).Delete("/{id}", handler.DeleteProject)
+		})
+		r.Route("/tasks", func(r chi.Router) {
+			r.With(handler.RequireScope(api.ScopeCompletionsRead)).Get("/", handler.ListTasks)
+			r.With(handler.RequireScope(api.ScopeCompletionsRead)).Get("/{id}", handler.GetTask)
+			r.With(handler.RequireScope(api.ScopeCompletionsWrite)).Post("/", handler.CreateTask)
+			r.With(handler.RequireScope(api.ScopeCompletionsWrite)).Patch("/{id}", handler.UpdateTask)
+			r.With(handler.RequireScope(api.ScopeCompletionsWrite)).Delete("/{id}", handler.DeleteTask)
+		})
+
+		r.Route("/trash", func(r chi.Router) {
+			r.With(handler.RequireScope(api.ScopeWorkoutsRead, api.ScopeCompletionsRead)).Get("/", handler.ListTrash)
+			r.With(handler.RequireScope(api.ScopeWorkoutsWrite)).Post("/workouts/{id}/restore", handler.RestoreWorkout)
+			r.With(handler.RequireScope(api.ScopeCompletionsWrite)).Post("/completions/{id}/restore", handler.RestoreCompletion)
+		})
+
+		// Device pairing
+		r.Route("/pairing", func(r chi.Router) {
+			r.Post("/", handler.CreatePairingCode)
+			r.Post("/redeem", handler.RedeemPairingCode)
+		})
+
+		// Administration (ADMIN_TOKEN)
+		r.Route("/admin", func(r chi.Router) {
+			r.Get("/groups", handler.ListGroups)
+			r.Post("/groups", handler.CreateGroup)
+			r.Delete("/groups/{id}", handler.DeleteGroup)
+
+			r.Get("/passwords", handler.AdminListPasswords)
+			r.Post("/passwords/rotate", handler.AdminRotatePassword)
+			r.Delete("/passwords/{id}", handler.AdminRevokePassword)
+
+			r.Get("/profiles", handler.AdminListProfiles)
+			r.Get("/sessions", handler.AdminListSessions)
+			r.Delete("/sessions", handler.AdminRevokeProfileSessions)
+			r.Delete("/sessions/{id}", handler.AdminRevokeSession)
+			r.Post("/identities", handler.AdminLinkIdentity)
+			r.Get("/storage", handler.AdminStorage)
+			r.Get("/metrics", handler.AdminMetrics)
+			r.Post("/maintenance/purge", handler.AdminPurge)
+			r.Post("/maintenance/backup", handler.AdminBackup)
+			r.Get("/audit", handler.AdminAuditLog)
+		})
+
+		// Personal API tokens (session only)
+		r.Route("/tokens", func(r chi.Router) {
+			r.Get("/", handler.ListAPITokens)
+			r.Post("/", handler.CreateAPIToken)
+			r.Delete("/{id}", handler.DeleteAPIToken)
 		})
 	})
 
@@ -104,3 +288,14 @@ func main() {
 		log.Fatalf("Server error: %v", err)
 	}
 }
+
+// splitList parses a comma-separated environment value
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,396 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"intervals-sync/internal/models"
+	"intervals-sync/internal/store"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AdminConfig configures the /api/admin routes
+type AdminConfig struct {
+	Token     string // bearer token, separate from SYNC_PASSWORD
+	BackupDir string // directory for database backups
+}
+
+// adminSession is a session as shown to administrators, identified by a
+// fingerprint so the token itself is never exposed
+type adminSession struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// EnableAdmin turns on the /api/admin routes
+func (h *Handler) EnableAdmin(cfg AdminConfig) {
+	if cfg.Token != "" {
+		h.adminTokenHash = hashPassphrase(cfg.Token)
+	}
+	h.backupDir = cfg.BackupDir
+	if h.backupDir == "" {
+		h.backupDir = "./backups"
+	}
+}
+
+// requireAdmin checks the admin token, writing an error response and
+// returning false if the request is not authorized
+func (h *Handler) requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if h.adminTokenHash == "" {
+		writeJSON(w, http.StatusNotFound, models.ErrorResponse{Error: "Admin API is not enabled"})
+		return false
+	}
+
+	// Rate limit admin requests to slow down token guessing
+	if !h.rateLimit(w, r, "admin", "", "Too many attempts") {
+		return false
+	}
+
+	token := extractToken(r)
+	if token == "" || subtle.ConstantTimeCompare([]byte(hashPassphrase(token)), []byte(h.adminTokenHash)) != 1 {
+		h.audit(r, "auth_failed", r.URL.Path, "")
+		writeJSON(w, http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid admin token"})
+		return false
+	}
+	return true
+}
+
+// audit records an admin action; failures are logged but never block the action
+func (h *Handler) audit(r *http.Request, action string, target string, detail string) {
+	entry := models.AuditEntry{
+		Action:     action,
+		Target:     target,
+		Detail:     detail,
+		RemoteAddr: h.clientIP(r),
+	}
+	if err := h.store.AddAuditEntry(&entry); err != nil {
+		log.Printf("Failed to write audit entry %s: %v\n", action, err)
+	}
+}
+
+// sessionFingerprint identifies a session without revealing its token
+func sessionFingerprint(token string) string {
+	return hashPassphrase(token)[:16]
+}
+
+// AdminListProfiles handles GET /api/admin/profiles
+// Returns every profile with data counts and last sync time
+func (h *Handler) AdminListProfiles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.requireAdmin(w, r) {
+		return
+	}
+
+	profiles, err := h.store.ListProfileSummaries()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch profiles"})
+		return
+	}
+	h.audit(r, "list_profiles", "", "")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"profiles": profiles,
+	})
+}
+
+// AdminListSessions handles GET /api/admin/sessions
+// Optional ?user_id= restricts the list to one profile
+func (h *Handler) AdminListSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.requireAdmin(w, r) {
+		return
+	}
+
+	userID := r.URL.Query().Get("user_id")
+	sessions, err := h.store.ListSessions(userID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch sessions"})
+		return
+	}
+	h.audit(r, "list_sessions", userID, "")
+
+	result := make([]adminSession, 0, len(sessions))
+	for _, s := range sessions {
+		result = append(result, adminSession{
+			ID:        sessionFingerprint(s.Token),
+			UserID:    s.UserID,
+			CreatedAt: s.CreatedAt,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"sessions": result,
+	})
+}
+
+// identityLink is the body of POST /api/admin/identities
+type identityLink struct {
+	Issuer  string `json:"issuer"` // defaults to the configured OIDC issuer
+	Subject string `json:"subject"`
+	UserID  string `json:"user_id"`
+}
+
+// AdminLinkIdentity handles POST /api/admin/identities
+// Links an external identity to an existing profile, which OIDC login
+// refuses to do on its own
+func (h *Handler) AdminLinkIdentity(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.requireAdmin(w, r) {
+		return
+	}
+
+	var req identityLink
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+	if req.Issuer == "" && h.oidc != nil {
+		req.Issuer = h.oidc.issuer
+	}
+	if req.Issuer == "" || req.Subject == "" || req.UserID == "" {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "issuer, subject and user_id are required"})
+		return
+	}
+
+	if err := h.store.LinkIdentity(req.Issuer, req.Subject, req.UserID); err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to link identity"})
+		return
+	}
+	h.audit(r, "link_identity", req.UserID, req.Issuer+" "+req.Subject)
+
+	writeJSON(w, http.StatusOK, req)
+}
+
+// AdminRevokeSession handles DELETE /api/admin/sessions/:id
+func (h *Handler) AdminRevokeSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.requireAdmin(w, r) {
+		return
+	}
+
+	sessionID := strings.TrimPrefix(r.URL.Path, "/api/admin/sessions/")
+	sessions, err := h.store.ListSessions("")
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch sessions"})
+		return
+	}
+
+	for _, s := range sessions {
+		if sessionFingerprint(s.Token) != sessionID {
+			continue
+		}
+		if err := h.store.DeleteSession(s.Token); err != nil {
+			writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to revoke session"})
+			return
+		}
+		h.audit(r, "revoke_session", s.UserID, sessionID)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("{}"))
+		return
+	}
+
+	writeJSON(w, http.StatusNotFound, models.ErrorResponse{Error: "Session not found"})
+}
+
+// AdminRevokeProfileSessions handles DELETE /api/admin/sessions?user_id=
+// Logs a profile out of every device
+func (h *Handler) AdminRevokeProfileSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.requireAdmin(w, r) {
+		return
+	}
+
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "user_id is required"})
+		return
+	}
+
+	revoked, err := h.store.DeleteUserSessions(userID)
+	if err == nil {
+		err = h.revokeUserSignedSessions(userID)
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to revoke sessions"})
+		return
+	}
+	h.audit(r, "revoke_profile_sessions", userID, fmt.Sprintf("revoked=%d", revoked))
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"revoked": revoked,
+	})
+}
+
+// AdminStorage handles GET /api/admin/storage
+func (h *Handler) AdminStorage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.requireAdmin(w, r) {
+		return
+	}
+
+	usage, err := h.store.GetStorageUsage()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to read storage usage"})
+		return
+	}
+	h.audit(r, "storage_usage", "", "")
+
+	writeJSON(w, http.StatusOK, usage)
+}
+
+// AdminMetrics handles GET /api/admin/metrics
+// Returns session cache hit/miss counters, or null when the cache is disabled
+func (h *Handler) AdminMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.requireAdmin(w, r) {
+		return
+	}
+
+	var sessionCache *store.CacheStats
+	if c, ok := h.store.(*store.SessionCache); ok {
+		stats := c.Stats()
+		sessionCache = &stats
+	}
+	h.audit(r, "view_metrics", "", "")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"session_cache": sessionCache,
+	})
+}
+
+// AdminPurge handles POST /api/admin/maintenance/purge
+// Permanently removes tombstones older than older_than_days (default 30)
+func (h *Handler) AdminPurge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.requireAdmin(w, r) {
+		return
+	}
+
+	req := struct {
+		OlderThanDays *int `json:"older_than_days"`
+	}{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+			return
+		}
+	}
+	days := 30
+	if req.OlderThanDays != nil {
+		days = *req.OlderThanDays
+	}
+	if days < 0 {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "older_than_days must not be negative"})
+		return
+	}
+
+	before := time.Now().AddDate(0, 0, -days)
+	workouts, completions, err := h.store.PurgeDeleted(before)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to purge deleted records"})
+		return
+	}
+	h.audit(r, "purge_deleted", "", fmt.Sprintf("older_than_days=%d workouts=%d completions=%d", days, workouts, completions))
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"workouts_purged":    workouts,
+		"completions_purged": completions,
+	})
+}
+
+// AdminBackup handles POST /api/admin/maintenance/backup
+// Writes a timestamped copy of the database into the backup directory
+func (h *Handler) AdminBackup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.requireAdmin(w, r) {
+		return
+	}
+
+	if err := os.MkdirAll(h.backupDir, 0o700); err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to create backup directory"})
+		return
+	}
+	path := filepath.Join(h.backupDir, "intervals-"+time.Now().UTC().Format("20060102-150405")+".db")
+
+	if err := h.store.Backup(path); err != nil {
+		if errors.Is(err, store.ErrNotSupported) {
+			writeJSON(w, http.StatusNotImplemented, models.ErrorResponse{Error: "Backups are not supported by this database"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to back up database"})
+		return
+	}
+	h.audit(r, "backup", path, "")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"path": path,
+	})
+}
+
+// AdminAuditLog handles GET /api/admin/audit
+// Returns the most recent admin actions (?limit=, default 100)
+func (h *Handler) AdminAuditLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.requireAdmin(w, r) {
+		return
+	}
+
+	limit := 100
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 1000 {
+			writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "limit must be between 1 and 1000"})
+			return
+		}
+		limit = n
+	}
+
+	entries, err := h.store.ListAuditEntries(limit)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch audit log"})
+		return
+	}
+	h.audit(r, "view_audit_log", "", "")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"entries": entries,
+	})
+}
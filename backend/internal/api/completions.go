@@ -0,0 +1,120 @@
+package api
+
+import (
+	"errors"
+	"intervals-sync/internal/models"
+	"intervals-sync/internal/store"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ListCompletions handles GET /api/completions
+// Filters: ?from= and ?to= (RFC3339 or YYYY-MM-DD, on started_at, to is
+// exclusive), ?workout_id=, ?project_id=, ?task_id=, ?completed=true|false.
+// ?order=asc|desc (default desc, newest first), ?limit= and ?cursor= from the
+// previous page's next_cursor.
+func (h *Handler) ListCompletions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	opts := models.CompletionListOptions{
+		WorkoutID: query.Get("workout_id"),
+		ProjectID: query.Get("project_id"),
+		TaskID:    query.Get("task_id"),
+		Cursor:    query.Get("cursor"),
+		Limit:     defaultPageSize,
+	}
+	var err error
+	if opts.From, err = parseTimeParam(query.Get("from")); err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "from must be an RFC3339 timestamp or YYYY-MM-DD date"})
+		return
+	}
+	if opts.To, err = parseTimeParam(query.Get("to")); err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "to must be an RFC3339 timestamp or YYYY-MM-DD date"})
+		return
+	}
+	if v := query.Get("completed"); v != "" {
+		completed, err := strconv.ParseBool(v)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "completed must be true or false"})
+			return
+		}
+		opts.Completed = &completed
+	}
+	switch strings.ToLower(query.Get("order")) {
+	case "", "desc":
+	case "asc":
+		opts.Ascending = true
+	default:
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "order must be asc or desc"})
+		return
+	}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageSize {
+			writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "limit must be between 1 and " + strconv.Itoa(maxPageSize)})
+			return
+		}
+		opts.Limit = n
+	}
+
+	completions, nextCursor, err := h.store.ListCompletions(session.UserID, opts)
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidCursor) {
+			writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Invalid cursor"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch completions"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"completions": completions,
+		"next_cursor": nextCursor,
+	})
+}
+
+// GetCompletion handles GET /api/completions/:id
+func (h *Handler) GetCompletion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	completionID := strings.TrimPrefix(r.URL.Path, "/api/completions/")
+	completion, err := h.store.GetCompletion(session.UserID, completionID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, models.ErrorResponse{Error: "Completion not found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch completion"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, completion)
+}
+
+// parseTimeParam parses an optional time query parameter, returning the zero
+// time if it is empty
+func parseTimeParam(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	return parseTimeOrDate(v)
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"fmt"
+	"intervals-sync/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListCompletions(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+	token := login(t, h, "test-profile")
+
+	// Five completions a day apart, alternating workouts and outcomes
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		h.store.UpsertCompletion(&models.Completion{
+			ID:          fmt.Sprintf("c%d", i),
+			UserID:      "test-profile",
+			WorkoutID:   []string{"w-a", "w-b"}[i%2],
+			WorkoutName: "Workout",
+			Completed:   i != 3,
+			StartedAt:   start.AddDate(0, 0, i),
+		})
+	}
+	h.store.UpsertCompletion(&models.Completion{ID: "other", UserID: "other-profile", WorkoutID: "w-a", StartedAt: start})
+
+	type page struct {
+		Completions []models.Completion `json:"completions"`
+		NextCursor  string              `json:"next_cursor"`
+	}
+	ids := func(p page) string {
+		s := ""
+		for _, c := range p.Completions {
+			s += c.ID + " "
+		}
+		return s
+	}
+
+	// Walk all pages, newest first
+	var all string
+	cursor := ""
+	for pages := 0; pages < 5; pages++ {
+		var p page
+		if code := do(t, h.ListCompletions, http.MethodGet, "/api/completions?limit=2&cursor="+cursor, token, "", &p); code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", code)
+		}
+		all += ids(p)
+		if cursor = p.NextCursor; cursor == "" {
+			break
+		}
+	}
+	if all != "c4 c3 c2 c1 c0 " {
+		t.Errorf("unexpected pagination order: %q", all)
+	}
+
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"order=asc&limit=2", "c0 c1 "},
+		{"from=2026-03-02&to=2026-03-04", "c2 c1 "},
+		{"workout_id=w-b", "c3 c1 "},
+		{"completed=false", "c3 "},
+		{"completed=true&workout_id=w-b", "c1 "},
+	}
+	for _, tt := range tests {
+		var p page
+		do(t, h.ListCompletions, http.MethodGet, "/api/completions?"+tt.query, token, "", &p)
+		if got := ids(p); got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.query, tt.expected, got)
+		}
+	}
+
+	for _, query := range []string{"cursor=%21%21", "from=March", "completed=maybe", "order=up", "limit=0"} {
+		if code := do(t, h.ListCompletions, http.MethodGet, "/api/completions?"+query, token, "", nil); code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, code)
+		}
+	}
+}
+
+func TestGetCompletion(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+	token := login(t, h, "test-profile")
+
+	h.store.UpsertCompletion(&models.Completion{ID: "c1", UserID: "test-profile", WorkoutID: "w", WorkoutName: "Tabata", StartedAt: time.Now()})
+	h.store.UpsertCompletion(&models.Completion{ID: "c2", UserID: "other-profile", WorkoutID: "w", StartedAt: time.Now()})
+
+	var c models.Completion
+	if code := do(t, h.GetCompletion, http.MethodGet, "/api/completions/c1", token, "", &c); code != http.StatusOK || c.WorkoutName != "Tabata" {
+		t.Errorf("expected completion c1, got %d %+v", code, c)
+	}
+	if code := do(t, h.GetCompletion, http.MethodGet, "/api/completions/c2", token, "", nil); code != http.StatusNotFound {
+		t.Errorf("expected 404 for another profile's completion, got %d", code)
+	}
+
+	h.store.DeleteCompletion("test-profile", "c1")
+	if code := do(t, h.GetCompletion, http.MethodGet, "/api/completions/c1", token, "", nil); code != http.StatusNotFound {
+		t.Errorf("expected 404 for a deleted completion, got %d", code)
+	}
+}
+
+func TestCompletionSnapshotFromSync(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+	token := login(t, h, "test-profile")
+
+	var workout models.Workout
+	do(t, h.CreateWorkout, http.MethodPost, "/api/workouts", token, `{"name":"EMOM","rounds":10,"intervals":[{"name":"Go","duration":60}]}`, &workout)
+
+	// Completions synced without a snapshot get the workout as it is now
+	body := `{"completions":[{"id":"c1","workout_id":"` + workout.ID + `","workout_name":"EMOM","started_at":"2026-03-01T09:00:00Z"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/sync", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	h.Sync(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("sync failed: %d %s", w.Code, w.Body.String())
+	}
+
+	// Editing the workout afterwards does not change what was performed
+	do(t, h.PatchWorkout, http.MethodPatch, "/api/workouts/"+workout.ID, token, `{"rounds":5}`, nil)
+
+	var c models.Completion
+	if code := do(t, h.GetCompletion, http.MethodGet, "/api/completions/c1", token, "", &c); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if c.Snapshot == nil || c.Snapshot.Rounds != 10 || len(c.Snapshot.Intervals) != 1 || c.Snapshot.Intervals[0].Name != "Go" {
+		t.Errorf("expected the snapshot taken at sync time, got %+v", c.Snapshot)
+	}
+
+	// Completions stored before snapshots existed aren't given today's workout
+	h.store.UpsertCompletion(&models.Completion{ID: "c2", UserID: "test-profile", WorkoutID: workout.ID, WorkoutName: "EMOM", StartedAt: time.Now()})
+	h.rl = NewRateLimiter()
+	body = `{"completions":[{"id":"c2","workout_id":"` + workout.ID + `","workout_name":"EMOM","elapsed_duration":60}]}`
+	if code := do(t, h.Sync, http.MethodPost, "/api/sync", token, body, nil); code != http.StatusOK {
+		t.Fatalf("sync failed: %d", code)
+	}
+	var old models.Completion
+	do(t, h.GetCompletion, http.MethodGet, "/api/completions/c2", token, "", &old)
+	if old.ElapsedDuration != 60 || old.Snapshot != nil {
+		t.Errorf("expected the update without a snapshot, got %+v", old)
+	}
+}
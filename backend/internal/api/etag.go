@@ -0,0 +1,73 @@
+package api
+
+import (
+	"intervals-sync/internal/models"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// RequireIfMatch makes writes to a workout fail with 428 unless they carry an
+// If-Match header, so clients cannot overwrite changes they have not seen
+func (h *Handler) RequireIfMatch(require bool) {
+	h.requireIfMatch = require
+}
+
+// workoutETag is the entity tag of a workout's current version
+func workoutETag(workout *models.Workout) string {
+	return `"` + strconv.FormatInt(workout.Version, 10) + `"`
+}
+
+// etagListMatches reports whether an If-Match or If-None-Match header lists
+// etag. "*" matches anything. Weak comparison ignores the W/ prefix; strong
+// comparison never matches a weak tag.
+func etagListMatches(header string, etag string, weak bool) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if weak {
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+		if tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified writes the workout's ETag and, if the request's If-None-Match
+// already lists it, a 304. It returns true when the response is complete.
+func notModified(w http.ResponseWriter, r *http.Request, workout *models.Workout) bool {
+	etag := workoutETag(workout)
+	w.Header().Set("ETag", etag)
+	if inm := r.Header.Get("If-None-Match"); inm != "" && etagListMatches(inm, etag, true) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// checkIfMatch checks a write's If-Match header against the current workout.
+// It returns the version the store must still find when writing, or 0 for an
+// unconditional write. On a mismatch it writes a 412 and returns false.
+func (h *Handler) checkIfMatch(w http.ResponseWriter, r *http.Request, workout *models.Workout) (int64, bool) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		if h.requireIfMatch {
+			writeJSON(w, http.StatusPreconditionRequired, models.ErrorResponse{Error: "If-Match header is required"})
+			return 0, false
+		}
+		return 0, true
+	}
+	if strings.TrimSpace(header) == "*" {
+		return 0, true
+	}
+	if !etagListMatches(header, workoutETag(workout), false) {
+		w.Header().Set("ETag", workoutETag(workout))
+		writeJSON(w, http.StatusPreconditionFailed, models.ErrorResponse{Error: "Workout has been modified"})
+		return 0, false
+	}
+	return workout.Version, true
+}
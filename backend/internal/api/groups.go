@@ -0,0 +1,118 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"intervals-sync/internal/models"
+	"intervals-sync/internal/store"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ListGroups handles GET /api/admin/groups
+func (h *Handler) ListGroups(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.requireAdmin(w, r) {
+		return
+	}
+
+	groups, err := h.store.ListGroups()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch groups"})
+		return
+	}
+	h.audit(r, "list_groups", "", "")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"groups": groups,
+	})
+}
+
+// CreateGroup handles POST /api/admin/groups
+func (h *Handler) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.requireAdmin(w, r) {
+		return
+	}
+
+	var req models.GroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || req.Password == "" {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Group name and password are required"})
+		return
+	}
+
+	// Passwords select the group, so they must be unique across the server
+	passwordHash := hashPassphrase(req.Password)
+	if inUse, err := h.isServerPassword(passwordHash); err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to create group"})
+		return
+	} else if inUse {
+		writeJSON(w, http.StatusConflict, models.ErrorResponse{Error: "Password is already in use"})
+		return
+	}
+	if _, err := h.store.GetGroupByPasswordHash(passwordHash); err == nil {
+		writeJSON(w, http.StatusConflict, models.ErrorResponse{Error: "Password is already in use"})
+		return
+	} else if !errors.Is(err, store.ErrNotFound) {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to create group"})
+		return
+	}
+
+	group := models.Group{
+		ID:           uuid.New().String(),
+		Name:         req.Name,
+		PasswordHash: passwordHash,
+	}
+	if err := h.store.CreateGroup(&group); err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to create group"})
+		return
+	}
+	h.audit(r, "create_group", group.ID, group.Name)
+
+	writeJSON(w, http.StatusCreated, group)
+}
+
+// DeleteGroup handles DELETE /api/admin/groups/:id
+// Removes the group, its sessions and tokens, and all of its profiles' data
+func (h *Handler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.requireAdmin(w, r) {
+		return
+	}
+
+	groupID := strings.TrimPrefix(r.URL.Path, "/api/admin/groups/")
+	if err := h.store.DeleteGroup(groupID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, models.ErrorResponse{Error: "Group not found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to delete group"})
+		return
+	}
+	// Signed sessions are not stored with the group, so revoke them separately
+	if err := h.revokeUserSignedSessions(store.GroupUserID(groupID, "")); err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to revoke group sessions"})
+		return
+	}
+	h.audit(r, "delete_group", groupID, "")
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("{}"))
+}
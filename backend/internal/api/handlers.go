@@ -3,9 +3,11 @@ package api
 import (
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"intervals-sync/internal/models"
 	"intervals-sync/internal/store"
+	"net"
 	"net/http"
 	"strings"
 	"time"
@@ -17,20 +19,27 @@ import (
 type Handler struct {
 	store            store.Store
 	rl               *RateLimiter
-	syncPasswordHash string // SHA-256 hash of the password
+	syncPasswords    []models.ServerPassword // configured default-group passwords
+	adminTokenHash   string                  // SHA-256 hash of the admin token
+	backupDir        string
+	oidc             *OIDCProvider
+	proxyAuth        *ProxyAuthConfig
+	signed           *signedSessions
+	trustedProxies   []*net.IPNet
+	requireIfMatch   bool
 }
 
 // NewHandler creates a new handler
 func NewHandler(s store.Store, rl *RateLimiter, syncPassword string) *Handler {
-	var passwordHash string
+	var passwords []models.ServerPassword
 	if syncPassword != "" {
 		// Hash the password for comparison
-		passwordHash = hashPassphrase(syncPassword)
+		passwords = append(passwords, models.ServerPassword{PasswordHash: hashPassphrase(syncPassword)})
 	}
 	return &Handler{
-		store:            s,
-		rl:               rl,
-		syncPasswordHash: passwordHash,
+		store:         s,
+		rl:            rl,
+		syncPasswords: passwords,
 	}
 }
 
@@ -43,9 +52,7 @@ func (h *Handler) TestConnection(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Rate limit test attempts
-	clientIP := r.RemoteAddr
-	if !h.rl.Allow(clientIP, "login") {
-		http.Error(w, "Too many attempts", http.StatusTooManyRequests)
+	if !h.rateLimit(w, r, "login", "", "Too many attempts") {
 		return
 	}
 
@@ -57,25 +64,33 @@ func (h *Handler) TestConnection(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// If no password is set on the server, any password (or empty) works
-	if h.syncPasswordHash == "" {
+	// A trusted proxy has already authenticated the user
+	if user := h.proxyUser(r); user != "" {
 		writeJSON(w, http.StatusOK, map[string]interface{}{
 			"success":           true,
 			"password_required": false,
+			"profile_name":      user,
 		})
 		return
 	}
 
-	// Check if password hash matches
-	if req.PasswordHash != h.syncPasswordHash {
-		writeJSON(w, http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid password"})
+	// Check the password against the server and tenant group passwords.
+	// If no password is set on the server, any password (or empty) works.
+	match, ok := h.verifyPassword(w, r, req.PasswordHash, "")
+	if !ok {
 		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]interface{}{
+	resp := map[string]interface{}{
 		"success":           true,
-		"password_required": true,
-	})
+		"password_required": match.required,
+	}
+	// Warn clients whose password is being rotated out
+	if match.expiresAt != nil {
+		resp["password_expires_at"] = match.expiresAt
+		resp["password_expiring"] = time.Until(*match.expiresAt) < passwordExpiryWarning
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
 
 // AuthInit handles POST /api/auth/init
@@ -87,9 +102,7 @@ func (h *Handler) AuthInit(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Rate limit login attempts
-	clientIP := r.RemoteAddr
-	if !h.rl.Allow(clientIP, "login") {
-		http.Error(w, "Too many login attempts", http.StatusTooManyRequests)
+	if !h.rateLimit(w, r, "login", "", "Too many login attempts") {
 		return
 	}
 
@@ -99,10 +112,17 @@ func (h *Handler) AuthInit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check password hash if required
-	if h.syncPasswordHash != "" && req.PasswordHash != h.syncPasswordHash {
-		writeJSON(w, http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid password"})
-		return
+	// A trusted proxy names the profile and replaces the password check;
+	// otherwise the password selects the tenant group
+	var groupID string
+	if user := h.proxyUser(r); user != "" {
+		req.ProfileName = user
+	} else {
+		match, ok := h.verifyPassword(w, r, req.PasswordHash, req.ProfileName)
+		if !ok {
+			return
+		}
+		groupID = match.groupID
 	}
 
 	// Validate profile name (plaintext, not hashed)
@@ -110,20 +130,19 @@ func (h *Handler) AuthInit(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Profile name is required"})
 		return
 	}
+	if store.IsReservedProfileName(req.ProfileName) {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Invalid profile name"})
+		return
+	}
 
-	// Generate session token: 2x UUID concatenated, hyphens removed
-	token1 := uuid.New().String()
-	token2 := uuid.New().String()
-	sessionToken := strings.ReplaceAll(token1+token2, "-", "")
-
-	// Create session with profile name as user ID
-	session, err := h.store.CreateSession(sessionToken, req.ProfileName)
+	// Create session with the profile name, namespaced by group, as user ID
+	token, err := h.createSession(store.GroupUserID(groupID, req.ProfileName))
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to create session"})
 		return
 	}
 
-	writeJSON(w, http.StatusOK, models.AuthResponse{Token: session.Token})
+	writeJSON(w, http.StatusOK, models.AuthResponse{Token: token, ProfileName: req.ProfileName})
 }
 
 // Logout handles POST /api/auth/logout
@@ -139,7 +158,7 @@ func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.store.DeleteSession(token); err != nil {
+	if err := h.deleteSession(token); err != nil {
 		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to logout"})
 		return
 	}
@@ -156,15 +175,13 @@ func (h *Handler) Sync(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token := extractToken(r)
-	if token == "" {
-		writeJSON(w, http.StatusUnauthorized, models.ErrorResponse{Error: "Missing token"})
+	session, ok := h.authenticate(w, r)
+	if !ok {
 		return
 	}
 
-	session, err := h.store.VerifySession(token)
-	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid token"})
+	// Sync is limited per profile, so devices behind one NAT don't share a budget
+	if !h.rateLimit(w, r, "sync", session.UserID, "Too many sync requests") {
 		return
 	}
 
@@ -174,18 +191,47 @@ func (h *Handler) Sync(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Store client data
+	// Store client data, skipping workouts the server could not expand so
+	// one bad workout doesn't block the rest of the device's data
+	var rejected []models.SyncRejection
 	for _, workout := range payload.Workouts {
+		if workout.DeletedAt == nil {
+			if msg := validateStructure(&workout); msg != "" {
+				rejected = append(rejected, models.SyncRejection{ID: workout.ID, Error: msg})
+				continue
+			}
+		}
 		workout.UserID = session.UserID
-		if err := h.store.UpsertWorkout(&workout); err != nil {
+		changed, err := h.store.UpsertWorkout(&workout)
+		if err != nil {
+			// The ID belongs to another profile's workout
+			if errors.Is(err, store.ErrNotFound) {
+				rejected = append(rejected, models.SyncRejection{ID: workout.ID, Error: "Workout ID is already in use"})
+				continue
+			}
 			writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to save workout"})
 			return
 		}
+		if changed && workout.DeletedAt == nil {
+			h.recordRevision(r, &workout, revisionSourceSync)
+		}
 	}
 
+	// Completions recorded without a snapshot get the workout as it is now;
+	// ones already stored keep what they have
+	snapshots := map[string]*models.WorkoutSnapshot{}
 	for _, completion := range payload.Completions {
 		completion.UserID = session.UserID
+		if completion.Snapshot == nil && completion.WorkoutID != "" {
+			if _, err := h.store.GetCompletion(session.UserID, completion.ID); errors.Is(err, store.ErrNotFound) {
+				completion.Snapshot = h.currentSnapshot(session.UserID, completion.WorkoutID, snapshots)
+			}
+		}
 		if err := h.store.UpsertCompletion(&completion); err != nil {
+			// The ID belongs to another profile's completion
+			if errors.Is(err, store.ErrNotFound) {
+				continue
+			}
 			writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to save completion"})
 			return
 		}
@@ -215,6 +261,7 @@ func (h *Handler) Sync(w http.ResponseWriter, r *http.Request) {
 		LastSyncedAt:  now,
 		Workouts:      workouts,
 		Completions:   completions,
+		Rejected:      rejected,
 	}
 
 	writeJSON(w, http.StatusOK, response)
@@ -227,15 +274,8 @@ func (h *Handler) GetWorkout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token := extractToken(r)
-	if token == "" {
-		writeJSON(w, http.StatusUnauthorized, models.ErrorResponse{Error: "Missing token"})
-		return
-	}
-
-	session, err := h.store.VerifySession(token)
-	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid token"})
+	session, ok := h.authenticate(w, r)
+	if !ok {
 		return
 	}
 
@@ -245,6 +285,9 @@ func (h *Handler) GetWorkout(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusNotFound, models.ErrorResponse{Error: "Workout not found"})
 		return
 	}
+	if notModified(w, r, workout) {
+		return
+	}
 
 	writeJSON(w, http.StatusOK, workout)
 }
@@ -256,20 +299,35 @@ func (h *Handler) DeleteWorkout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token := extractToken(r)
-	if token == "" {
-		writeJSON(w, http.StatusUnauthorized, models.ErrorResponse{Error: "Missing token"})
+	session, ok := h.authenticate(w, r)
+	if !ok {
 		return
 	}
 
-	session, err := h.store.VerifySession(token)
-	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid token"})
-		return
+	workoutID := strings.TrimPrefix(r.URL.Path, "/api/workouts/")
+
+	// Conditional deletes check the version the client last saw
+	var ifVersion int64
+	if r.Header.Get("If-Match") != "" || h.requireIfMatch {
+		workout, err := h.store.GetWorkout(session.UserID, workoutID)
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, models.ErrorResponse{Error: "Workout not found"})
+			return
+		}
+		if ifVersion, ok = h.checkIfMatch(w, r, workout); !ok {
+			return
+		}
 	}
 
-	workoutID := strings.TrimPrefix(r.URL.Path, "/api/workouts/")
-	if err := h.store.DeleteWorkout(session.UserID, workoutID); err != nil {
+	err := h.store.DeleteWorkout(session.UserID, workoutID, ifVersion)
+	switch {
+	case errors.Is(err, store.ErrVersionConflict):
+		writeJSON(w, http.StatusPreconditionFailed, models.ErrorResponse{Error: "Workout has been modified"})
+		return
+	case errors.Is(err, store.ErrNotFound):
+		writeJSON(w, http.StatusNotFound, models.ErrorResponse{Error: "Workout not found"})
+		return
+	case err != nil:
 		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to delete workout"})
 		return
 	}
@@ -285,15 +343,8 @@ func (h *Handler) DeleteCompletion(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token := extractToken(r)
-	if token == "" {
-		writeJSON(w, http.StatusUnauthorized, models.ErrorResponse{Error: "Missing token"})
-		return
-	}
-
-	session, err := h.store.VerifySession(token)
-	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid token"})
+	session, ok := h.authenticate(w, r)
+	if !ok {
 		return
 	}
 
@@ -327,9 +378,7 @@ func (h *Handler) GetProfiles(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Rate limit profile list requests
-	clientIP := r.RemoteAddr
-	if !h.rl.Allow(clientIP, "login") {
-		http.Error(w, "Too many attempts", http.StatusTooManyRequests)
+	if !h.rateLimit(w, r, "login", "", "Too many attempts") {
 		return
 	}
 
@@ -341,18 +390,34 @@ func (h *Handler) GetProfiles(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check password hash if required
-	if h.syncPasswordHash != "" && req.PasswordHash != h.syncPasswordHash {
-		writeJSON(w, http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid password"})
+	// Behind a trusted proxy only the proxy-authenticated profile is visible
+	if user := h.proxyUser(r); user != "" {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"profiles": []string{user},
+		})
+		return
+	}
+
+	// Check password hash if required; it also selects the tenant group
+	match, ok := h.verifyPassword(w, r, req.PasswordHash, "")
+	if !ok {
 		return
 	}
 
-	profiles, err := h.store.GetProfiles()
+	userIDs, err := h.store.GetProfiles()
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch profiles"})
 		return
 	}
 
+	// Only list profiles in the caller's group
+	profiles := []string{}
+	for _, userID := range userIDs {
+		if g, profile := store.SplitGroupUserID(userID); g == match.groupID {
+			profiles = append(profiles, profile)
+		}
+	}
+
 	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"profiles": profiles,
 	})
@@ -360,6 +425,45 @@ func (h *Handler) GetProfiles(w http.ResponseWriter, r *http.Request) {
 
 // Helper functions
 
+// authenticate resolves the caller's session, writing an error response and
+// returning false if the request is not authorized. A session already resolved
+// by RequireScope or asserted by a trusted proxy is used as-is; otherwise only
+// full-access session tokens are accepted, so routes without a scope check
+// never admit personal API tokens.
+func (h *Handler) authenticate(w http.ResponseWriter, r *http.Request) (*models.Session, bool) {
+	if session, ok := r.Context().Value(sessionContextKey).(*models.Session); ok {
+		return session, true
+	}
+	if user := h.proxyUser(r); user != "" {
+		return &models.Session{UserID: user}, true
+	}
+
+	token := extractToken(r)
+	if token == "" {
+		writeJSON(w, http.StatusUnauthorized, models.ErrorResponse{Error: "Missing token"})
+		return nil, false
+	}
+
+	session, err := h.resolveToken(token)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid token"})
+		return nil, false
+	}
+	if len(session.Scopes) > 0 {
+		writeJSON(w, http.StatusForbidden, models.ErrorResponse{Error: "API tokens cannot access this endpoint"})
+		return nil, false
+	}
+
+	return session, true
+}
+
+// generateToken returns a random token: 2x UUID concatenated, hyphens removed
+func generateToken() string {
+	token1 := uuid.New().String()
+	token2 := uuid.New().String()
+	return strings.ReplaceAll(token1+token2, "-", "")
+}
+
 func extractToken(r *http.Request) string {
 	auth := r.Header.Get("Authorization")
 	if auth == "" {
@@ -7,7 +7,9 @@ import (
 	"intervals-sync/internal/store"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 func setupTestHandler(t *testing.T) (*Handler, func()) {
@@ -18,7 +20,7 @@ func setupTestHandler(t *testing.T) (*Handler, func()) {
 	}
 
 	rl := NewRateLimiter()
-	h := NewHandler(s, rl)
+	h := NewHandler(s, rl, "")
 
 	cleanup := func() {
 		s.Close()
@@ -51,8 +53,7 @@ func TestAuthInit(t *testing.T) {
 	h, cleanup := setupTestHandler(t)
 	defer cleanup()
 
-	// Valid passphrase hash (64 char hex)
-	body := `{"passphrase_hash":"abcd1234abcd1234abcd1234abcd1234abcd1234abcd1234abcd1234abcd1234"}`
+	body := `{"profile_name":"test-profile"}`
 	req := httptest.NewRequest(http.MethodPost, "/api/auth/init", bytes.NewBufferString(body))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
@@ -73,12 +74,11 @@ func TestAuthInit(t *testing.T) {
 	}
 }
 
-func TestAuthInitInvalidHash(t *testing.T) {
+func TestAuthInitMissingProfile(t *testing.T) {
 	h, cleanup := setupTestHandler(t)
 	defer cleanup()
 
-	// Invalid passphrase hash (too short)
-	body := `{"passphrase_hash":"tooshort"}`
+	body := `{"password_hash":""}`
 	req := httptest.NewRequest(http.MethodPost, "/api/auth/init", bytes.NewBufferString(body))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
@@ -95,7 +95,7 @@ func TestSyncWithAuth(t *testing.T) {
 	defer cleanup()
 
 	// First, authenticate
-	authBody := `{"passphrase_hash":"abcd1234abcd1234abcd1234abcd1234abcd1234abcd1234abcd1234abcd1234"}`
+	authBody := `{"profile_name":"test-profile"}`
 	authReq := httptest.NewRequest(http.MethodPost, "/api/auth/init", bytes.NewBufferString(authBody))
 	authReq.Header.Set("Content-Type", "application/json")
 	authW := httptest.NewRecorder()
@@ -164,7 +164,7 @@ func TestLogout(t *testing.T) {
 	defer cleanup()
 
 	// First, authenticate
-	authBody := `{"passphrase_hash":"abcd1234abcd1234abcd1234abcd1234abcd1234abcd1234abcd1234abcd1234"}`
+	authBody := `{"profile_name":"test-profile"}`
 	authReq := httptest.NewRequest(http.MethodPost, "/api/auth/init", bytes.NewBufferString(authBody))
 	authReq.Header.Set("Content-Type", "application/json")
 	authW := httptest.NewRecorder()
@@ -231,3 +231,703 @@ func TestExtractToken(t *testing.T) {
 		})
 	}
 }
+
+// login creates a session for a profile and returns its token
+func login(t *testing.T, h *Handler, profile string) string {
+	t.Helper()
+	body := `{"profile_name":"` + profile + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/init", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	h.AuthInit(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("auth failed: %d %s", w.Code, w.Body.String())
+	}
+
+	var resp models.AuthResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return resp.Token
+}
+
+func TestAPITokenScopes(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	session := login(t, h, "test-profile")
+
+	// Create a read-only workouts token
+	body := `{"name":"home automation","scopes":["workouts:read"]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/tokens", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer "+session)
+	w := httptest.NewRecorder()
+	h.CreateAPIToken(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created models.APITokenResponse
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if !strings.HasPrefix(created.Token, apiTokenPrefix) {
+		t.Fatalf("expected token with prefix %q, got %q", apiTokenPrefix, created.Token)
+	}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name     string
+		scopes   []string
+		expected int
+	}{
+		{"Granted scope", []string{ScopeWorkoutsRead}, http.StatusOK},
+		{"Missing scope", []string{ScopeWorkoutsWrite}, http.StatusForbidden},
+		{"Partially granted", []string{ScopeWorkoutsRead, ScopeCompletionsRead}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/workouts/x", nil)
+			req.Header.Set("Authorization", "Bearer "+created.Token)
+			w := httptest.NewRecorder()
+			h.RequireScope(tt.scopes...)(ok).ServeHTTP(w, req)
+			if w.Code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+
+	// API tokens cannot reach handlers that are not behind RequireScope
+	syncReq := httptest.NewRequest(http.MethodPost, "/api/sync", bytes.NewBufferString(`{}`))
+	syncReq.Header.Set("Authorization", "Bearer "+created.Token)
+	syncW := httptest.NewRecorder()
+	h.Sync(syncW, syncReq)
+	if syncW.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 for unscoped handler, got %d", syncW.Code)
+	}
+
+	// Revoke the token
+	delReq := httptest.NewRequest(http.MethodDelete, "/api/tokens/"+created.ID, nil)
+	delReq.Header.Set("Authorization", "Bearer "+session)
+	delW := httptest.NewRecorder()
+	h.DeleteAPIToken(delW, delReq)
+	if delW.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", delW.Code, delW.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/workouts/x", nil)
+	req.Header.Set("Authorization", "Bearer "+created.Token)
+	w = httptest.NewRecorder()
+	h.RequireScope(ScopeWorkoutsRead)(ok).ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401 after revoke, got %d", w.Code)
+	}
+}
+
+func TestCreateAPITokenValidation(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	session := login(t, h, "test-profile")
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"Missing name", `{"scopes":["workouts:read"]}`},
+		{"Missing scopes", `{"name":"script"}`},
+		{"Unknown scope", `{"name":"script","scopes":["admin"]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/tokens", bytes.NewBufferString(tt.body))
+			req.Header.Set("Authorization", "Bearer "+session)
+			w := httptest.NewRecorder()
+			h.CreateAPIToken(w, req)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestProxyAuth(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	// httptest requests come from 192.0.2.1
+	proxies, err := ParseCIDRs([]string{"192.0.2.0/24"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.EnableProxyAuth(ProxyAuthConfig{Header: "Remote-User", Proxies: proxies})
+
+	// Trusted proxy header authenticates sync without a token
+	syncReq := httptest.NewRequest(http.MethodPost, "/api/sync", bytes.NewBufferString(`{"last_synced_at":0}`))
+	syncReq.Header.Set("Remote-User", "alice")
+	syncW := httptest.NewRecorder()
+	h.Sync(syncW, syncReq)
+	if syncW.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", syncW.Code, syncW.Body.String())
+	}
+
+	// AuthInit ignores the requested profile and password
+	h.AcceptPasswords([]models.ServerPassword{{PasswordHash: hashPassphrase("secret")}})
+	authReq := httptest.NewRequest(http.MethodPost, "/api/auth/init", bytes.NewBufferString(`{"profile_name":"bob"}`))
+	authReq.Header.Set("Remote-User", "alice")
+	authW := httptest.NewRecorder()
+	h.AuthInit(authW, authReq)
+	if authW.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", authW.Code, authW.Body.String())
+	}
+	var authResp models.AuthResponse
+	json.Unmarshal(authW.Body.Bytes(), &authResp)
+	session, err := h.store.VerifySession(authResp.Token)
+	if err != nil || session.UserID != "alice" {
+		t.Errorf("expected session for 'alice', got %+v (%v)", session, err)
+	}
+
+	// The header is ignored from untrusted peers
+	untrusted := httptest.NewRequest(http.MethodPost, "/api/sync", bytes.NewBufferString(`{"last_synced_at":0}`))
+	untrusted.RemoteAddr = "203.0.113.9:4444"
+	untrusted.Header.Set("Remote-User", "alice")
+	untrustedW := httptest.NewRecorder()
+	h.Sync(untrustedW, untrusted)
+	if untrustedW.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401 from untrusted peer, got %d", untrustedW.Code)
+	}
+}
+
+func TestPairing(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	token := login(t, h, "test-profile")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/pairing", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	h.CreatePairingCode(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var created struct {
+		Code      string `json:"code"`
+		QRPayload string `json:"qr_payload"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if len(created.Code) != pairingCodeDigits {
+		t.Fatalf("expected %d digit code, got %q", pairingCodeDigits, created.Code)
+	}
+	if !strings.Contains(created.QRPayload, created.Code) {
+		t.Errorf("expected QR payload to contain code, got %q", created.QRPayload)
+	}
+
+	redeem := func() *httptest.ResponseRecorder {
+		body := `{"code":"` + created.Code + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/pairing/redeem", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		h.RedeemPairingCode(w, req)
+		return w
+	}
+
+	w = redeem()
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp models.AuthResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.ProfileName != "test-profile" || resp.Token == token {
+		t.Errorf("expected a new session for 'test-profile', got %+v", resp)
+	}
+	if _, err := h.store.VerifySession(resp.Token); err != nil {
+		t.Errorf("expected redeemed session to be valid: %v", err)
+	}
+
+	// Codes are single-use
+	if w := redeem(); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401 on reuse, got %d", w.Code)
+	}
+}
+
+func TestPairingCodeBurnedAfterFailures(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	token := login(t, h, "test-profile")
+	var created struct {
+		Code string `json:"code"`
+	}
+	do(t, h.CreatePairingCode, http.MethodPost, "/api/pairing", token, "", &created)
+
+	wrong := "000000"
+	if created.Code == wrong {
+		wrong = "000001"
+	}
+	for i := 0; i < maxPairingFailures; i++ {
+		h.rl = NewRateLimiter()
+		if code := do(t, h.RedeemPairingCode, http.MethodPost, "/api/pairing/redeem", "", `{"code":"`+wrong+`"}`, nil); code != http.StatusUnauthorized {
+			t.Fatalf("expected 401 for a wrong code, got %d", code)
+		}
+	}
+
+	// The real code was burned by the guesses
+	h.rl = NewRateLimiter()
+	if code := do(t, h.RedeemPairingCode, http.MethodPost, "/api/pairing/redeem", "", `{"code":"`+created.Code+`"}`, nil); code != http.StatusUnauthorized {
+		t.Errorf("expected the code to be burned, got %d", code)
+	}
+}
+
+func TestPairingGroupProfile(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	token, err := h.createSession(store.GroupUserID("gym", "alice"))
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	var created struct {
+		Code string `json:"code"`
+	}
+	do(t, h.CreatePairingCode, http.MethodPost, "/api/pairing", token, "", &created)
+
+	// The paired device gets the display name, not the group-qualified ID
+	var resp models.AuthResponse
+	if code := do(t, h.RedeemPairingCode, http.MethodPost, "/api/pairing/redeem", "", `{"code":"`+created.Code+`"}`, &resp); code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", code)
+	}
+	if resp.ProfileName != "alice" {
+		t.Errorf("expected profile name 'alice', got %q", resp.ProfileName)
+	}
+	session, err := h.store.VerifySession(resp.Token)
+	if err != nil || session.UserID != store.GroupUserID("gym", "alice") {
+		t.Errorf("expected a session in the gym group, got %+v (%v)", session, err)
+	}
+}
+
+func TestSyncRejectsOtherProfilesIDs(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	member, err := h.createSession(store.GroupUserID("gym", "alice"))
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	payload := `{"workouts":[{"id":"w1","name":"Gym","rounds":1}],"completions":[{"id":"c1","workout_id":"w1","workout_name":"Gym","elapsed_duration":30}]}`
+	do(t, h.Sync, http.MethodPost, "/api/sync", member, payload, nil)
+
+	// The default group's alice can't overwrite them by ID
+	h.rl = NewRateLimiter()
+	var resp models.SyncPayload
+	payload = `{"workouts":[{"id":"w1","name":"Taken","rounds":1}],"completions":[{"id":"c1","workout_id":"w1","workout_name":"Taken","elapsed_duration":1}]}`
+	if code := do(t, h.Sync, http.MethodPost, "/api/sync", login(t, h, "alice"), payload, &resp); code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", code)
+	}
+	if len(resp.Rejected) != 1 || resp.Rejected[0].ID != "w1" {
+		t.Errorf("expected w1 to be rejected, got %+v", resp.Rejected)
+	}
+	if len(resp.Workouts) != 0 || len(resp.Completions) != 0 {
+		t.Errorf("expected no data for the default group, got %+v", resp)
+	}
+
+	workout, err := h.store.GetWorkout(store.GroupUserID("gym", "alice"), "w1")
+	if err != nil || workout.Name != "Gym" {
+		t.Errorf("expected the gym workout to be untouched, got %+v (%v)", workout, err)
+	}
+}
+
+func TestGroups(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	h.AcceptPasswords([]models.ServerPassword{{PasswordHash: hashPassphrase("family")}})
+	h.EnableAdmin(AdminConfig{Token: "admin-secret"})
+
+	// Create a group with its own password
+	body := `{"name":"gym","password":"gym-pass"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/groups", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	w := httptest.NewRecorder()
+	h.CreateGroup(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var group models.Group
+	json.Unmarshal(w.Body.Bytes(), &group)
+
+	// The default password cannot be reused for a group
+	req = httptest.NewRequest(http.MethodPost, "/api/admin/groups", bytes.NewBufferString(`{"name":"x","password":"family"}`))
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	w = httptest.NewRecorder()
+	h.CreateGroup(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status 409, got %d", w.Code)
+	}
+
+	// The same profile name in two groups gets separate data
+	syncAs := func(passwordHash, profile string, workouts []models.Workout) models.SyncPayload {
+		body, _ := json.Marshal(models.AuthRequest{ProfileName: profile, PasswordHash: passwordHash})
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/init", bytes.NewBuffer(body))
+		w := httptest.NewRecorder()
+		h.AuthInit(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("auth failed: %d %s", w.Code, w.Body.String())
+		}
+		var auth models.AuthResponse
+		json.Unmarshal(w.Body.Bytes(), &auth)
+
+		payload, _ := json.Marshal(models.SyncPayload{Workouts: workouts})
+		req = httptest.NewRequest(http.MethodPost, "/api/sync", bytes.NewBuffer(payload))
+		req.Header.Set("Authorization", "Bearer "+auth.Token)
+		w = httptest.NewRecorder()
+		h.Sync(w, req)
+		var resp models.SyncPayload
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp
+	}
+
+	syncAs(hashPassphrase("gym-pass"), "alice", []models.Workout{{ID: "gym-workout", Name: "Gym", Rounds: 1}})
+	h.rl = NewRateLimiter()
+	resp := syncAs(hashPassphrase("family"), "alice", nil)
+	if len(resp.Workouts) != 0 {
+		t.Errorf("expected default group to see no gym workouts, got %d", len(resp.Workouts))
+	}
+
+	profilesFor := func(passwordHash string) []string {
+		body := `{"password_hash":"` + passwordHash + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/profiles", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		h.GetProfiles(w, req)
+		var resp struct {
+			Profiles []string `json:"profiles"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp.Profiles
+	}
+	h.rl = NewRateLimiter()
+	if profiles := profilesFor(hashPassphrase("gym-pass")); len(profiles) != 1 || profiles[0] != "alice" {
+		t.Errorf("expected gym profiles [alice], got %v", profiles)
+	}
+	if profiles := profilesFor(hashPassphrase("family")); len(profiles) != 1 || profiles[0] != "alice" {
+		t.Errorf("expected family profiles [alice], got %v", profiles)
+	}
+
+	// Deleting the group removes its data
+	req = httptest.NewRequest(http.MethodDelete, "/api/admin/groups/"+group.ID, nil)
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	w = httptest.NewRecorder()
+	h.DeleteGroup(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if _, err := h.store.GetWorkout(store.GroupUserID(group.ID, "alice"), "gym-workout"); err == nil {
+		t.Error("expected group workout to be deleted")
+	}
+}
+
+func TestAdminRequiresToken(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/groups", nil)
+	w := httptest.NewRecorder()
+	h.ListGroups(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 when admin is disabled, got %d", w.Code)
+	}
+
+	h.EnableAdmin(AdminConfig{Token: "admin-secret"})
+	req = httptest.NewRequest(http.MethodGet, "/api/admin/groups", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	w = httptest.NewRecorder()
+	h.ListGroups(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401 for wrong token, got %d", w.Code)
+	}
+}
+
+func TestAdminAPI(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	h.EnableAdmin(AdminConfig{Token: "admin-secret", BackupDir: t.TempDir()})
+	token := login(t, h, "alice")
+
+	h.store.UpsertWorkout(&models.Workout{ID: "w1", UserID: "alice", Name: "Keep", Rounds: 1})
+	h.store.UpsertWorkout(&models.Workout{ID: "w2", UserID: "alice", Name: "Trash", Rounds: 1})
+	h.store.DeleteWorkout("alice", "w2", 0)
+
+	admin := func(handler http.HandlerFunc, method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer admin-secret")
+		w := httptest.NewRecorder()
+		handler(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s %s: expected status 200, got %d: %s", method, url, w.Code, w.Body.String())
+		}
+		return w
+	}
+
+	// Profiles with counts
+	w := admin(h.AdminListProfiles, http.MethodGet, "/api/admin/profiles", "")
+	var profiles struct {
+		Profiles []models.ProfileSummary `json:"profiles"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &profiles)
+	if len(profiles.Profiles) != 1 || profiles.Profiles[0].Workouts != 1 || profiles.Profiles[0].Sessions != 1 {
+		t.Errorf("unexpected profile summaries: %+v", profiles.Profiles)
+	}
+
+	// Sessions are listed by fingerprint and can be revoked
+	w = admin(h.AdminListSessions, http.MethodGet, "/api/admin/sessions?user_id=alice", "")
+	var sessions struct {
+		Sessions []adminSession `json:"sessions"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &sessions)
+	if len(sessions.Sessions) != 1 || strings.Contains(w.Body.String(), token) {
+		t.Fatalf("expected one session without its token, got %s", w.Body.String())
+	}
+	admin(h.AdminRevokeSession, http.MethodDelete, "/api/admin/sessions/"+sessions.Sessions[0].ID, "")
+	if _, err := h.store.VerifySession(token); err == nil {
+		t.Error("expected session to be revoked")
+	}
+
+	// Maintenance
+	w = admin(h.AdminPurge, http.MethodPost, "/api/admin/maintenance/purge", `{"older_than_days":0}`)
+	var purged map[string]int
+	json.Unmarshal(w.Body.Bytes(), &purged)
+	if purged["workouts_purged"] != 1 {
+		t.Errorf("expected 1 purged workout, got %v", purged)
+	}
+	admin(h.AdminStorage, http.MethodGet, "/api/admin/storage", "")
+	admin(h.AdminMetrics, http.MethodGet, "/api/admin/metrics", "")
+
+	// Every action is audited
+	w = admin(h.AdminAuditLog, http.MethodGet, "/api/admin/audit", "")
+	var audit struct {
+		Entries []models.AuditEntry `json:"entries"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &audit)
+	if len(audit.Entries) != 6 || audit.Entries[0].Action != "view_metrics" {
+		t.Errorf("unexpected audit log: %+v", audit.Entries)
+	}
+}
+
+func TestPasswordRotation(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	h.AcceptPasswords(ParsePasswordList([]string{"old"}))
+	h.EnableAdmin(AdminConfig{Token: "admin-secret"})
+
+	testConnection := func(password string) (int, map[string]interface{}) {
+		body := `{"password_hash":"` + hashPassphrase(password) + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/test", bytes.NewBufferString(body))
+		req.RemoteAddr = "198.51.100.1:1234"
+		w := httptest.NewRecorder()
+		h.rl = NewRateLimiter()
+		h.TestConnection(w, req)
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	if code, _ := testConnection("new"); code != http.StatusUnauthorized {
+		t.Fatalf("expected new password to be rejected before rotation, got %d", code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/passwords/rotate", bytes.NewBufferString(`{"password":"new","grace_hours":24}`))
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	w := httptest.NewRecorder()
+	h.AdminRotatePassword(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Both passwords work during the grace period; the old one warns
+	code, resp := testConnection("old")
+	if code != http.StatusOK || resp["password_expiring"] != true {
+		t.Errorf("expected old password to work with an expiry warning, got %d %v", code, resp)
+	}
+	code, resp = testConnection("new")
+	if code != http.StatusOK || resp["password_expires_at"] != nil {
+		t.Errorf("expected new password to work without expiry, got %d %v", code, resp)
+	}
+
+	// Revoking the old password ends its grace period immediately
+	h.rl = NewRateLimiter()
+	req = httptest.NewRequest(http.MethodDelete, "/api/admin/passwords/"+passwordFingerprint(hashPassphrase("old")), nil)
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	w = httptest.NewRecorder()
+	h.AdminRevokePassword(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if code, _ := testConnection("old"); code != http.StatusUnauthorized {
+		t.Errorf("expected old password to be rejected after revoke, got %d", code)
+	}
+}
+
+func TestParsePasswordList(t *testing.T) {
+	passwords := ParsePasswordList([]string{"plain", "old@2026-01-02", "me@example"})
+	if len(passwords) != 3 {
+		t.Fatalf("expected 3 passwords, got %d", len(passwords))
+	}
+	if passwords[0].ExpiresAt != nil {
+		t.Error("expected no expiry for plain password")
+	}
+	if passwords[1].PasswordHash != hashPassphrase("old") || passwords[1].ExpiresAt == nil {
+		t.Errorf("expected 'old' with expiry, got %+v", passwords[1])
+	}
+	if passwords[2].PasswordHash != hashPassphrase("me@example") {
+		t.Error("expected '@' without a date to be part of the password")
+	}
+}
+
+func TestSignedSessions(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	if err := h.EnableSignedSessions(SignedSessionConfig{Key: key[:16]}); err == nil {
+		t.Fatal("expected short signing key to be rejected")
+	}
+	if err := h.EnableSignedSessions(SignedSessionConfig{Key: key}); err != nil {
+		t.Fatal(err)
+	}
+	h.EnableAdmin(AdminConfig{Token: "admin-secret"})
+
+	syncStatus := func(h *Handler, token string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/sync", bytes.NewBufferString(`{"last_synced_at":0}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		h.Sync(w, req)
+		return w.Code
+	}
+
+	token := login(t, h, "test-profile")
+	if !strings.HasPrefix(token, signedSessionPrefix) {
+		t.Fatalf("expected a signed token, got %q", token)
+	}
+	if sessions, _ := h.store.ListSessions(""); len(sessions) != 0 {
+		t.Errorf("expected no stored sessions, got %d", len(sessions))
+	}
+	if code := syncStatus(h, token); code != http.StatusOK {
+		t.Fatalf("expected signed token to sync, got %d", code)
+	}
+
+	// Tampering with the payload invalidates the signature
+	forged := signedSessionPrefix + "e30" + token[strings.LastIndex(token, "."):]
+	if code := syncStatus(h, forged); code != http.StatusUnauthorized {
+		t.Errorf("expected forged token to be rejected, got %d", code)
+	}
+
+	// Another instance sharing the key and store verifies the token too
+	other := NewHandler(h.store, NewRateLimiter(), "")
+	other.EnableSignedSessions(SignedSessionConfig{Key: key, RefreshInterval: time.Millisecond})
+	if code := syncStatus(other, token); code != http.StatusOK {
+		t.Fatalf("expected other instance to accept token, got %d", code)
+	}
+
+	// Logout revokes the token here and, after a refresh, on the other instance
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/logout", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	h.Logout(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected logout to succeed, got %d", w.Code)
+	}
+	if code := syncStatus(h, token); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 after logout, got %d", code)
+	}
+	time.Sleep(2 * time.Millisecond)
+	if code := syncStatus(other, token); code != http.StatusUnauthorized {
+		t.Errorf("expected other instance to see the revocation, got %d", code)
+	}
+
+	// Revoking a profile's sessions covers every token issued so far
+	h.rl = NewRateLimiter()
+	token = login(t, h, "test-profile")
+	time.Sleep(2 * time.Millisecond)
+	req = httptest.NewRequest(http.MethodDelete, "/api/admin/sessions?user_id=test-profile", nil)
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	w = httptest.NewRecorder()
+	h.AdminRevokeProfileSessions(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if code := syncStatus(h, token); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 after revoking profile sessions, got %d", code)
+	}
+	time.Sleep(2 * time.Millisecond)
+	if code := syncStatus(h, login(t, h, "test-profile")); code != http.StatusOK {
+		t.Errorf("expected new login after revocation to work, got %d", code)
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+	h.AcceptPasswords(ParsePasswordList([]string{"secret"}))
+
+	authInit := func(h *Handler, remoteAddr string, password string) *httptest.ResponseRecorder {
+		h.rl = NewRateLimiter()
+		body := `{"password_hash":"` + hashPassphrase(password) + `","profile_name":"alice"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/init", bytes.NewBufferString(body))
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		h.AuthInit(w, req)
+		return w
+	}
+
+	for i := 0; i < lockoutFreeAttempts; i++ {
+		if w := authInit(h, "198.51.100.1:1234", "guess"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected 401, got %d", i+1, w.Code)
+		}
+	}
+
+	// Locked out now, even with the right password
+	w := authInit(h, "198.51.100.1:1234", "secret")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 429 with Retry-After, got %d %v", w.Code, w.Header())
+	}
+
+	// The lockout is in the store, so it survives a restart and also
+	// applies to the targeted profile from another address
+	restarted := NewHandler(h.store, NewRateLimiter(), "secret")
+	if w := authInit(restarted, "203.0.113.9:1234", "secret"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected profile lockout from another IP, got %d", w.Code)
+	}
+
+	// A locked-out profile can't tell a right password from a wrong one
+	right := authInit(restarted, "203.0.113.10:1234", "secret")
+	wrong := authInit(restarted, "203.0.113.11:1234", "guess")
+	if right.Code != http.StatusTooManyRequests || right.Code != wrong.Code || right.Body.String() != wrong.Body.String() {
+		t.Errorf("expected identical lockout responses, got %d %s and %d %s", right.Code, right.Body.String(), wrong.Code, wrong.Body.String())
+	}
+	if (right.Header().Get("Retry-After") == "") != (wrong.Header().Get("Retry-After") == "") {
+		t.Errorf("expected Retry-After on both responses, got %v and %v", right.Header(), wrong.Header())
+	}
+
+	// Once the lockout expires a successful login clears the failures
+	past := time.Now().Add(-time.Second)
+	h.store.LockLogin("ip:198.51.100.1", past)
+	h.store.LockLogin("profile:alice", past)
+	if w := authInit(h, "198.51.100.1:1234", "secret"); w.Code != http.StatusOK {
+		t.Fatalf("expected login after lockout to succeed, got %d", w.Code)
+	}
+	if _, err := h.store.GetLoginFailure("ip:198.51.100.1"); err == nil {
+		t.Error("expected failures to be cleared after a successful login")
+	}
+}
+
+func TestLockoutDelay(t *testing.T) {
+	if lockoutDelay(lockoutFreeAttempts-1) != 0 {
+		t.Error("expected no lockout before the free attempts are used")
+	}
+	if lockoutDelay(lockoutFreeAttempts) != lockoutBaseDelay || lockoutDelay(lockoutFreeAttempts+2) != 4*lockoutBaseDelay {
+		t.Error("expected the lockout to double with each failure")
+	}
+	if lockoutDelay(1000) != lockoutMaxDelay {
+		t.Error("expected the lockout to be capped")
+	}
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"errors"
+	"intervals-sync/internal/models"
+	"intervals-sync/internal/store"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Brute-force lockout policy. After lockoutFreeAttempts failures a client IP
+// or profile is locked out for lockoutBaseDelay, doubling with every further
+// failure up to lockoutMaxDelay. Failures are forgotten after a successful
+// login or lockoutWindow without failures.
+const (
+	lockoutFreeAttempts = 5
+	lockoutBaseDelay    = time.Second
+	lockoutMaxDelay     = 15 * time.Minute
+	lockoutWindow       = 24 * time.Hour
+)
+
+// lockoutDelay returns how long to lock a key out after a number of failures
+func lockoutDelay(failures int) time.Duration {
+	if failures < lockoutFreeAttempts {
+		return 0
+	}
+	exp := failures - lockoutFreeAttempts
+	if exp > 30 {
+		return lockoutMaxDelay
+	}
+	delay := lockoutBaseDelay * time.Duration(1<<uint(exp))
+	if delay > lockoutMaxDelay {
+		return lockoutMaxDelay
+	}
+	return delay
+}
+
+// verifyPassword checks a password hash like checkPassword, but first rejects
+// locked-out clients and profiles, and records the outcome in the store so
+// lockouts survive restarts and apply across instances. It writes the error
+// response and returns false if the request must stop.
+//
+// Lockouts are checked before the password, so a locked-out client gets the
+// same response whether or not the password is right. Profiles are keyed by
+// the name alone, as the tenant group is only known once the password is.
+func (h *Handler) verifyPassword(w http.ResponseWriter, r *http.Request, passwordHash string, profile string) (*passwordMatch, bool) {
+	keys := []string{"ip:" + h.clientIP(r)}
+	if profile != "" {
+		keys = append(keys, profileLockoutKey(profile))
+	}
+	for _, key := range keys {
+		if !h.checkLockout(w, key) {
+			return nil, false
+		}
+	}
+
+	match, err := h.checkPassword(passwordHash)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to check password"})
+		return nil, false
+	}
+
+	if match == nil {
+		for _, key := range keys {
+			h.recordLoginFailure(key)
+		}
+		writeJSON(w, http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid password"})
+		return nil, false
+	}
+
+	if match.required {
+		for _, key := range keys {
+			if err := h.store.ClearLoginFailure(key); err != nil {
+				log.Printf("Failed to clear login failures for %s: %v\n", key, err)
+			}
+		}
+	}
+	return match, true
+}
+
+// profileLockoutKey is the lockout key of a profile name
+func profileLockoutKey(profile string) string {
+	return "profile:" + profile
+}
+
+// checkLockout writes a 429 and returns false while a key is locked out
+func (h *Handler) checkLockout(w http.ResponseWriter, key string) bool {
+	f, err := h.store.GetLoginFailure(key)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to check password"})
+		return false
+	}
+	if f != nil && time.Now().Before(f.LockedUntil) {
+		retryAfter := int(math.Ceil(time.Until(f.LockedUntil).Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		writeJSON(w, http.StatusTooManyRequests, models.ErrorResponse{Error: "Too many failed attempts, try again later"})
+		return false
+	}
+	return true
+}
+
+// recordLoginFailure counts a failed attempt and locks the key out once it
+// has failed too often. Errors are logged; the attempt is rejected regardless.
+func (h *Handler) recordLoginFailure(key string) {
+	f, err := h.store.RecordLoginFailure(key, time.Now().Add(-lockoutWindow))
+	if err != nil {
+		log.Printf("Failed to record login failure for %s: %v\n", key, err)
+		return
+	}
+	if delay := lockoutDelay(f.Failures); delay > 0 {
+		if err := h.store.LockLogin(key, time.Now().Add(delay)); err != nil {
+			log.Printf("Failed to lock out %s: %v\n", key, err)
+		}
+	}
+}
@@ -0,0 +1,402 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"intervals-sync/internal/models"
+	"intervals-sync/internal/store"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// oidcLoginTTL bounds how long a user may take at the identity provider
+const oidcLoginTTL = 10 * time.Minute
+
+// maxPendingOIDCLogins caps the logins waiting for their callback, so
+// anonymous login requests can't grow memory without bound
+const maxPendingOIDCLogins = 10000
+
+// oidcSweepInterval is how often expired pending logins are removed
+const oidcSweepInterval = time.Minute
+
+// errProfileUnavailable is returned when a new identity names a profile it
+// may not claim
+var errProfileUnavailable = errors.New("profile unavailable")
+
+// OIDCConfig configures OpenID Connect login
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string   // callback URL registered with the identity provider
+	ProfileClaim string   // claim used to name new profiles (default preferred_username)
+	ReturnURLs   []string // frontend URLs allowed to receive the session token
+}
+
+// OIDCProvider runs the authorization-code flow against an identity provider
+type OIDCProvider struct {
+	cfg                   OIDCConfig
+	client                *http.Client
+	issuer                string
+	authorizationEndpoint string
+	tokenEndpoint         string
+
+	mu        sync.Mutex
+	pending   map[string]*oidcLogin // keyed by state
+	lastSweep time.Time
+}
+
+// oidcLogin tracks an authorization request until its callback arrives
+type oidcLogin struct {
+	nonce    string
+	verifier string
+	returnTo string
+	expires  time.Time
+}
+
+// NewOIDCProvider fetches the provider's discovery document
+func NewOIDCProvider(cfg OIDCConfig) (*OIDCProvider, error) {
+	if cfg.ProfileClaim == "" {
+		cfg.ProfileClaim = "preferred_username"
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	discoveryURL := strings.TrimSuffix(cfg.IssuerURL, "/") + "/.well-known/openid-configuration"
+	resp, err := client.Get(discoveryURL)
+	if err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc discovery: unexpected status %d", resp.StatusCode)
+	}
+
+	var doc struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	if doc.Issuer == "" || doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" {
+		return nil, errors.New("oidc discovery: incomplete provider metadata")
+	}
+
+	return &OIDCProvider{
+		cfg:                   cfg,
+		client:                client,
+		issuer:                doc.Issuer,
+		authorizationEndpoint: doc.AuthorizationEndpoint,
+		tokenEndpoint:         doc.TokenEndpoint,
+		pending:               make(map[string]*oidcLogin),
+	}, nil
+}
+
+// EnableOIDC turns on OpenID Connect login for the handler
+func (h *Handler) EnableOIDC(p *OIDCProvider) {
+	h.oidc = p
+}
+
+// OIDCLogin handles GET /api/auth/oidc/login
+// Redirects the browser to the identity provider
+func (h *Handler) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.oidc == nil {
+		writeJSON(w, http.StatusNotFound, models.ErrorResponse{Error: "OIDC login is not enabled"})
+		return
+	}
+
+	returnTo := r.URL.Query().Get("return_to")
+	if returnTo != "" && !h.oidc.allowedReturnURL(returnTo) {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "return_to is not allowed"})
+		return
+	}
+
+	// Rate limit login attempts
+	if !h.rateLimit(w, r, "login", "", "Too many login attempts") {
+		return
+	}
+
+	authURL, ok := h.oidc.begin(returnTo)
+	if !ok {
+		writeJSON(w, http.StatusServiceUnavailable, models.ErrorResponse{Error: "Too many logins in progress, try again later"})
+		return
+	}
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OIDCCallback handles GET /api/auth/oidc/callback
+// Exchanges the authorization code and issues a normal session
+func (h *Handler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.oidc == nil {
+		writeJSON(w, http.StatusNotFound, models.ErrorResponse{Error: "OIDC login is not enabled"})
+		return
+	}
+
+	// Rate limit login attempts
+	if !h.rateLimit(w, r, "login", "", "Too many login attempts") {
+		return
+	}
+
+	query := r.URL.Query()
+	if errCode := query.Get("error"); errCode != "" {
+		writeJSON(w, http.StatusUnauthorized, models.ErrorResponse{Error: "Identity provider error: " + errCode})
+		return
+	}
+
+	login := h.oidc.take(query.Get("state"))
+	if login == nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Unknown or expired login state"})
+		return
+	}
+
+	claims, err := h.oidc.exchange(query.Get("code"), login)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, models.ErrorResponse{Error: "Failed to verify identity"})
+		return
+	}
+
+	profile, err := h.oidcProfile(claims)
+	if errors.Is(err, errProfileUnavailable) {
+		writeJSON(w, http.StatusForbidden, models.ErrorResponse{Error: "Profile is already in use; ask an administrator to link this identity"})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to resolve profile"})
+		return
+	}
+
+	token, err := h.createSession(profile)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to create session"})
+		return
+	}
+
+	if login.returnTo != "" {
+		fragment := url.Values{"token": {token}, "profile": {profile}}
+		http.Redirect(w, r, login.returnTo+"#"+fragment.Encode(), http.StatusFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, models.AuthResponse{Token: token, ProfileName: profile})
+}
+
+// oidcProfile maps the IdP subject to a profile, linking new subjects on first login
+func (h *Handler) oidcProfile(claims map[string]interface{}) (string, error) {
+	subject, _ := claims["sub"].(string)
+	profile, err := h.store.GetIdentityProfile(h.oidc.issuer, subject)
+	if err == nil {
+		return profile, nil
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		return "", err
+	}
+
+	profile, _ = claims[h.oidc.cfg.ProfileClaim].(string)
+	if profile == "" {
+		profile = subject
+	}
+	// A new subject only gets a fresh profile; existing ones, including
+	// those linked to another identity, must be linked by an administrator
+	if store.IsReservedProfileName(profile) {
+		return "", errProfileUnavailable
+	}
+	inUse, err := h.store.ProfileInUse(profile)
+	if err != nil {
+		return "", err
+	}
+	if inUse {
+		return "", errProfileUnavailable
+	}
+	if err := h.store.LinkIdentity(h.oidc.issuer, subject, profile); err != nil {
+		return "", err
+	}
+	return profile, nil
+}
+
+func (p *OIDCProvider) allowedReturnURL(returnTo string) bool {
+	for _, allowed := range p.cfg.ReturnURLs {
+		if returnTo == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// begin records a pending login and returns the authorization URL, or false
+// if too many logins are pending
+func (p *OIDCProvider) begin(returnTo string) (string, bool) {
+	state := generateToken()
+	login := &oidcLogin{
+		nonce:    generateToken(),
+		verifier: generateToken() + generateToken(),
+		returnTo: returnTo,
+		expires:  time.Now().Add(oidcLoginTTL),
+	}
+
+	p.mu.Lock()
+	now := time.Now()
+	if now.Sub(p.lastSweep) >= oidcSweepInterval || len(p.pending) >= maxPendingOIDCLogins {
+		for key, pending := range p.pending {
+			if now.After(pending.expires) {
+				delete(p.pending, key)
+			}
+		}
+		p.lastSweep = now
+	}
+	if len(p.pending) >= maxPendingOIDCLogins {
+		p.mu.Unlock()
+		return "", false
+	}
+	p.pending[state] = login
+	p.mu.Unlock()
+
+	challenge := sha256.Sum256([]byte(login.verifier))
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {"openid profile email"},
+		"state":                 {state},
+		"nonce":                 {login.nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	sep := "?"
+	if strings.Contains(p.authorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return p.authorizationEndpoint + sep + params.Encode(), true
+}
+
+// take removes and returns a pending login, or nil if unknown or expired
+func (p *OIDCProvider) take(state string) *oidcLogin {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	login, ok := p.pending[state]
+	if !ok {
+		return nil
+	}
+	delete(p.pending, state)
+	if time.Now().After(login.expires) {
+		return nil
+	}
+	return login
+}
+
+// exchange redeems an authorization code and validates the returned ID token.
+// The token comes straight from the token endpoint over TLS, so per OIDC Core
+// 3.1.3.7 the issuer is trusted via TLS instead of checking the signature.
+func (p *OIDCProvider) exchange(code string, login *oidcLogin) (map[string]interface{}, error) {
+	if code == "" {
+		return nil, errors.New("missing authorization code")
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"code_verifier": {login.verifier},
+	}
+	req, err := http.NewRequest(http.MethodPost, p.tokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned status %d", resp.StatusCode)
+	}
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return nil, err
+	}
+
+	claims, err := decodeJWTClaims(tokens.IDToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.validateClaims(claims, login.nonce); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// validateClaims checks issuer, audience, expiry and nonce of an ID token
+func (p *OIDCProvider) validateClaims(claims map[string]interface{}, nonce string) error {
+	if iss, _ := claims["iss"].(string); iss != p.issuer {
+		return errors.New("id token issuer mismatch")
+	}
+
+	audienceOK := false
+	switch aud := claims["aud"].(type) {
+	case string:
+		audienceOK = aud == p.cfg.ClientID
+	case []interface{}:
+		for _, a := range aud {
+			if a == p.cfg.ClientID {
+				audienceOK = true
+			}
+		}
+	}
+	if !audienceOK {
+		return errors.New("id token audience mismatch")
+	}
+
+	exp, _ := claims["exp"].(float64)
+	if time.Now().Unix() >= int64(exp) {
+		return errors.New("id token expired")
+	}
+	if n, _ := claims["nonce"].(string); n != nonce {
+		return errors.New("id token nonce mismatch")
+	}
+	if sub, _ := claims["sub"].(string); sub == "" {
+		return errors.New("id token has no subject")
+	}
+	return nil
+}
+
+// decodeJWTClaims returns the payload of a compact JWT
+func decodeJWTClaims(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed id token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
@@ -0,0 +1,232 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"intervals-sync/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mockIdP is a minimal OpenID provider that issues unsigned ID tokens
+type mockIdP struct {
+	server   *httptest.Server
+	nonce    string
+	username string
+	subject  string
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	idp := &mockIdP{subject: "sub-123", username: "alice"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		id, secret, _ := r.BasicAuth()
+		if r.Form.Get("code") != "good-code" || id != "intervals" || secret != "s3cret" || r.Form.Get("code_verifier") == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		claims, _ := json.Marshal(map[string]interface{}{
+			"iss":                idp.server.URL,
+			"aud":                "intervals",
+			"sub":                idp.subject,
+			"exp":                time.Now().Add(time.Minute).Unix(),
+			"nonce":              idp.nonce,
+			"preferred_username": idp.username,
+		})
+		header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+		idToken := header + "." + base64.RawURLEncoding.EncodeToString(claims) + "."
+		writeJSON(w, http.StatusOK, map[string]string{"id_token": idToken, "token_type": "Bearer"})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// oidcLoginFlow drives login and callback, returning the callback response
+func oidcLoginFlow(t *testing.T, h *Handler, idp *mockIdP, code string) *httptest.ResponseRecorder {
+	h.rl = NewRateLimiter()
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login", nil)
+	w := httptest.NewRecorder()
+	h.OIDCLogin(w, req)
+	if w.Code != http.StatusFound {
+		t.Fatalf("expected redirect, got %d: %s", w.Code, w.Body.String())
+	}
+
+	location, _ := url.Parse(w.Header().Get("Location"))
+	if !strings.HasPrefix(location.String(), idp.server.URL+"/authorize") {
+		t.Fatalf("unexpected redirect: %s", location)
+	}
+	idp.nonce = location.Query().Get("nonce")
+	state := location.Query().Get("state")
+
+	callback := "/api/auth/oidc/callback?" + url.Values{"code": {code}, "state": {state}}.Encode()
+	req = httptest.NewRequest(http.MethodGet, callback, nil)
+	w = httptest.NewRecorder()
+	h.OIDCCallback(w, req)
+	return w
+}
+
+func setupOIDCHandler(t *testing.T) (*Handler, *mockIdP, func()) {
+	idp := newMockIdP(t)
+	h, cleanup := setupTestHandler(t)
+	provider, err := NewOIDCProvider(OIDCConfig{
+		IssuerURL:    idp.server.URL,
+		ClientID:     "intervals",
+		ClientSecret: "s3cret",
+		RedirectURL:  "https://sync.example.com/api/auth/oidc/callback",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.EnableOIDC(provider)
+	return h, idp, cleanup
+}
+
+func TestOIDCLogin(t *testing.T) {
+	h, idp, cleanup := setupOIDCHandler(t)
+	defer cleanup()
+
+	w := oidcLoginFlow(t, h, idp, "good-code")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp models.AuthResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.ProfileName != "alice" {
+		t.Errorf("expected profile 'alice', got '%s'", resp.ProfileName)
+	}
+	session, err := h.store.VerifySession(resp.Token)
+	if err != nil {
+		t.Fatalf("expected a usable session: %v", err)
+	}
+	if session.UserID != "alice" {
+		t.Errorf("expected session for 'alice', got '%s'", session.UserID)
+	}
+
+	// The subject stays mapped to its profile when the username changes
+	idp.username = "alice.renamed"
+	w = oidcLoginFlow(t, h, idp, "good-code")
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.ProfileName != "alice" {
+		t.Errorf("expected subject to stay mapped to 'alice', got '%s'", resp.ProfileName)
+	}
+}
+
+func TestOIDCLoginRejected(t *testing.T) {
+	h, idp, cleanup := setupOIDCHandler(t)
+	defer cleanup()
+
+	w := oidcLoginFlow(t, h, idp, "bad-code")
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401, got %d", w.Code)
+	}
+
+	// Unknown state is rejected without contacting the IdP
+	h.rl = NewRateLimiter()
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback?code=good-code&state=forged", nil)
+	rec := httptest.NewRecorder()
+	h.OIDCCallback(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for unknown state, got %d", rec.Code)
+	}
+
+	// return_to must be allow-listed
+	h.rl = NewRateLimiter()
+	req = httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login?return_to=https://evil.example", nil)
+	rec = httptest.NewRecorder()
+	h.OIDCLogin(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for disallowed return_to, got %d", rec.Code)
+	}
+}
+
+func TestOIDCLoginRefusesTakenProfiles(t *testing.T) {
+	h, idp, cleanup := setupOIDCHandler(t)
+	defer cleanup()
+	h.EnableAdmin(AdminConfig{Token: "admin-secret"})
+
+	// alice already exists through a password login
+	login(t, h, "alice")
+	w := oidcLoginFlow(t, h, idp, "good-code")
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for an existing profile, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Names in the group namespace are never handed out
+	idp.username = "group:other:alice"
+	if w := oidcLoginFlow(t, h, idp, "good-code"); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a reserved name, got %d", w.Code)
+	}
+
+	// An administrator can link the subject explicitly
+	body := `{"subject":"sub-123","user_id":"alice"}`
+	if code := do(t, h.AdminLinkIdentity, http.MethodPost, "/api/admin/identities", "admin-secret", body, nil); code != http.StatusOK {
+		t.Fatalf("expected 200 linking the identity, got %d", code)
+	}
+	w = oidcLoginFlow(t, h, idp, "good-code")
+	var resp models.AuthResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || resp.ProfileName != "alice" {
+		t.Errorf("expected the linked profile, got %d %+v", w.Code, resp)
+	}
+
+	// A second subject can't claim the linked profile
+	idp.subject = "sub-456"
+	idp.username = "alice"
+	if w := oidcLoginFlow(t, h, idp, "good-code"); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a profile linked to another subject, got %d", w.Code)
+	}
+}
+
+func TestOIDCPendingLoginsCapped(t *testing.T) {
+	h, _, cleanup := setupOIDCHandler(t)
+	defer cleanup()
+
+	// Logins are rate limited per client
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login", nil)
+		w := httptest.NewRecorder()
+		h.OIDCLogin(w, req)
+		if i == 2 && w.Code != http.StatusTooManyRequests {
+			t.Errorf("expected 429 after the burst, got %d", w.Code)
+		}
+	}
+
+	// Once the pending logins are full, new ones are refused
+	for i := 0; i < maxPendingOIDCLogins; i++ {
+		h.oidc.pending[strconv.Itoa(i)] = &oidcLogin{expires: time.Now().Add(time.Minute)}
+	}
+	h.rl = NewRateLimiter()
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login", nil)
+	w := httptest.NewRecorder()
+	h.OIDCLogin(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 with too many pending logins, got %d", w.Code)
+	}
+
+	// Expired ones are swept to make room
+	for _, login := range h.oidc.pending {
+		login.expires = time.Now().Add(-time.Second)
+	}
+	h.rl = NewRateLimiter()
+	w = httptest.NewRecorder()
+	h.OIDCLogin(w, req)
+	if w.Code != http.StatusFound || len(h.oidc.pending) != 1 {
+		t.Errorf("expected a redirect after sweeping, got %d with %d pending", w.Code, len(h.oidc.pending))
+	}
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"intervals-sync/internal/models"
+	"intervals-sync/internal/store"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// pairingCodeTTL is how long a pairing code can be redeemed
+const pairingCodeTTL = 5 * time.Minute
+
+// pairingCodeDigits is the length of the numeric pairing code
+const pairingCodeDigits = 6
+
+// maxPairingFailures is how many failed redemptions, from any client, a live
+// code survives before it is burned
+const maxPairingFailures = 10
+
+// CreatePairingCode handles POST /api/pairing
+// Issues a short-lived single-use code a new device can redeem for a session
+func (h *Handler) CreatePairingCode(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	expiresAt := time.Now().Add(pairingCodeTTL)
+	var code string
+	var err error
+	// Retry on the rare collision with another live code
+	for attempt := 0; attempt < 3; attempt++ {
+		code, err = generatePairingCode()
+		if err != nil {
+			break
+		}
+		if err = h.store.CreatePairingCode(hashPassphrase(code), session.UserID, expiresAt); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to create pairing code"})
+		return
+	}
+
+	qrPayload, _ := json.Marshal(map[string]string{
+		"server": requestBaseURL(r),
+		"code":   code,
+	})
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"code":       code,
+		"expires_at": expiresAt,
+		"qr_payload": string(qrPayload),
+	})
+}
+
+// RedeemPairingCode handles POST /api/pairing/redeem
+// Exchanges a pairing code for a new session on the redeeming device
+func (h *Handler) RedeemPairingCode(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Pairing codes are short, so attempts are limited much more strictly than logins
+	if !h.rateLimit(w, r, "pair", "", "Too many pairing attempts") {
+		return
+	}
+	// A server-wide limit bounds guessing spread over many clients
+	if !h.rateLimit(w, r, "pair_all", "all", "Too many pairing attempts") {
+		return
+	}
+
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	code := strings.TrimSpace(req.Code)
+	if len(code) != pairingCodeDigits {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Invalid pairing code"})
+		return
+	}
+
+	userID, err := h.store.RedeemPairingCode(hashPassphrase(code))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			if err := h.store.RecordPairingFailure(maxPairingFailures); err != nil {
+				log.Printf("Failed to record pairing failure: %v\n", err)
+			}
+			writeJSON(w, http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid or expired pairing code"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to redeem pairing code"})
+		return
+	}
+
+	token, err := h.createSession(userID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to create session"})
+		return
+	}
+
+	_, profile := store.SplitGroupUserID(userID)
+	writeJSON(w, http.StatusOK, models.AuthResponse{Token: token, ProfileName: profile})
+}
+
+// generatePairingCode returns a uniformly random numeric code
+func generatePairingCode() (string, error) {
+	limit := big.NewInt(1)
+	for i := 0; i < pairingCodeDigits; i++ {
+		limit.Mul(limit, big.NewInt(10))
+	}
+	n, err := rand.Int(rand.Reader, limit)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", pairingCodeDigits, n), nil
+}
+
+// requestBaseURL reconstructs the public URL the client used to reach the server
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
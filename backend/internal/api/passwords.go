@@ -0,0 +1,284 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"intervals-sync/internal/models"
+	"intervals-sync/internal/store"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// passwordExpiryWarning is how early TestConnection warns that a password is
+// about to stop working
+const passwordExpiryWarning = 7 * 24 * time.Hour
+
+// defaultRotationGrace is how long replaced passwords keep working
+const defaultRotationGrace = 7 * 24 * time.Hour
+
+// passwordMatch describes the credential a password hash was accepted as
+type passwordMatch struct {
+	groupID   string     // "" for the default group
+	expiresAt *time.Time // set while a rotated password is in its grace period
+	required  bool       // false when the server is open to anyone
+}
+
+// ParsePasswordList parses SYNC_PASSWORDS: comma-separated passwords, each
+// optionally suffixed with @<RFC3339 date or YYYY-MM-DD> after which it stops
+// being accepted
+func ParsePasswordList(list []string) []models.ServerPassword {
+	var passwords []models.ServerPassword
+	for _, item := range list {
+		password := models.ServerPassword{PasswordHash: hashPassphrase(item)}
+		if i := strings.LastIndex(item, "@"); i > 0 {
+			if expiresAt, err := parseTimeOrDate(item[i+1:]); err == nil {
+				password.PasswordHash = hashPassphrase(item[:i])
+				password.ExpiresAt = &expiresAt
+			}
+		}
+		passwords = append(passwords, password)
+	}
+	return passwords
+}
+
+// parseTimeOrDate accepts an RFC3339 timestamp or a YYYY-MM-DD date (UTC midnight)
+func parseTimeOrDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
+
+// AcceptPasswords adds configured default-group passwords, e.g. from SYNC_PASSWORDS
+func (h *Handler) AcceptPasswords(passwords []models.ServerPassword) {
+	h.syncPasswords = append(h.syncPasswords, passwords...)
+}
+
+// serverPasswords merges configured passwords with those managed through the
+// admin API. A stored entry overrides the expiry of a configured one, which
+// is how SYNC_PASSWORD itself gets retired by a rotation. Expired passwords
+// are included.
+func (h *Handler) serverPasswords() ([]models.ServerPassword, error) {
+	stored, err := h.store.ListServerPasswords()
+	if err != nil {
+		return nil, err
+	}
+
+	overrides := make(map[string]models.ServerPassword, len(stored))
+	for _, p := range stored {
+		overrides[p.PasswordHash] = p
+	}
+
+	var passwords []models.ServerPassword
+	for _, p := range h.syncPasswords {
+		if o, ok := overrides[p.PasswordHash]; ok {
+			p.ExpiresAt = o.ExpiresAt
+			delete(overrides, p.PasswordHash)
+		}
+		passwords = append(passwords, p)
+	}
+	for _, p := range stored {
+		if _, ok := overrides[p.PasswordHash]; ok {
+			passwords = append(passwords, p)
+		}
+	}
+	return passwords, nil
+}
+
+// checkPassword returns what a password hash grants access to, or nil if it
+// is rejected. Tenant group passwords select their group; otherwise the hash
+// must be an unexpired server password, unless none were ever configured.
+func (h *Handler) checkPassword(passwordHash string) (*passwordMatch, error) {
+	passwords, err := h.serverPasswords()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, p := range passwords {
+		if p.PasswordHash != passwordHash {
+			continue
+		}
+		if p.ExpiresAt != nil && !now.Before(*p.ExpiresAt) {
+			return nil, nil
+		}
+		return &passwordMatch{expiresAt: p.ExpiresAt, required: true}, nil
+	}
+
+	if passwordHash != "" {
+		group, err := h.store.GetGroupByPasswordHash(passwordHash)
+		if err == nil {
+			return &passwordMatch{groupID: group.ID, required: true}, nil
+		}
+		if !errors.Is(err, store.ErrNotFound) {
+			return nil, err
+		}
+	}
+
+	// With no password ever configured, the server is open to anyone
+	if len(passwords) == 0 {
+		return &passwordMatch{}, nil
+	}
+	return nil, nil
+}
+
+// isServerPassword reports whether a hash is a configured server password
+func (h *Handler) isServerPassword(passwordHash string) (bool, error) {
+	passwords, err := h.serverPasswords()
+	if err != nil {
+		return false, err
+	}
+	for _, p := range passwords {
+		if p.PasswordHash == passwordHash {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// passwordFingerprint identifies a password to administrators without revealing its hash
+func passwordFingerprint(passwordHash string) string {
+	return hashPassphrase(passwordHash)[:16]
+}
+
+// AdminListPasswords handles GET /api/admin/passwords
+func (h *Handler) AdminListPasswords(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.requireAdmin(w, r) {
+		return
+	}
+
+	passwords, err := h.serverPasswords()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch passwords"})
+		return
+	}
+	h.audit(r, "list_passwords", "", "")
+
+	now := time.Now()
+	result := make([]map[string]interface{}, 0, len(passwords))
+	for _, p := range passwords {
+		result = append(result, map[string]interface{}{
+			"id":         passwordFingerprint(p.PasswordHash),
+			"expires_at": p.ExpiresAt,
+			"expired":    p.ExpiresAt != nil && !now.Before(*p.ExpiresAt),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"passwords": result,
+	})
+}
+
+// AdminRotatePassword handles POST /api/admin/passwords/rotate
+// Adds a new server password and retires every other active one after a
+// grace period (grace_hours, default 168), so clients can switch over
+// without being locked out
+func (h *Handler) AdminRotatePassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.requireAdmin(w, r) {
+		return
+	}
+
+	var req struct {
+		Password   string `json:"password"`
+		GraceHours *int   `json:"grace_hours"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+	if req.Password == "" {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Password is required"})
+		return
+	}
+	grace := defaultRotationGrace
+	if req.GraceHours != nil {
+		if *req.GraceHours < 0 {
+			writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "grace_hours must not be negative"})
+			return
+		}
+		grace = time.Duration(*req.GraceHours) * time.Hour
+	}
+
+	newHash := hashPassphrase(req.Password)
+	if _, err := h.store.GetGroupByPasswordHash(newHash); err == nil {
+		writeJSON(w, http.StatusConflict, models.ErrorResponse{Error: "Password is already in use"})
+		return
+	}
+
+	passwords, err := h.serverPasswords()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to rotate password"})
+		return
+	}
+
+	// Retire the current passwords, never extending an earlier expiry
+	retireAt := time.Now().Add(grace)
+	for _, p := range passwords {
+		if p.PasswordHash == newHash || (p.ExpiresAt != nil && p.ExpiresAt.Before(retireAt)) {
+			continue
+		}
+		p.ExpiresAt = &retireAt
+		if err := h.store.PutServerPassword(&p); err != nil {
+			writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to rotate password"})
+			return
+		}
+	}
+
+	if err := h.store.PutServerPassword(&models.ServerPassword{PasswordHash: newHash}); err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to rotate password"})
+		return
+	}
+	h.audit(r, "rotate_password", passwordFingerprint(newHash), fmt.Sprintf("grace=%s", grace))
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":                  passwordFingerprint(newHash),
+		"previous_expires_at": retireAt,
+	})
+}
+
+// AdminRevokePassword handles DELETE /api/admin/passwords/:id
+// Stops accepting a server password immediately
+func (h *Handler) AdminRevokePassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.requireAdmin(w, r) {
+		return
+	}
+
+	passwordID := strings.TrimPrefix(r.URL.Path, "/api/admin/passwords/")
+	passwords, err := h.serverPasswords()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch passwords"})
+		return
+	}
+
+	for _, p := range passwords {
+		if passwordFingerprint(p.PasswordHash) != passwordID {
+			continue
+		}
+		now := time.Now()
+		p.ExpiresAt = &now
+		if err := h.store.PutServerPassword(&p); err != nil {
+			writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to revoke password"})
+			return
+		}
+		h.audit(r, "revoke_password", passwordID, "")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("{}"))
+		return
+	}
+
+	writeJSON(w, http.StatusNotFound, models.ErrorResponse{Error: "Password not found"})
+}
@@ -0,0 +1,480 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"intervals-sync/internal/models"
+	"intervals-sync/internal/store"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxProjectName limits project and task names
+const maxProjectName = 200
+
+// projectRequest is the body of the project routes; omitted fields are kept
+// on PATCH
+type projectRequest struct {
+	Name  *string `json:"name"`
+	Color *string `json:"color"`
+}
+
+// taskRequest is the body of the task routes; omitted fields are kept on
+// PATCH. An empty project_id removes the task from its project.
+type taskRequest struct {
+	Name      *string `json:"name"`
+	ProjectID *string `json:"project_id"`
+	Done      *bool   `json:"done"`
+}
+
+// completionPatch is the body of PATCH /api/completions/:id. An empty
+// project_id or task_id clears it.
+type completionPatch struct {
+	ProjectID *string `json:"project_id"`
+	TaskID    *string `json:"task_id"`
+}
+
+// ListProjects handles GET /api/projects
+func (h *Handler) ListProjects(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	projects, err := h.store.ListProjects(session.UserID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch projects"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"projects": projects,
+	})
+}
+
+// CreateProject handles POST /api/projects
+func (h *Handler) CreateProject(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	var req projectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	now := time.Now()
+	project := models.Project{
+		ID:        uuid.New().String(),
+		UserID:    session.UserID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if msg := applyProjectRequest(&project, &req); msg != "" {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: msg})
+		return
+	}
+	if project.Name == "" {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Project name is required"})
+		return
+	}
+
+	if err := h.store.CreateProject(&project); err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to save project"})
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, project)
+}
+
+// GetProject handles GET /api/projects/:id
+func (h *Handler) GetProject(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	project, ok := h.loadProject(w, session.UserID, strings.TrimPrefix(r.URL.Path, "/api/projects/"))
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, project)
+}
+
+// UpdateProject handles PATCH /api/projects/:id
+func (h *Handler) UpdateProject(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	project, ok := h.loadProject(w, session.UserID, strings.TrimPrefix(r.URL.Path, "/api/projects/"))
+	if !ok {
+		return
+	}
+
+	var req projectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+	if msg := applyProjectRequest(project, &req); msg != "" {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: msg})
+		return
+	}
+
+	project.UpdatedAt = time.Now()
+	if err := h.store.UpdateProject(project); err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to save project"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, project)
+}
+
+// DeleteProject handles DELETE /api/projects/:id
+// Tasks of the project are kept without a project; tagged completions keep
+// the ID and are reported under "No project"
+func (h *Handler) DeleteProject(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	projectID := strings.TrimPrefix(r.URL.Path, "/api/projects/")
+	if err := h.store.DeleteProject(session.UserID, projectID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, models.ErrorResponse{Error: "Project not found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to delete project"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("{}"))
+}
+
+// ListTasks handles GET /api/tasks
+// Supports ?project_id= to list the tasks of one project
+func (h *Handler) ListTasks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	tasks, err := h.store.ListTasks(session.UserID, r.URL.Query().Get("project_id"))
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch tasks"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"tasks": tasks,
+	})
+}
+
+// CreateTask handles POST /api/tasks
+func (h *Handler) CreateTask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	var req taskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	now := time.Now()
+	task := models.Task{
+		ID:        uuid.New().String(),
+		UserID:    session.UserID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if !h.applyTaskRequest(w, &task, &req) {
+		return
+	}
+	if task.Name == "" {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Task name is required"})
+		return
+	}
+
+	if err := h.store.CreateTask(&task); err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to save task"})
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, task)
+}
+
+// GetTask handles GET /api/tasks/:id
+func (h *Handler) GetTask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	task, ok := h.loadTask(w, session.UserID, strings.TrimPrefix(r.URL.Path, "/api/tasks/"))
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, task)
+}
+
+// UpdateTask handles PATCH /api/tasks/:id
+func (h *Handler) UpdateTask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	task, ok := h.loadTask(w, session.UserID, strings.TrimPrefix(r.URL.Path, "/api/tasks/"))
+	if !ok {
+		return
+	}
+
+	var req taskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+	if !h.applyTaskRequest(w, task, &req) {
+		return
+	}
+
+	task.UpdatedAt = time.Now()
+	if err := h.store.UpdateTask(task); err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to save task"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, task)
+}
+
+// DeleteTask handles DELETE /api/tasks/:id
+// Tagged completions keep the ID
+func (h *Handler) DeleteTask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	taskID := strings.TrimPrefix(r.URL.Path, "/api/tasks/")
+	if err := h.store.DeleteTask(session.UserID, taskID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, models.ErrorResponse{Error: "Task not found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to delete task"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("{}"))
+}
+
+// PatchCompletion handles PATCH /api/completions/:id
+// Tags a completion with a project and task. Given only a task, the
+// completion takes the task's project.
+func (h *Handler) PatchCompletion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	completionID := strings.TrimPrefix(r.URL.Path, "/api/completions/")
+	completion, err := h.store.GetCompletion(session.UserID, completionID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, models.ErrorResponse{Error: "Completion not found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch completion"})
+		return
+	}
+
+	var req completionPatch
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	if req.TaskID != nil {
+		completion.TaskID = *req.TaskID
+		if completion.TaskID != "" {
+			task, err := h.store.GetTask(session.UserID, completion.TaskID)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Task not found"})
+				return
+			}
+			if req.ProjectID == nil {
+				completion.ProjectID = task.ProjectID
+			} else if task.ProjectID != "" && task.ProjectID != *req.ProjectID {
+				writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Task belongs to another project"})
+				return
+			}
+		}
+	}
+	if req.ProjectID != nil {
+		completion.ProjectID = *req.ProjectID
+		if completion.ProjectID != "" {
+			if _, err := h.store.GetProject(session.UserID, completion.ProjectID); err != nil {
+				writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Project not found"})
+				return
+			}
+		}
+	}
+
+	err = h.store.TagCompletion(session.UserID, completionID, completion.ProjectID, completion.TaskID, time.Now())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to save completion"})
+		return
+	}
+
+	completion, err = h.store.GetCompletion(session.UserID, completionID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch completion"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, completion)
+}
+
+// loadProject fetches a project, writing a 404 or 500 if it can't
+func (h *Handler) loadProject(w http.ResponseWriter, userID string, projectID string) (*models.Project, bool) {
+	project, err := h.store.GetProject(userID, projectID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, models.ErrorResponse{Error: "Project not found"})
+			return nil, false
+		}
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch project"})
+		return nil, false
+	}
+	return project, true
+}
+
+// loadTask fetches a task, writing a 404 or 500 if it can't
+func (h *Handler) loadTask(w http.ResponseWriter, userID string, taskID string) (*models.Task, bool) {
+	task, err := h.store.GetTask(userID, taskID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, models.ErrorResponse{Error: "Task not found"})
+			return nil, false
+		}
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch task"})
+		return nil, false
+	}
+	return task, true
+}
+
+// applyProjectRequest copies the fields present in req onto project
+func applyProjectRequest(project *models.Project, req *projectRequest) string {
+	if req.Name != nil {
+		project.Name = strings.TrimSpace(*req.Name)
+		if project.Name == "" {
+			return "Project name is required"
+		}
+		if len(project.Name) > maxProjectName {
+			return "Project names can be at most 200 characters"
+		}
+	}
+	if req.Color != nil {
+		project.Color = strings.TrimSpace(*req.Color)
+	}
+	return ""
+}
+
+// applyTaskRequest copies the fields present in req onto task, writing a 400
+// if they are invalid
+func (h *Handler) applyTaskRequest(w http.ResponseWriter, task *models.Task, req *taskRequest) bool {
+	if req.Name != nil {
+		task.Name = strings.TrimSpace(*req.Name)
+		if task.Name == "" {
+			writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Task name is required"})
+			return false
+		}
+		if len(task.Name) > maxProjectName {
+			writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Task names can be at most 200 characters"})
+			return false
+		}
+	}
+	if req.ProjectID != nil {
+		task.ProjectID = *req.ProjectID
+		if task.ProjectID != "" {
+			if _, err := h.store.GetProject(task.UserID, task.ProjectID); err != nil {
+				writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Project not found"})
+				return false
+			}
+		}
+	}
+	if req.Done != nil {
+		task.Done = *req.Done
+	}
+	return true
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"intervals-sync/internal/models"
+	"net/http"
+	"testing"
+)
+
+func TestProjectsAndTasks(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+	token := login(t, h, "test-profile")
+
+	var project models.Project
+	if code := do(t, h.CreateProject, http.MethodPost, "/api/projects", token, `{"name":"  Thesis ","color":"#ff0000"}`, &project); code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", code)
+	}
+	if project.ID == "" || project.Name != "Thesis" {
+		t.Fatalf("unexpected project: %+v", project)
+	}
+	if code := do(t, h.CreateProject, http.MethodPost, "/api/projects", token, `{"name":" "}`, nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 without a name, got %d", code)
+	}
+
+	var renamed models.Project
+	if code := do(t, h.UpdateProject, http.MethodPatch, "/api/projects/"+project.ID, token, `{"name":"PhD"}`, &renamed); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if renamed.Name != "PhD" || renamed.Color != "#ff0000" {
+		t.Errorf("expected the name to change and the color to stay, got %+v", renamed)
+	}
+
+	var task models.Task
+	if code := do(t, h.CreateTask, http.MethodPost, "/api/tasks", token, `{"name":"Chapter 1","project_id":"`+project.ID+`"}`, &task); code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", code)
+	}
+	if code := do(t, h.CreateTask, http.MethodPost, "/api/tasks", token, `{"name":"Orphan","project_id":"missing"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown project, got %d", code)
+	}
+	do(t, h.CreateTask, http.MethodPost, "/api/tasks", token, `{"name":"Inbox"}`, nil)
+
+	var tasks struct {
+		Tasks []models.Task `json:"tasks"`
+	}
+	do(t, h.ListTasks, http.MethodGet, "/api/tasks?project_id="+project.ID, token, "", &tasks)
+	if len(tasks.Tasks) != 1 || tasks.Tasks[0].ID != task.ID {
+		t.Errorf("expected only the project's task, got %+v", tasks.Tasks)
+	}
+
+	var done models.Task
+	do(t, h.UpdateTask, http.MethodPatch, "/api/tasks/"+task.ID, token, `{"done":true}`, &done)
+	if !done.Done || done.ProjectID != project.ID {
+		t.Errorf("unexpected task: %+v", done)
+	}
+
+	// Tagging a completion with a task also sets its project
+	h.store.UpsertCompletion(&models.Completion{ID: "c1", UserID: "test-profile", WorkoutID: "w"})
+	var tagged models.Completion
+	if code := do(t, h.PatchCompletion, http.MethodPatch, "/api/completions/c1", token, `{"task_id":"`+task.ID+`"}`, &tagged); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if tagged.TaskID != task.ID || tagged.ProjectID != project.ID {
+		t.Errorf("expected the task and its project, got %+v", tagged)
+	}
+	// Clients that don't know about tags keep them when they sync the completion
+	body := `{"last_synced_at":0,"completions":[{"id":"c1","workout_id":"w","elapsed_duration":60}]}`
+	if code := do(t, h.Sync, http.MethodPost, "/api/sync", token, body, nil); code != http.StatusOK {
+		t.Fatalf("expected 200 syncing, got %d", code)
+	}
+	resynced, _ := h.store.GetCompletion("test-profile", "c1")
+	if resynced.ProjectID != project.ID || resynced.TaskID != task.ID || resynced.ElapsedDuration != 60 {
+		t.Errorf("expected sync to keep the tags, got %+v", resynced)
+	}
+
+	if code := do(t, h.PatchCompletion, http.MethodPatch, "/api/completions/c1", token, `{"project_id":"missing"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown project, got %d", code)
+	}
+	if code := do(t, h.PatchCompletion, http.MethodPatch, "/api/completions/nope", token, `{}`, nil); code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown completion, got %d", code)
+	}
+
+	var list struct {
+		Completions []models.Completion `json:"completions"`
+	}
+	do(t, h.ListCompletions, http.MethodGet, "/api/completions?project_id="+project.ID, token, "", &list)
+	if len(list.Completions) != 1 {
+		t.Errorf("expected 1 completion in the project, got %d", len(list.Completions))
+	}
+
+	var cleared models.Completion
+	do(t, h.PatchCompletion, http.MethodPatch, "/api/completions/c1", token, `{"project_id":"","task_id":""}`, &cleared)
+	if cleared.TaskID != "" || cleared.ProjectID != "" {
+		t.Errorf("expected the tags to be cleared, got %+v", cleared)
+	}
+
+	// Other profiles can't see the project
+	other := login(t, h, "other-profile")
+	if code := do(t, h.GetProject, http.MethodGet, "/api/projects/"+project.ID, other, "", nil); code != http.StatusNotFound {
+		t.Errorf("expected 404 for another profile, got %d", code)
+	}
+
+	if code := do(t, h.DeleteProject, http.MethodDelete, "/api/projects/"+project.ID, token, "", nil); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	do(t, h.GetTask, http.MethodGet, "/api/tasks/"+task.ID, token, "", &done)
+	if done.ProjectID != "" {
+		t.Errorf("expected the task to leave the deleted project, got %+v", done)
+	}
+	if code := do(t, h.DeleteTask, http.MethodDelete, "/api/tasks/"+task.ID, token, "", nil); code != http.StatusOK {
+		t.Errorf("expected 200, got %d", code)
+	}
+	if code := do(t, h.DeleteTask, http.MethodDelete, "/api/tasks/"+task.ID, token, "", nil); code != http.StatusNotFound {
+		t.Errorf("expected 404 deleting twice, got %d", code)
+	}
+}
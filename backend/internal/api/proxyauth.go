@@ -0,0 +1,118 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ProxyAuthConfig configures authentication by a trusted reverse proxy
+// (Authelia, oauth2-proxy, Tailscale) that sets a header naming the user
+type ProxyAuthConfig struct {
+	Header  string       // header carrying the profile name, e.g. Remote-User
+	Proxies []*net.IPNet // peers allowed to set the header
+}
+
+// ParseCIDRs parses CIDR ranges or bare IP addresses
+func ParseCIDRs(list []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, item := range list {
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address %q", item)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				bits = 32
+			}
+			item = fmt.Sprintf("%s/%d", item, bits)
+		}
+		_, n, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// EnableProxyAuth trusts the configured header from the configured proxies
+func (h *Handler) EnableProxyAuth(cfg ProxyAuthConfig) {
+	h.proxyAuth = &cfg
+}
+
+// proxyUser returns the profile asserted by a trusted proxy, or "" if the
+// request did not come directly from one
+func (h *Handler) proxyUser(r *http.Request) string {
+	if h.proxyAuth == nil {
+		return ""
+	}
+	user := strings.TrimSpace(r.Header.Get(h.proxyAuth.Header))
+	if user == "" {
+		return ""
+	}
+	if !ipInNets(remoteIP(r), h.proxyAuth.Proxies) {
+		return ""
+	}
+	return user
+}
+
+// TrustProxies sets the reverse proxies (e.g. Fly's edge) whose client IP
+// headers are believed when identifying clients for rate limiting
+func (h *Handler) TrustProxies(proxies []*net.IPNet) {
+	h.trustedProxies = proxies
+}
+
+// clientIP identifies the client for rate limiting and auditing. Requests
+// from a trusted proxy are attributed to Fly-Client-IP, or else to the
+// nearest untrusted hop in X-Forwarded-For; anything else to the direct peer.
+func (h *Handler) clientIP(r *http.Request) string {
+	ip := remoteIP(r)
+	if ip == nil {
+		return r.RemoteAddr
+	}
+	if !ipInNets(ip, h.trustedProxies) {
+		return ip.String()
+	}
+
+	if fly := net.ParseIP(strings.TrimSpace(r.Header.Get("Fly-Client-IP"))); fly != nil {
+		return fly.String()
+	}
+	// Walk X-Forwarded-For from the right, skipping our own proxies; the
+	// left-most entries are whatever the client chose to send
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+		ip = hop
+		if !ipInNets(hop, h.trustedProxies) {
+			break
+		}
+	}
+	return ip.String()
+}
+
+// remoteIP returns the IP of the direct peer, without the port
+func remoteIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+func ipInNets(ip net.IP, nets []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
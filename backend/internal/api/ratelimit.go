@@ -1,78 +1,192 @@
 package api
 
 import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
 
+// Limit is a token bucket: Rate tokens per second, up to Burst
+type Limit struct {
+	Rate  float64
+	Burst float64
+}
+
+// DefaultLimits are the built-in limits by name. Requests checked against an
+// unknown name are not limited.
+var DefaultLimits = map[string]Limit{
+	"login":    {Rate: 0.5, Burst: 2},  // 1 request per 2 seconds
+	"admin":    {Rate: 1, Burst: 10},   // 1 request per second
+	"pair":     {Rate: 0.1, Burst: 3},  // 1 request per 10 seconds
+	"pair_all": {Rate: 0.5, Burst: 10}, // all clients together
+	"sync":     {Rate: 10, Burst: 20},  // per profile
+}
+
+// bucketSweepInterval is how often idle buckets are evicted
+const bucketSweepInterval = time.Minute
+
 // RateLimiter implements token bucket rate limiting
 type RateLimiter struct {
-	mu      sync.Mutex
-	buckets map[string]*bucket
+	mu        sync.Mutex
+	limits    map[string]Limit
+	buckets   map[string]*bucket
+	lastSweep time.Time
 }
 
 type bucket struct {
-	tokens    float64
+	tokens     float64
 	lastRefill time.Time
-	rate      float64    // tokens per second
-	capacity  float64
+	limit      Limit
+}
+
+// RateDecision is the outcome of a rate limit check
+type RateDecision struct {
+	Allowed    bool
+	Limit      int           // bucket size
+	Remaining  int           // whole tokens left after this request
+	RetryAfter time.Duration // until the next token, when denied
+	Reset      time.Duration // until the bucket is full again
 }
 
-// NewRateLimiter creates a new rate limiter
+// NewRateLimiter creates a new rate limiter with the default limits
 func NewRateLimiter() *RateLimiter {
+	limits := make(map[string]Limit, len(DefaultLimits))
+	for name, l := range DefaultLimits {
+		limits[name] = l
+	}
 	return &RateLimiter{
-		buckets: make(map[string]*bucket),
+		limits:    limits,
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
 	}
 }
 
-// Allow checks if a request is allowed based on the rate limit for a key
-// rate is number of requests per second (e.g., 0.5 = 1 request per 2 seconds)
-func (rl *RateLimiter) Allow(key string, limitType string) bool {
+// SetLimits adds or replaces limits. A name of the form "<limit>@<key>"
+// overrides the limit for one key, e.g. "sync@alice" for one profile.
+func (rl *RateLimiter) SetLimits(limits map[string]Limit) {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
+	for name, l := range limits {
+		rl.limits[name] = l
+	}
+}
 
-	var rate, capacity float64
-	switch limitType {
-	case "login":
-		rate = 0.5      // 1 request per 2 seconds
-		capacity = 2.0  // Allow burst of 2
-	case "sync":
-		rate = 10.0     // 10 requests per second
-		capacity = 20.0
-	default:
-		return true
+// ParseRateLimits parses RATE_LIMITS entries of the form
+// name=rate/burst, e.g. "login=0.5/2" or "sync@alice=50/100"
+func ParseRateLimits(list []string) (map[string]Limit, error) {
+	limits := make(map[string]Limit, len(list))
+	for _, item := range list {
+		name, spec, ok := strings.Cut(item, "=")
+		rate, burst, ok2 := strings.Cut(spec, "/")
+		if !ok || !ok2 || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid rate limit %q, expected name=rate/burst", item)
+		}
+		r, err := strconv.ParseFloat(strings.TrimSpace(rate), 64)
+		if err != nil || r <= 0 {
+			return nil, fmt.Errorf("invalid rate in %q", item)
+		}
+		b, err := strconv.ParseFloat(strings.TrimSpace(burst), 64)
+		if err != nil || b < 1 {
+			return nil, fmt.Errorf("invalid burst in %q", item)
+		}
+		limits[strings.TrimSpace(name)] = Limit{Rate: r, Burst: b}
+	}
+	return limits, nil
+}
+
+// Take consumes a token from the key's bucket for a limit, if one is available
+func (rl *RateLimiter) Take(key string, limitType string) RateDecision {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	limit, ok := rl.limits[limitType+"@"+key]
+	if !ok {
+		if limit, ok = rl.limits[limitType]; !ok {
+			return RateDecision{Allowed: true}
+		}
+	}
+
+	now := time.Now()
+	if now.Sub(rl.lastSweep) >= bucketSweepInterval {
+		rl.sweep(now)
 	}
 
 	bucketKey := key + ":" + limitType
 	b, exists := rl.buckets[bucketKey]
-	if !exists {
+	if !exists || b.limit != limit {
 		b = &bucket{
-			tokens:     capacity,
-			lastRefill: time.Now(),
-			rate:       rate,
-			capacity:   capacity,
+			tokens:     limit.Burst,
+			lastRefill: now,
+			limit:      limit,
 		}
 		rl.buckets[bucketKey] = b
 	}
 
 	// Refill tokens
-	now := time.Now()
-	elapsed := now.Sub(b.lastRefill).Seconds()
-	b.tokens = min(b.capacity, b.tokens+elapsed*b.rate)
-	b.lastRefill = now
+	b.refill(now)
 
-	// Check if request is allowed
+	decision := RateDecision{Limit: int(limit.Burst)}
 	if b.tokens >= 1.0 {
 		b.tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = secondsToDuration((1 - b.tokens) / limit.Rate)
+	}
+	decision.Remaining = int(math.Floor(b.tokens))
+	decision.Reset = secondsToDuration((limit.Burst - b.tokens) / limit.Rate)
+	return decision
+}
+
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.lastRefill).Seconds()
+	b.tokens = math.Min(b.limit.Burst, b.tokens+elapsed*b.limit.Rate)
+	b.lastRefill = now
+}
+
+// sweep evicts buckets that have refilled completely. A full bucket behaves
+// exactly like a new one, so evicting it loses nothing. rl.mu must be held.
+func (rl *RateLimiter) sweep(now time.Time) {
+	for key, b := range rl.buckets {
+		b.refill(now)
+		if b.tokens >= b.limit.Burst {
+			delete(rl.buckets, key)
+		}
+	}
+	rl.lastSweep = now
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// rateLimit checks a request against a limit, keyed by client IP unless a key
+// is given, and sets the X-RateLimit-* headers. When the request is denied it
+// writes a 429 with Retry-After and returns false.
+func (h *Handler) rateLimit(w http.ResponseWriter, r *http.Request, limitType string, key string, message string) bool {
+	if key == "" {
+		key = h.clientIP(r)
+	}
+	decision := h.rl.Take(key, limitType)
+	if decision.Limit == 0 {
 		return true
 	}
 
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(decision.Limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(decision.Reset)))
+	if decision.Allowed {
+		return true
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(decision.RetryAfter)))
+	http.Error(w, message, http.StatusTooManyRequests)
 	return false
 }
 
-func min(a, b float64) float64 {
-	if a < b {
-		return a
-	}
-	return b
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
 }
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimits(t *testing.T) {
+	limits, err := ParseRateLimits([]string{"login=1/5", "sync@alice=50/100"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limits["login"] != (Limit{Rate: 1, Burst: 5}) || limits["sync@alice"] != (Limit{Rate: 50, Burst: 100}) {
+		t.Errorf("unexpected limits: %+v", limits)
+	}
+
+	for _, bad := range []string{"login", "login=1", "login=0/5", "login=1/0", "=1/5"} {
+		if _, err := ParseRateLimits([]string{bad}); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestRateLimiterOverrides(t *testing.T) {
+	rl := NewRateLimiter()
+	rl.SetLimits(map[string]Limit{
+		"sync":       {Rate: 1, Burst: 1},
+		"sync@alice": {Rate: 1, Burst: 3},
+	})
+
+	if !rl.Take("bob", "sync").Allowed || rl.Take("bob", "sync").Allowed {
+		t.Error("expected bob to get the default burst of 1")
+	}
+	for i := 0; i < 3; i++ {
+		if !rl.Take("alice", "sync").Allowed {
+			t.Fatalf("expected alice's request %d to be allowed", i+1)
+		}
+	}
+	d := rl.Take("alice", "sync")
+	if d.Allowed || d.Remaining != 0 || d.RetryAfter <= 0 || d.RetryAfter > time.Second {
+		t.Errorf("unexpected decision after alice's burst: %+v", d)
+	}
+
+	if !rl.Take("anyone", "unknown").Allowed {
+		t.Error("expected unknown limits to allow everything")
+	}
+}
+
+func TestRateLimiterEvictsIdleBuckets(t *testing.T) {
+	rl := NewRateLimiter()
+	rl.SetLimits(map[string]Limit{"fast": {Rate: 1000, Burst: 1}})
+	rl.Take("a", "fast")
+	rl.Take("b", "login")
+	if len(rl.buckets) != 2 {
+		t.Fatalf("expected 2 buckets, got %d", len(rl.buckets))
+	}
+
+	// Only the bucket that has refilled is dropped
+	time.Sleep(5 * time.Millisecond)
+	rl.lastSweep = time.Now().Add(-bucketSweepInterval)
+	rl.Take("c", "unknown-limit")
+	rl.Take("b", "login")
+	if _, ok := rl.buckets["a:fast"]; ok {
+		t.Error("expected idle bucket to be evicted")
+	}
+	if _, ok := rl.buckets["b:login"]; !ok {
+		t.Error("expected partially used bucket to be kept")
+	}
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	var w *httptest.ResponseRecorder
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/test", bytes.NewBufferString(`{}`))
+		// A different port on each request is still the same client
+		req.RemoteAddr = fmt.Sprintf("198.51.100.7:%d", 40000+i)
+		w = httptest.NewRecorder()
+		h.TestConnection(w, req)
+	}
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 on the third attempt, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "2" {
+		t.Errorf("expected Retry-After 2, got %q", w.Header().Get("Retry-After"))
+	}
+	if w.Header().Get("X-RateLimit-Limit") != "2" || w.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("unexpected rate limit headers: %v", w.Header())
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	proxies, _ := ParseCIDRs([]string{"10.0.0.0/8"})
+	h.TrustProxies(proxies)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		expected   string
+	}{
+		{"direct peer", "203.0.113.5:4321", nil, "203.0.113.5"},
+		{"untrusted peer ignores headers", "203.0.113.5:4321", map[string]string{"Fly-Client-IP": "1.2.3.4"}, "203.0.113.5"},
+		{"fly header from proxy", "10.1.2.3:80", map[string]string{"Fly-Client-IP": "198.51.100.9"}, "198.51.100.9"},
+		{"forwarded for skips trusted hops", "10.1.2.3:80", map[string]string{"X-Forwarded-For": "6.6.6.6, 198.51.100.9, 10.0.0.2"}, "198.51.100.9"},
+		{"proxy without headers", "10.1.2.3:80", nil, "10.1.2.3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			if got := h.clientIP(req); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
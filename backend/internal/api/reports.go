@@ -0,0 +1,175 @@
+package api
+
+import (
+	"encoding/csv"
+	"intervals-sync/internal/models"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// noProjectName labels time that isn't tagged with a known project
+const noProjectName = "No project"
+
+// TimeReport handles GET /api/reports/time
+// Sums the elapsed time of completions per project per day or week; see
+// parseReportRange for the parameters. A completion counts towards its
+// project, or else its task's project. ?format=csv (or Accept: text/csv)
+// returns the rows as CSV.
+func (h *Handler) TimeReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	asCSV := false
+	switch strings.ToLower(query.Get("format")) {
+	case "":
+		asCSV = strings.Contains(r.Header.Get("Accept"), "text/csv")
+	case "json":
+	case "csv":
+		asCSV = true
+	default:
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "format must be json or csv"})
+		return
+	}
+
+	rr, msg := parseReportRange(query, time.Now())
+	if msg != "" {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: msg})
+		return
+	}
+
+	projects, err := h.store.ListProjects(session.UserID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch projects"})
+		return
+	}
+	tasks, err := h.store.ListTasks(session.UserID, "")
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch tasks"})
+		return
+	}
+	names := map[string]string{"": noProjectName}
+	for _, p := range projects {
+		names[p.ID] = p.Name
+	}
+	taskProjects := map[string]string{}
+	for _, t := range tasks {
+		taskProjects[t.ID] = t.ProjectID
+	}
+
+	type rowKey struct{ start, projectID string }
+	rows := map[rowKey]*models.TimeReportRow{}
+	totals := map[string]*models.TimeReportRow{}
+	err = h.eachCompletion(session.UserID, rr.From, rr.To, func(c *models.Completion) {
+		projectID := c.ProjectID
+		if projectID == "" {
+			projectID = taskProjects[c.TaskID]
+		}
+		// Time of deleted projects is reported without a project
+		if _, ok := names[projectID]; !ok {
+			projectID = ""
+		}
+		key := rowKey{rr.start(c.StartedAt).Format("2006-01-02"), projectID}
+		if rows[key] == nil {
+			rows[key] = &models.TimeReportRow{Start: key.start, ProjectID: projectID, ProjectName: names[projectID]}
+		}
+		if totals[projectID] == nil {
+			totals[projectID] = &models.TimeReportRow{ProjectID: projectID, ProjectName: names[projectID]}
+		}
+		for _, row := range []*models.TimeReportRow{rows[key], totals[projectID]} {
+			row.Seconds += c.ElapsedDuration
+			row.Sessions++
+		}
+	})
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch completions"})
+		return
+	}
+
+	report := models.TimeReport{
+		Period:   rr.Period,
+		TimeZone: rr.Location.String(),
+		Rows:     make([]models.TimeReportRow, 0, len(rows)),
+		Totals:   make([]models.TimeReportRow, 0, len(totals)),
+	}
+	for _, row := range rows {
+		report.Rows = append(report.Rows, *row)
+	}
+	for _, row := range totals {
+		report.Totals = append(report.Totals, *row)
+	}
+	sortReportRows(report.Rows)
+	sortReportRows(report.Totals)
+
+	if asCSV {
+		writeTimeReportCSV(w, &report, rr)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// sortReportRows orders rows by period, then project name, with time without
+// a project last
+func sortReportRows(rows []models.TimeReportRow) {
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		if (a.ProjectID == "") != (b.ProjectID == "") {
+			return b.ProjectID == ""
+		}
+		if a.ProjectName != b.ProjectName {
+			return strings.ToLower(a.ProjectName) < strings.ToLower(b.ProjectName)
+		}
+		return a.ProjectID < b.ProjectID
+	})
+}
+
+// writeTimeReportCSV writes the rows of a report, followed by the totals
+// with "total" as their start, as a CSV attachment
+func writeTimeReportCSV(w http.ResponseWriter, report *models.TimeReport, rr reportRange) {
+	filename := "time-" + rr.From.Format("2006-01-02") + "-" + rr.To.Format("2006-01-02") + ".csv"
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"start", "project_id", "project", "seconds", "hours", "sessions"})
+	write := func(start string, row models.TimeReportRow) {
+		cw.Write([]string{
+			start,
+			csvText(row.ProjectID),
+			csvText(row.ProjectName),
+			strconv.Itoa(row.Seconds),
+			strconv.FormatFloat(float64(row.Seconds)/3600, 'f', 2, 64),
+			strconv.Itoa(row.Sessions),
+		})
+	}
+	for _, row := range report.Rows {
+		write(row.Start, row)
+	}
+	for _, row := range report.Totals {
+		write("total", row)
+	}
+	cw.Flush()
+}
+
+// csvText keeps spreadsheet apps from running user-provided text as a
+// formula by prefixing it with a quote
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
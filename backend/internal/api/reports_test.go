@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/csv"
+	"intervals-sync/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeReport(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+	token := login(t, h, "test-profile")
+
+	var writing, coding models.Project
+	do(t, h.CreateProject, http.MethodPost, "/api/projects", token, `{"name":"Writing"}`, &writing)
+	do(t, h.CreateProject, http.MethodPost, "/api/projects", token, `{"name":"Coding"}`, &coding)
+	var task models.Task
+	do(t, h.CreateTask, http.MethodPost, "/api/tasks", token, `{"name":"Review","project_id":"`+coding.ID+`"}`, &task)
+
+	at := func(s string) time.Time {
+		ts, _ := time.Parse(time.RFC3339, s)
+		return ts
+	}
+	for _, c := range []models.Completion{
+		{ID: "c1", StartedAt: at("2026-03-02T09:00:00Z"), ElapsedDuration: 1500, ProjectID: writing.ID},
+		{ID: "c2", StartedAt: at("2026-03-02T10:00:00Z"), ElapsedDuration: 1800, ProjectID: coding.ID},
+		{ID: "c3", StartedAt: at("2026-03-02T11:00:00Z"), ElapsedDuration: 900, TaskID: task.ID}, // counted through its task
+		{ID: "c4", StartedAt: at("2026-03-03T09:00:00Z"), ElapsedDuration: 600},
+		{ID: "c5", StartedAt: at("2026-03-03T10:00:00Z"), ElapsedDuration: 3600, ProjectID: writing.ID},
+	} {
+		c.UserID = "test-profile"
+		c.WorkoutID = "w"
+		if err := h.store.UpsertCompletion(&c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var report models.TimeReport
+	if code := do(t, h.TimeReport, http.MethodGet, "/api/reports/time?from=2026-03-02&to=2026-03-04", token, "", &report); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	want := []models.TimeReportRow{
+		{Start: "2026-03-02", ProjectID: coding.ID, ProjectName: "Coding", Seconds: 2700, Sessions: 2},
+		{Start: "2026-03-02", ProjectID: writing.ID, ProjectName: "Writing", Seconds: 1500, Sessions: 1},
+		{Start: "2026-03-03", ProjectID: writing.ID, ProjectName: "Writing", Seconds: 3600, Sessions: 1},
+		{Start: "2026-03-03", ProjectID: "", ProjectName: "No project", Seconds: 600, Sessions: 1},
+	}
+	if len(report.Rows) != len(want) {
+		t.Fatalf("expected %d rows, got %+v", len(want), report.Rows)
+	}
+	for i := range want {
+		if report.Rows[i] != want[i] {
+			t.Errorf("row %d: expected %+v, got %+v", i, want[i], report.Rows[i])
+		}
+	}
+	if len(report.Totals) != 3 || report.Totals[1].ProjectName != "Writing" || report.Totals[1].Seconds != 5100 {
+		t.Errorf("unexpected totals: %+v", report.Totals)
+	}
+
+	// Weeks combine the days
+	do(t, h.TimeReport, http.MethodGet, "/api/reports/time?period=week&from=2026-03-02&to=2026-03-09", token, "", &report)
+	if len(report.Rows) != 3 || report.Rows[1].Seconds != 5100 {
+		t.Errorf("unexpected weekly rows: %+v", report.Rows)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/reports/time?from=2026-03-02&to=2026-03-04&format=csv", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	h.TimeReport(w, req)
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("expected CSV, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(w.Header().Get("Content-Disposition"), "attachment") {
+		t.Errorf("expected an attachment, got %q", w.Header().Get("Content-Disposition"))
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if len(records) != 8 || strings.Join(records[0], ",") != "start,project_id,project,seconds,hours,sessions" {
+		t.Fatalf("unexpected CSV: %v", records)
+	}
+	if got := strings.Join(records[1], ","); got != "2026-03-02,"+coding.ID+",Coding,2700,0.75,2" {
+		t.Errorf("unexpected first row: %s", got)
+	}
+
+	if got := strings.Join(records[5], ","); got != "total,"+coding.ID+",Coding,2700,0.75,2" {
+		t.Errorf("unexpected first total: %s", got)
+	}
+
+	// Names that look like formulas are neutralized
+	do(t, h.UpdateProject, http.MethodPatch, "/api/projects/"+writing.ID, token, `{"name":"=HYPERLINK(\"http://evil\")"}`, nil)
+	req = httptest.NewRequest(http.MethodGet, "/api/reports/time?from=2026-03-02&to=2026-03-04", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "text/csv")
+	w = httptest.NewRecorder()
+	h.TimeReport(w, req)
+	records, _ = csv.NewReader(w.Body).ReadAll()
+	if len(records) < 2 || records[1][2] != "'=HYPERLINK(\"http://evil\")" {
+		t.Errorf("expected the formula to be quoted, got %v", records)
+	}
+
+	if code := do(t, h.TimeReport, http.MethodGet, "/api/reports/time?format=xml", token, "", nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown format, got %d", code)
+	}
+}
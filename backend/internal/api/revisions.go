@@ -0,0 +1,293 @@
+package api
+
+import (
+	"errors"
+	"intervals-sync/internal/models"
+	"intervals-sync/internal/store"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Revision sources, recording how a workout was changed
+const (
+	revisionSourceSync    = "sync"
+	revisionSourceAPI     = "api"
+	revisionSourceRestore = "restore"
+)
+
+// maxDeviceNameLength caps the X-Device-Name stored with a revision
+const maxDeviceNameLength = 100
+
+// ListWorkoutRevisions handles GET /api/workouts/:id/revisions
+// Returns the workout's revisions, newest first
+func (h *Handler) ListWorkoutRevisions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	workoutID, _ := revisionPath(r)
+	revisions, err := h.store.ListWorkoutRevisions(session.UserID, workoutID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch revisions"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"revisions": revisions,
+	})
+}
+
+// GetWorkoutRevision handles GET /api/workouts/:id/revisions/:version
+func (h *Handler) GetWorkoutRevision(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	workoutID, version := revisionPath(r)
+	revision, ok := h.loadRevision(w, session.UserID, workoutID, version)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, revision)
+}
+
+// DiffWorkoutRevisions handles GET /api/workouts/:id/revisions/diff?from=&to=
+// to defaults to the latest revision
+func (h *Handler) DiffWorkoutRevisions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	workoutID, _ := revisionPath(r)
+	query := r.URL.Query()
+	from, ok := h.loadRevision(w, session.UserID, workoutID, query.Get("from"))
+	if !ok {
+		return
+	}
+
+	var to *models.WorkoutRevision
+	if v := query.Get("to"); v != "" {
+		if to, ok = h.loadRevision(w, session.UserID, workoutID, v); !ok {
+			return
+		}
+	} else {
+		revisions, err := h.store.ListWorkoutRevisions(session.UserID, workoutID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch revisions"})
+			return
+		}
+		to = &revisions[0] // from exists, so there is at least one revision
+	}
+
+	writeJSON(w, http.StatusOK, diffRevisions(from, to))
+}
+
+// RestoreWorkoutRevision handles POST /api/workouts/:id/revisions/:version/restore
+// Saves the revision's content as the current version of the workout
+func (h *Handler) RestoreWorkoutRevision(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	workoutID, version := revisionPath(r)
+	workout, err := h.store.GetWorkout(session.UserID, workoutID)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, models.ErrorResponse{Error: "Workout not found"})
+		return
+	}
+	ifVersion, ok := h.checkIfMatch(w, r, workout)
+	if !ok {
+		return
+	}
+	revision, ok := h.loadRevision(w, session.UserID, workoutID, version)
+	if !ok {
+		return
+	}
+
+	workout.Name = revision.Snapshot.Name
+	workout.Rounds = revision.Snapshot.Rounds
+	workout.Intervals = revision.Snapshot.Intervals
+	workout.Blocks = revision.Snapshot.Blocks
+	workout.Kind = revision.Snapshot.Kind
+	workout.Period = revision.Snapshot.Period
+	workout.TimeCap = revision.Snapshot.TimeCap
+	workout.Pomodoro = revision.Snapshot.Pomodoro
+	if !h.saveWorkout(w, r, workout, ifVersion, revisionSourceRestore) {
+		return
+	}
+
+	w.Header().Set("ETag", workoutETag(workout))
+	writeJSON(w, http.StatusOK, workout)
+}
+
+// recordRevision adds a workout's current content to its history. Errors are
+// logged; the change itself has already been saved.
+func (h *Handler) recordRevision(r *http.Request, workout *models.Workout, source string) {
+	revision := models.WorkoutRevision{
+		WorkoutID: workout.ID,
+		UserID:    workout.UserID,
+		Version:   workout.Version,
+		Device:    deviceName(r),
+		Source:    source,
+		Snapshot:  workoutSnapshot(workout),
+	}
+	if err := h.store.AddWorkoutRevision(&revision); err != nil {
+		log.Printf("Failed to record revision of workout %s: %v\n", workout.ID, err)
+	}
+}
+
+// loadRevision parses a version and fetches that revision. It writes the
+// error response and returns false if there is none.
+func (h *Handler) loadRevision(w http.ResponseWriter, userID string, workoutID string, version string) (*models.WorkoutRevision, bool) {
+	v, err := strconv.ParseInt(version, 10, 64)
+	if err != nil || v < 1 {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Invalid revision version"})
+		return nil, false
+	}
+	revision, err := h.store.GetWorkoutRevision(userID, workoutID, v)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, models.ErrorResponse{Error: "Revision not found"})
+			return nil, false
+		}
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch revision"})
+		return nil, false
+	}
+	return revision, true
+}
+
+// diffRevisions compares two revisions, matching intervals by ID
+func diffRevisions(from *models.WorkoutRevision, to *models.WorkoutRevision) models.WorkoutDiff {
+	diff := models.WorkoutDiff{
+		From:             from.Version,
+		To:               to.Version,
+		AddedIntervals:   []models.Interval{},
+		RemovedIntervals: []models.Interval{},
+		ChangedIntervals: []models.IntervalChange{},
+	}
+	a, b := from.Snapshot, to.Snapshot
+	if a.Name != b.Name {
+		diff.Name = &models.ValueChange{From: a.Name, To: b.Name}
+	}
+	if a.Rounds != b.Rounds {
+		diff.Rounds = &models.ValueChange{From: a.Rounds, To: b.Rounds}
+	}
+	if !reflect.DeepEqual(a.Blocks, b.Blocks) {
+		diff.Blocks = &models.ValueChange{From: a.Blocks, To: b.Blocks}
+	}
+	if a.Kind != b.Kind {
+		diff.Kind = &models.ValueChange{From: a.Kind, To: b.Kind}
+	}
+	if a.Period != b.Period {
+		diff.Period = &models.ValueChange{From: a.Period, To: b.Period}
+	}
+	if a.TimeCap != b.TimeCap {
+		diff.TimeCap = &models.ValueChange{From: a.TimeCap, To: b.TimeCap}
+	}
+	if !reflect.DeepEqual(a.Pomodoro, b.Pomodoro) {
+		diff.Pomodoro = &models.ValueChange{From: a.Pomodoro, To: b.Pomodoro}
+	}
+
+	before := make(map[string]models.Interval, len(a.Intervals))
+	for _, interval := range a.Intervals {
+		before[interval.ID] = interval
+	}
+	for _, interval := range b.Intervals {
+		old, ok := before[interval.ID]
+		switch {
+		case !ok:
+			diff.AddedIntervals = append(diff.AddedIntervals, interval)
+		case !reflect.DeepEqual(old, interval):
+			diff.ChangedIntervals = append(diff.ChangedIntervals, models.IntervalChange{ID: interval.ID, From: old, To: interval})
+		}
+		delete(before, interval.ID)
+	}
+	for _, interval := range a.Intervals {
+		if _, ok := before[interval.ID]; ok {
+			diff.RemovedIntervals = append(diff.RemovedIntervals, interval)
+		}
+	}
+	return diff
+}
+
+// workoutSnapshot copies a workout's editable content
+func workoutSnapshot(workout *models.Workout) models.WorkoutSnapshot {
+	intervals := append([]models.Interval{}, workout.Intervals...)
+	return models.WorkoutSnapshot{
+		Name:      workout.Name,
+		Rounds:    workout.Rounds,
+		Intervals: intervals,
+		Blocks:    workout.Blocks,
+		Kind:      workout.Kind,
+		Period:    workout.Period,
+		TimeCap:   workout.TimeCap,
+		Pomodoro:  workout.Pomodoro,
+	}
+}
+
+// currentSnapshot snapshots a workout as it is now, for new completions sent
+// without one. Snapshots are kept in cache so each workout is read once per
+// request. It returns nil if the workout no longer exists.
+func (h *Handler) currentSnapshot(userID string, workoutID string, cache map[string]*models.WorkoutSnapshot) *models.WorkoutSnapshot {
+	if workoutID == "" {
+		return nil
+	}
+	if snapshot, ok := cache[workoutID]; ok {
+		return snapshot
+	}
+	var snapshot *models.WorkoutSnapshot
+	if workout, err := h.store.GetWorkout(userID, workoutID); err == nil {
+		s := workoutSnapshot(workout)
+		snapshot = &s
+	}
+	cache[workoutID] = snapshot
+	return snapshot
+}
+
+// deviceName identifies the device making a change from X-Device-Name
+func deviceName(r *http.Request) string {
+	name := strings.TrimSpace(r.Header.Get("X-Device-Name"))
+	if runes := []rune(name); len(runes) > maxDeviceNameLength {
+		name = string(runes[:maxDeviceNameLength])
+	}
+	return name
+}
+
+// revisionPath extracts the workout ID and version from
+// /api/workouts/:id/revisions[/:version[/restore]]
+func revisionPath(r *http.Request) (workoutID string, version string) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/workouts/"), "/")
+	workoutID = parts[0]
+	if len(parts) >= 3 && parts[1] == "revisions" {
+		version = parts[2]
+	}
+	return workoutID, version
+}
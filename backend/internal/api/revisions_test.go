@@ -0,0 +1,126 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"intervals-sync/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWorkoutRevisions(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+	token := login(t, h, "test-profile")
+
+	var workout models.Workout
+	do(t, h.CreateWorkout, http.MethodPost, "/api/workouts", token, `{"name":"Tabata","rounds":8,"intervals":[{"name":"Work","duration":20},{"name":"Rest","duration":10}]}`, &workout)
+	base := "/api/workouts/" + workout.ID + "/revisions"
+
+	// A phone changes the workout through sync, then re-sends it unchanged
+	synced := workout
+	synced.Rounds = 10
+	synced.Intervals = []models.Interval{workout.Intervals[0], {ID: "cooldown", Name: "Cooldown", Duration: 60, Position: 1}}
+	synced.UpdatedAt = time.Now()
+	for i := 0; i < 2; i++ {
+		body, _ := json.Marshal(models.SyncPayload{Workouts: []models.Workout{synced}})
+		req := httptest.NewRequest(http.MethodPost, "/api/sync", bytes.NewBuffer(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("X-Device-Name", "Coach's phone")
+		w := httptest.NewRecorder()
+		h.Sync(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("sync failed: %d %s", w.Code, w.Body.String())
+		}
+		h.rl = NewRateLimiter()
+	}
+
+	var list struct {
+		Revisions []models.WorkoutRevision `json:"revisions"`
+	}
+	if code := do(t, h.ListWorkoutRevisions, http.MethodGet, base, token, "", &list); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if len(list.Revisions) != 2 {
+		t.Fatalf("expected 2 revisions (unchanged sync skipped), got %+v", list.Revisions)
+	}
+	latest, first := list.Revisions[0], list.Revisions[1]
+	if latest.Version != 2 || latest.Source != "sync" || latest.Device != "Coach's phone" || latest.Snapshot.Rounds != 10 {
+		t.Errorf("unexpected latest revision: %+v", latest)
+	}
+	if first.Version != 1 || first.Source != "api" || first.Snapshot.Name != "Tabata" {
+		t.Errorf("unexpected first revision: %+v", first)
+	}
+
+	var diff models.WorkoutDiff
+	if code := do(t, h.DiffWorkoutRevisions, http.MethodGet, base+"/diff?from=1", token, "", &diff); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if diff.To != latest.Version || diff.Name != nil || diff.Rounds == nil || diff.Rounds.To != float64(10) {
+		t.Errorf("unexpected diff of workout fields: %+v", diff)
+	}
+	if len(diff.AddedIntervals) != 1 || diff.AddedIntervals[0].Name != "Cooldown" ||
+		len(diff.RemovedIntervals) != 1 || diff.RemovedIntervals[0].Name != "Rest" || len(diff.ChangedIntervals) != 0 {
+		t.Errorf("unexpected diff of intervals: %+v", diff)
+	}
+
+	// Restoring the first revision makes it current again, as a new version
+	var restored models.Workout
+	if code := do(t, h.RestoreWorkoutRevision, http.MethodPost, base+"/1/restore", token, "", &restored); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if restored.Rounds != 8 || len(restored.Intervals) != 2 || restored.Intervals[1].Name != "Rest" || restored.Version <= latest.Version {
+		t.Errorf("unexpected restored workout: %+v", restored)
+	}
+	var revision models.WorkoutRevision
+	do(t, h.GetWorkoutRevision, http.MethodGet, base+"/"+strconv.FormatInt(restored.Version, 10), token, "", &revision)
+	if revision.Source != "restore" || revision.Snapshot.Rounds != 8 {
+		t.Errorf("expected the restore to be recorded, got %+v", revision)
+	}
+
+	for path, expected := range map[string]int{
+		base + "/99":          http.StatusNotFound,
+		base + "/latest":      http.StatusBadRequest,
+		base + "/diff?from=0": http.StatusBadRequest,
+	} {
+		handler := h.GetWorkoutRevision
+		if strings.Contains(path, "/diff") {
+			handler = h.DiffWorkoutRevisions
+		}
+		if code := do(t, handler, http.MethodGet, path, token, "", nil); code != expected {
+			t.Errorf("%s: expected %d, got %d", path, expected, code)
+		}
+	}
+
+	// Other profiles see no history
+	other := login(t, h, "other-profile")
+	do(t, h.ListWorkoutRevisions, http.MethodGet, base, other, "", &list)
+	if len(list.Revisions) != 0 {
+		t.Errorf("expected no revisions for another profile, got %+v", list.Revisions)
+	}
+}
+
+func TestDiffRevisionsComparesIntervalValues(t *testing.T) {
+	snapshot := func() models.WorkoutSnapshot {
+		var s models.WorkoutSnapshot
+		json.Unmarshal([]byte(`{"name":"Ladder","rounds":3,"intervals":[
+			{"id":"i1","name":"Work","duration":30,"progression":{"type":"step","step":10},"condition":{"skip_last_round":true}}
+		]}`), &s)
+		return s
+	}
+	from := &models.WorkoutRevision{Version: 1, Snapshot: snapshot()}
+	to := &models.WorkoutRevision{Version: 2, Snapshot: snapshot()}
+
+	if diff := diffRevisions(from, to); len(diff.ChangedIntervals) != 0 {
+		t.Errorf("expected identical intervals to be unchanged, got %+v", diff.ChangedIntervals)
+	}
+
+	to.Snapshot.Intervals[0].Progression.Step = 15
+	if diff := diffRevisions(from, to); len(diff.ChangedIntervals) != 1 {
+		t.Errorf("expected the changed progression, got %+v", diff.ChangedIntervals)
+	}
+}
@@ -0,0 +1,239 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"intervals-sync/internal/models"
+	"intervals-sync/internal/store"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// signedSessionPrefix distinguishes signed session tokens from stored ones
+const signedSessionPrefix = "ivs1."
+
+// Defaults for SignedSessionConfig
+const (
+	defaultSignedSessionTTL    = 30 * 24 * time.Hour
+	defaultRevocationRefresh   = 30 * time.Second
+	minSessionSigningKeyLength = 32
+)
+
+var errInvalidSignedSession = errors.New("invalid signed session token")
+
+// SignedSessionConfig configures stateless session tokens, which are verified
+// with a server-held key instead of a database lookup
+type SignedSessionConfig struct {
+	Key             []byte        // HMAC-SHA256 key, shared by every server instance
+	TTL             time.Duration // token lifetime, default 30 days
+	RefreshInterval time.Duration // how often revocations are reloaded, default 30s
+}
+
+// signedSessionClaims is the payload of a signed session token
+type signedSessionClaims struct {
+	ID        string   `json:"jti"`
+	UserID    string   `json:"sub"`
+	Scopes    []string `json:"scp,omitempty"`
+	IssuedAt  int64    `json:"iat"` // unix milliseconds
+	ExpiresAt int64    `json:"exp"` // unix milliseconds
+}
+
+// signedSessions issues and verifies signed tokens, keeping the revocation
+// list from the store in memory
+type signedSessions struct {
+	key     []byte
+	ttl     time.Duration
+	refresh time.Duration
+
+	mu          sync.Mutex
+	revocations []models.SessionRevocation
+	loadedAt    time.Time
+}
+
+// EnableSignedSessions makes new sessions signed tokens. Existing stored
+// sessions keep working until they are logged out.
+func (h *Handler) EnableSignedSessions(cfg SignedSessionConfig) error {
+	if len(cfg.Key) < minSessionSigningKeyLength {
+		return fmt.Errorf("session signing key must be at least %d bytes", minSessionSigningKeyLength)
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = defaultSignedSessionTTL
+	}
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = defaultRevocationRefresh
+	}
+	h.signed = &signedSessions{
+		key:     cfg.Key,
+		ttl:     cfg.TTL,
+		refresh: cfg.RefreshInterval,
+	}
+	return nil
+}
+
+// createSession starts a full-access session for a profile and returns its token
+func (h *Handler) createSession(userID string) (string, error) {
+	if h.signed == nil {
+		session, err := h.store.CreateSession(generateToken(), userID)
+		if err != nil {
+			return "", err
+		}
+		return session.Token, nil
+	}
+
+	now := time.Now()
+	return h.signed.sign(signedSessionClaims{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		IssuedAt:  now.UnixMilli(),
+		ExpiresAt: now.Add(h.signed.ttl).UnixMilli(),
+	})
+}
+
+// deleteSession logs out a stored or signed session token
+func (h *Handler) deleteSession(token string) error {
+	if !strings.HasPrefix(token, signedSessionPrefix) {
+		return h.store.DeleteSession(token)
+	}
+	if h.signed == nil {
+		return nil
+	}
+
+	claims, err := h.signed.parse(token)
+	if err != nil {
+		// Forged or expired tokens grant nothing, so there is nothing to revoke
+		return nil
+	}
+	return h.revokeSignedSessions(models.SessionRevocation{
+		TokenID:   claims.ID,
+		ExpiresAt: time.UnixMilli(claims.ExpiresAt),
+	})
+}
+
+// revokeUserSignedSessions revokes every signed token issued to a profile so
+// far. store.GroupUserID(groupID, "") covers every profile in a group.
+func (h *Handler) revokeUserSignedSessions(userID string) error {
+	if h.signed == nil {
+		return nil
+	}
+	return h.revokeSignedSessions(models.SessionRevocation{
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(h.signed.ttl),
+	})
+}
+
+// revokeSignedSessions stores a revocation and applies it locally right away;
+// other instances pick it up on their next refresh
+func (h *Handler) revokeSignedSessions(revocation models.SessionRevocation) error {
+	if err := h.store.RevokeSessions(&revocation); err != nil {
+		return err
+	}
+	h.signed.mu.Lock()
+	h.signed.revocations = append(h.signed.revocations, revocation)
+	h.signed.mu.Unlock()
+	return nil
+}
+
+// verifySignedSession checks a signed token's signature, expiry and the
+// revocation list
+func (h *Handler) verifySignedSession(token string) (*models.Session, error) {
+	if h.signed == nil {
+		return nil, errInvalidSignedSession
+	}
+	claims, err := h.signed.parse(token)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range h.currentRevocations() {
+		if r.TokenID != "" {
+			if r.TokenID == claims.ID {
+				return nil, errInvalidSignedSession
+			}
+			continue
+		}
+		groupID, profile := store.SplitGroupUserID(r.UserID)
+		wholeGroup := groupID != "" && profile == ""
+		covered := r.UserID == claims.UserID || (wholeGroup && strings.HasPrefix(claims.UserID, r.UserID))
+		if covered && claims.IssuedAt <= r.RevokedAt.UnixMilli() {
+			return nil, errInvalidSignedSession
+		}
+	}
+
+	return &models.Session{
+		Token:     token,
+		UserID:    claims.UserID,
+		CreatedAt: time.UnixMilli(claims.IssuedAt),
+		Scopes:    claims.Scopes,
+	}, nil
+}
+
+// currentRevocations returns the in-memory revocation list, reloading it
+// from the store once it is older than the refresh interval. If the reload
+// fails the previous list is kept and retried on the next request.
+func (h *Handler) currentRevocations() []models.SessionRevocation {
+	s := h.signed
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.loadedAt) >= s.refresh {
+		revocations, err := h.store.ListSessionRevocations()
+		if err != nil {
+			log.Printf("Failed to refresh session revocations: %v\n", err)
+		} else {
+			s.revocations = revocations
+		}
+		s.loadedAt = time.Now()
+	}
+	return s.revocations
+}
+
+// sign encodes claims as prefix + base64(payload) + "." + base64(signature)
+func (s *signedSessions) sign(claims signedSessionClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	body := signedSessionPrefix + base64.RawURLEncoding.EncodeToString(payload)
+	return body + "." + base64.RawURLEncoding.EncodeToString(s.mac(body)), nil
+}
+
+// parse verifies a token's signature and expiry and returns its claims
+func (s *signedSessions) parse(token string) (*signedSessionClaims, error) {
+	i := strings.LastIndex(token, ".")
+	if !strings.HasPrefix(token, signedSessionPrefix) || i <= len(signedSessionPrefix) {
+		return nil, errInvalidSignedSession
+	}
+	body, sig := token[:i], token[i+1:]
+
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, s.mac(body)) {
+		return nil, errInvalidSignedSession
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(body, signedSessionPrefix))
+	if err != nil {
+		return nil, errInvalidSignedSession
+	}
+	var claims signedSessionClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, errInvalidSignedSession
+	}
+	if claims.UserID == "" || time.Now().UnixMilli() >= claims.ExpiresAt {
+		return nil, errInvalidSignedSession
+	}
+	return &claims, nil
+}
+
+func (s *signedSessions) mac(body string) []byte {
+	m := hmac.New(sha256.New, s.key)
+	m.Write([]byte(body))
+	return m.Sum(nil)
+}
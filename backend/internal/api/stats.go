@@ -0,0 +1,189 @@
+package api
+
+import (
+	"intervals-sync/internal/models"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Report periods
+const (
+	periodDay  = "day"
+	periodWeek = "week"
+)
+
+// maxReportDays limits the range a report can cover
+const maxReportDays = 366
+
+// reportRange is the time range and grouping of a report. Periods start at
+// midnight in Location; weeks start on Monday.
+type reportRange struct {
+	From     time.Time
+	To       time.Time // exclusive
+	Period   string
+	Location *time.Location
+}
+
+// parseReportRange reads ?period=day|week, ?tz= (IANA name, default UTC) and
+// ?from= and ?to= (YYYY-MM-DD in tz or RFC3339, to exclusive). Without from
+// and to it covers the last 7 days or 8 weeks up to today. Returns a message
+// describing the first invalid parameter, or "".
+func parseReportRange(query url.Values, now time.Time) (reportRange, string) {
+	rr := reportRange{Period: strings.ToLower(query.Get("period")), Location: time.UTC}
+	switch rr.Period {
+	case "":
+		rr.Period = periodDay
+	case periodDay, periodWeek:
+	default:
+		return rr, "period must be day or week"
+	}
+	if tz := query.Get("tz"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return rr, "tz must be an IANA time zone such as Europe/Berlin"
+		}
+		rr.Location = loc
+	}
+
+	var err error
+	if rr.From, err = parseDateIn(query.Get("from"), rr.Location); err != nil {
+		return rr, "from must be an RFC3339 timestamp or YYYY-MM-DD date"
+	}
+	if rr.To, err = parseDateIn(query.Get("to"), rr.Location); err != nil {
+		return rr, "to must be an RFC3339 timestamp or YYYY-MM-DD date"
+	}
+	if rr.To.IsZero() {
+		rr.To = rr.next(rr.start(now))
+	}
+	if rr.From.IsZero() {
+		if rr.Period == periodWeek {
+			rr.From = rr.start(rr.To.AddDate(0, 0, -7*8))
+		} else {
+			rr.From = rr.To.AddDate(0, 0, -7)
+		}
+	}
+	rr.From = rr.start(rr.From)
+	if !rr.From.Before(rr.To) {
+		return rr, "from must be before to"
+	}
+	if rr.To.Sub(rr.From) > maxReportDays*24*time.Hour {
+		return rr, "Reports can cover at most 366 days"
+	}
+	return rr, ""
+}
+
+// parseDateIn parses an optional YYYY-MM-DD date at midnight in loc, or an
+// RFC3339 time
+func parseDateIn(v string, loc *time.Location) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", v, loc); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+// start returns the start of the period containing t
+func (rr reportRange) start(t time.Time) time.Time {
+	t = t.In(rr.Location)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, rr.Location)
+	if rr.Period == periodWeek {
+		// time.Weekday counts from Sunday
+		day = day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	}
+	return day
+}
+
+// next returns the start of the period after the one starting at t
+func (rr reportRange) next(t time.Time) time.Time {
+	if rr.Period == periodWeek {
+		return t.AddDate(0, 0, 7)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+// periods lists the start of every period in the range
+func (rr reportRange) periods() []time.Time {
+	var starts []time.Time
+	for t := rr.From; t.Before(rr.To); t = rr.next(t) {
+		starts = append(starts, t)
+	}
+	return starts
+}
+
+// eachCompletion calls fn for every completion of a profile started in
+// [from, to), oldest first, reading them a page at a time
+func (h *Handler) eachCompletion(userID string, from, to time.Time, fn func(c *models.Completion)) error {
+	opts := models.CompletionListOptions{From: from, To: to, Ascending: true, Limit: maxPageSize}
+	for {
+		completions, next, err := h.store.ListCompletions(userID, opts)
+		if err != nil {
+			return err
+		}
+		for i := range completions {
+			fn(&completions[i])
+		}
+		if next == "" {
+			return nil
+		}
+		opts.Cursor = next
+	}
+}
+
+// FocusStats handles GET /api/stats/focus
+// Sums the focus time, sessions and interruptions of pomodoro completions
+// per day or week; see parseReportRange for the parameters
+func (h *Handler) FocusStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	rr, msg := parseReportRange(r.URL.Query(), time.Now())
+	if msg != "" {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: msg})
+		return
+	}
+
+	starts := rr.periods()
+	stats := models.FocusStats{
+		Period:   rr.Period,
+		TimeZone: rr.Location.String(),
+		Periods:  make([]models.FocusPeriod, len(starts)),
+		Total:    models.FocusPeriod{Start: starts[0].Format("2006-01-02")},
+	}
+	index := map[string]int{}
+	for i, start := range starts {
+		stats.Periods[i].Start = start.Format("2006-01-02")
+		index[stats.Periods[i].Start] = i
+	}
+
+	err := h.eachCompletion(session.UserID, rr.From, rr.To, func(c *models.Completion) {
+		if c.FocusSeconds == 0 && c.Interruptions == 0 {
+			return
+		}
+		i, ok := index[rr.start(c.StartedAt).Format("2006-01-02")]
+		if !ok {
+			return
+		}
+		for _, p := range []*models.FocusPeriod{&stats.Periods[i], &stats.Total} {
+			p.FocusSeconds += c.FocusSeconds
+			p.Sessions++
+			p.Interruptions += c.Interruptions
+		}
+	})
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch completions"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stats)
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"intervals-sync/internal/models"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestFocusStats(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+	token := login(t, h, "test-profile")
+
+	at := func(s string) time.Time {
+		ts, _ := time.Parse(time.RFC3339, s)
+		return ts
+	}
+	for _, c := range []models.Completion{
+		{ID: "c1", StartedAt: at("2026-03-02T09:00:00Z"), FocusSeconds: 1500, Interruptions: 1},
+		{ID: "c2", StartedAt: at("2026-03-02T10:00:00Z"), FocusSeconds: 1500},
+		{ID: "c3", StartedAt: at("2026-03-04T23:30:00Z"), FocusSeconds: 1200, Interruptions: 2},
+		{ID: "c4", StartedAt: at("2026-03-03T12:00:00Z")}, // a workout, not a focus session
+		{ID: "c5", StartedAt: at("2026-03-10T09:00:00Z"), FocusSeconds: 1500},
+	} {
+		c.UserID = "test-profile"
+		c.WorkoutID = "w"
+		if err := h.store.UpsertCompletion(&c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stats models.FocusStats
+	if code := do(t, h.FocusStats, http.MethodGet, "/api/stats/focus?from=2026-03-02&to=2026-03-06", token, "", &stats); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if len(stats.Periods) != 4 || stats.Periods[0].Start != "2026-03-02" {
+		t.Fatalf("expected 4 days from 2026-03-02, got %+v", stats.Periods)
+	}
+	if p := stats.Periods[0]; p.FocusSeconds != 3000 || p.Sessions != 2 || p.Interruptions != 1 {
+		t.Errorf("unexpected first day: %+v", p)
+	}
+	if p := stats.Periods[1]; p.Sessions != 0 {
+		t.Errorf("expected no focus sessions on the second day, got %+v", p)
+	}
+	if stats.Total.FocusSeconds != 4200 || stats.Total.Sessions != 3 {
+		t.Errorf("unexpected total: %+v", stats.Total)
+	}
+
+	// Days follow the requested time zone
+	do(t, h.FocusStats, http.MethodGet, "/api/stats/focus?from=2026-03-02&to=2026-03-06&tz=Europe/Berlin", token, "", &stats)
+	if stats.TimeZone != "Europe/Berlin" || stats.Periods[3].FocusSeconds != 1200 {
+		t.Errorf("expected the late session on 2026-03-05 in Berlin, got %+v", stats.Periods)
+	}
+
+	// Weeks start on Monday
+	do(t, h.FocusStats, http.MethodGet, "/api/stats/focus?period=week&from=2026-03-04&to=2026-03-16", token, "", &stats)
+	if len(stats.Periods) != 2 || stats.Periods[0].Start != "2026-03-02" || stats.Periods[0].FocusSeconds != 4200 || stats.Periods[1].FocusSeconds != 1500 {
+		t.Errorf("unexpected weeks: %+v", stats.Periods)
+	}
+
+	for _, query := range []string{"period=month", "tz=Mars/Olympus", "from=2026-03-06&to=2026-03-02", "from=2020-01-01&to=2026-01-01"} {
+		if code := do(t, h.FocusStats, http.MethodGet, "/api/stats/focus?"+query, token, "", nil); code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %s, got %d", query, code)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"intervals-sync/internal/models"
+	"intervals-sync/internal/store"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Scopes that can be granted to personal API tokens
+const (
+	ScopeWorkoutsRead     = "workouts:read"
+	ScopeWorkoutsWrite    = "workouts:write"
+	ScopeCompletionsRead  = "completions:read"
+	ScopeCompletionsWrite = "completions:write"
+)
+
+var validScopes = map[string]bool{
+	ScopeWorkoutsRead:     true,
+	ScopeWorkoutsWrite:    true,
+	ScopeCompletionsRead:  true,
+	ScopeCompletionsWrite: true,
+}
+
+// apiTokenPrefix distinguishes personal API tokens from session tokens
+const apiTokenPrefix = "ivl_"
+
+type contextKey string
+
+const sessionContextKey contextKey = "session"
+
+// RequireScope returns middleware that authenticates the request with either
+// a session token or a personal API token. API tokens must carry every listed
+// scope; session tokens from AuthInit grant full access.
+func (h *Handler) RequireScope(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Proxy-authenticated users have full access
+			if user := h.proxyUser(r); user != "" {
+				ctx := context.WithValue(r.Context(), sessionContextKey, &models.Session{UserID: user})
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			token := extractToken(r)
+			if token == "" {
+				writeJSON(w, http.StatusUnauthorized, models.ErrorResponse{Error: "Missing token"})
+				return
+			}
+
+			session, err := h.resolveToken(token)
+			if err != nil {
+				writeJSON(w, http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid token"})
+				return
+			}
+
+			for _, scope := range scopes {
+				if !hasScope(session, scope) {
+					writeJSON(w, http.StatusForbidden, models.ErrorResponse{Error: "Token is missing scope " + scope})
+					return
+				}
+			}
+
+			ctx := context.WithValue(r.Context(), sessionContextKey, session)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// resolveToken verifies a session or personal API token
+func (h *Handler) resolveToken(token string) (*models.Session, error) {
+	if strings.HasPrefix(token, signedSessionPrefix) {
+		return h.verifySignedSession(token)
+	}
+	if !strings.HasPrefix(token, apiTokenPrefix) {
+		return h.store.VerifySession(token)
+	}
+
+	apiToken, err := h.store.GetAPIToken(hashPassphrase(token))
+	if err != nil {
+		return nil, err
+	}
+	return &models.Session{
+		Token:     token,
+		UserID:    apiToken.UserID,
+		CreatedAt: apiToken.CreatedAt,
+		Scopes:    apiToken.Scopes,
+	}, nil
+}
+
+// hasScope reports whether a session grants a scope
+func hasScope(session *models.Session, scope string) bool {
+	if len(session.Scopes) == 0 {
+		return true
+	}
+	for _, s := range session.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateAPIToken handles POST /api/tokens
+// Creates a named, scoped personal API token for the session's profile
+func (h *Handler) CreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.APITokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Token name is required"})
+		return
+	}
+	if len(req.Scopes) == 0 {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "At least one scope is required"})
+		return
+	}
+	for _, scope := range req.Scopes {
+		if !validScopes[scope] {
+			writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Unknown scope: " + scope})
+			return
+		}
+	}
+
+	plaintext := apiTokenPrefix + generateToken()
+	apiToken := models.APIToken{
+		ID:        uuid.New().String(),
+		UserID:    session.UserID,
+		Name:      req.Name,
+		TokenHash: hashPassphrase(plaintext),
+		Scopes:    req.Scopes,
+	}
+	if err := h.store.CreateAPIToken(&apiToken); err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to create token"})
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, models.APITokenResponse{APIToken: apiToken, Token: plaintext})
+}
+
+// ListAPITokens handles GET /api/tokens
+func (h *Handler) ListAPITokens(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	tokens, err := h.store.ListAPITokens(session.UserID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch tokens"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"tokens": tokens,
+	})
+}
+
+// DeleteAPIToken handles DELETE /api/tokens/:id
+func (h *Handler) DeleteAPIToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	tokenID := strings.TrimPrefix(r.URL.Path, "/api/tokens/")
+	if err := h.store.DeleteAPIToken(session.UserID, tokenID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, models.ErrorResponse{Error: "Token not found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to revoke token"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("{}"))
+}
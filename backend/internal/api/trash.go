@@ -0,0 +1,108 @@
+package api
+
+import (
+	"errors"
+	"intervals-sync/internal/models"
+	"intervals-sync/internal/store"
+	"net/http"
+	"strings"
+)
+
+// ListTrash handles GET /api/trash
+// Returns the profile's deleted workouts and completions that have not been
+// purged yet, most recently deleted first
+func (h *Handler) ListTrash(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	workouts, err := h.store.ListDeletedWorkouts(session.UserID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch deleted workouts"})
+		return
+	}
+	completions, err := h.store.ListDeletedCompletions(session.UserID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch deleted completions"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"workouts":    workouts,
+		"completions": completions,
+	})
+}
+
+// RestoreWorkout handles POST /api/trash/workouts/:id/restore
+func (h *Handler) RestoreWorkout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	workoutID := trashPath(r, "/api/trash/workouts/")
+	if err := h.store.RestoreWorkout(session.UserID, workoutID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, models.ErrorResponse{Error: "Workout is not in the trash"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to restore workout"})
+		return
+	}
+
+	workout, err := h.store.GetWorkout(session.UserID, workoutID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch workout"})
+		return
+	}
+
+	w.Header().Set("ETag", workoutETag(workout))
+	writeJSON(w, http.StatusOK, workout)
+}
+
+// RestoreCompletion handles POST /api/trash/completions/:id/restore
+func (h *Handler) RestoreCompletion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	completionID := trashPath(r, "/api/trash/completions/")
+	if err := h.store.RestoreCompletion(session.UserID, completionID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, models.ErrorResponse{Error: "Completion is not in the trash"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to restore completion"})
+		return
+	}
+
+	completion, err := h.store.GetCompletion(session.UserID, completionID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch completion"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, completion)
+}
+
+// trashPath extracts the ID from /api/trash/<kind>/:id/restore
+func trashPath(r *http.Request, prefix string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, prefix), "/restore")
+}
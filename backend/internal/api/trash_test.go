@@ -0,0 +1,66 @@
+package api
+
+import (
+	"intervals-sync/internal/models"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTrash(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+	token := login(t, h, "test-profile")
+
+	var workout models.Workout
+	do(t, h.CreateWorkout, http.MethodPost, "/api/workouts", token, `{"name":"Tabata","intervals":[{"name":"Work","duration":20}]}`, &workout)
+	h.store.UpsertCompletion(&models.Completion{ID: "c1", UserID: "test-profile", WorkoutID: workout.ID, StartedAt: time.Now()})
+	do(t, h.DeleteWorkout, http.MethodDelete, "/api/workouts/"+workout.ID, token, "", nil)
+	do(t, h.DeleteCompletion, http.MethodDelete, "/api/completions/c1", token, "", nil)
+
+	var trash struct {
+		Workouts    []models.Workout    `json:"workouts"`
+		Completions []models.Completion `json:"completions"`
+	}
+	if code := do(t, h.ListTrash, http.MethodGet, "/api/trash", token, "", &trash); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if len(trash.Workouts) != 1 || trash.Workouts[0].DeletedAt == nil || len(trash.Workouts[0].Intervals) != 1 {
+		t.Fatalf("expected the deleted workout with its intervals, got %+v", trash.Workouts)
+	}
+	if len(trash.Completions) != 1 || trash.Completions[0].DeletedAt == nil {
+		t.Errorf("expected the deleted completion, got %+v", trash.Completions)
+	}
+	deletedWorkout := trash.Workouts[0]
+
+	// Other profiles see an empty trash and cannot restore
+	other := login(t, h, "other-profile")
+	do(t, h.ListTrash, http.MethodGet, "/api/trash", other, "", &trash)
+	if len(trash.Workouts) != 0 || len(trash.Completions) != 0 {
+		t.Errorf("expected an empty trash for another profile, got %+v", trash)
+	}
+	if code := do(t, h.RestoreWorkout, http.MethodPost, "/api/trash/workouts/"+workout.ID+"/restore", other, "", nil); code != http.StatusNotFound {
+		t.Errorf("expected 404 restoring another profile's workout, got %d", code)
+	}
+
+	var restored models.Workout
+	if code := do(t, h.RestoreWorkout, http.MethodPost, "/api/trash/workouts/"+workout.ID+"/restore", token, "", &restored); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if restored.DeletedAt != nil || restored.Name != "Tabata" || len(restored.Intervals) != 1 {
+		t.Errorf("unexpected restored workout: %+v", restored)
+	}
+	if code := do(t, h.RestoreCompletion, http.MethodPost, "/api/trash/completions/c1/restore", token, "", nil); code != http.StatusOK {
+		t.Errorf("expected 200 restoring the completion, got %d", code)
+	}
+
+	// Restoring twice finds nothing in the trash
+	if code := do(t, h.RestoreCompletion, http.MethodPost, "/api/trash/completions/c1/restore", token, "", nil); code != http.StatusNotFound {
+		t.Errorf("expected 404 for a completion that is not in the trash, got %d", code)
+	}
+
+	// The restore is newer than the deletion, so it reaches other devices
+	if !restored.UpdatedAt.After(*deletedWorkout.DeletedAt) {
+		t.Errorf("expected updated_at %v to be after deleted_at %v", restored.UpdatedAt, deletedWorkout.DeletedAt)
+	}
+}
@@ -0,0 +1,966 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"intervals-sync/internal/models"
+	"intervals-sync/internal/store"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Page sizes for list endpoints
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// Limits on nested blocks, so a workout stays playable and cheap to expand
+const (
+	maxBlockDepth        = 5
+	maxExpandedIntervals = 1000
+)
+
+// Limits on interval metadata
+const (
+	maxIntervalNotes = 1000
+	maxExerciseRef   = 500
+)
+
+// maxVaryingRounds caps the rounds of workouts whose rounds differ through
+// progressions or conditions, since their length is computed round by round
+const maxVaryingRounds = 1000
+
+// maxEMOMPeriod is the longest EMOM round, in seconds
+const maxEMOMPeriod = 3600
+
+// maxTimelineSteps caps the steps returned by the timeline endpoint
+const maxTimelineSteps = 100000
+
+// maxRounds caps the rounds of any workout
+const maxRounds = 10000
+
+// maxIntervalDuration is the longest an interval may last in any round, in
+// seconds
+const maxIntervalDuration = 24 * 60 * 60
+
+// workoutPatch is the body of PATCH /api/workouts/:id; omitted fields are kept
+type workoutPatch struct {
+	Name      *string            `json:"name"`
+	Rounds    *int               `json:"rounds"`
+	Intervals *[]models.Interval `json:"intervals"`
+	Blocks    *[]models.Block    `json:"blocks"`
+	Kind      *string            `json:"kind"`
+	Period    *int               `json:"period"`
+	TimeCap   *int               `json:"time_cap"`
+	Pomodoro  *models.Pomodoro   `json:"pomodoro"`
+}
+
+// intervalRequest is the body of the interval sub-resource routes; omitted
+// fields are kept on PATCH. Position inserts or moves the interval.
+type intervalRequest struct {
+	Name         *string             `json:"name"`
+	Duration     *int                `json:"duration"`
+	Color        *string             `json:"color"`
+	Position     *int                `json:"position"`
+	Type         *string             `json:"type"`
+	Notes        *string             `json:"notes"`
+	TargetReps   *int                `json:"target_reps"`
+	TargetHRZone *int                `json:"target_hr_zone"`
+	ExerciseRef  *string             `json:"exercise_ref"`
+	Manual       *bool               `json:"manual"`
+	TimeCap      *int                `json:"time_cap"`
+	Progression  *models.Progression `json:"progression"`
+	Condition    *models.Condition   `json:"condition"`
+}
+
+// ListWorkouts handles GET /api/workouts
+// Supports ?q= (name contains), ?updated_after= (RFC3339), ?sort= (name,
+// created_at, updated_at, "-" prefix for descending), ?limit= and ?offset=
+func (h *Handler) ListWorkouts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	opts := models.WorkoutListOptions{
+		Query: strings.TrimSpace(query.Get("q")),
+		Sort:  query.Get("sort"),
+		Limit: defaultPageSize,
+	}
+	if opts.Sort != "" && !store.ValidWorkoutSort(opts.Sort) {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "sort must be name, created_at or updated_at, optionally prefixed with -"})
+		return
+	}
+	if v := query.Get("updated_after"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "updated_after must be an RFC3339 timestamp"})
+			return
+		}
+		opts.UpdatedAfter = t
+	}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageSize {
+			writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "limit must be between 1 and " + strconv.Itoa(maxPageSize)})
+			return
+		}
+		opts.Limit = n
+	}
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "offset must not be negative"})
+			return
+		}
+		opts.Offset = n
+	}
+
+	workouts, total, err := h.store.ListWorkouts(session.UserID, opts)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch workouts"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"workouts": workouts,
+		"total":    total,
+		"limit":    opts.Limit,
+		"offset":   opts.Offset,
+	})
+}
+
+// CreateWorkout handles POST /api/workouts
+// IDs are assigned by the server; any id in the body is ignored
+func (h *Handler) CreateWorkout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.Workout
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	now := time.Now()
+	workout := models.Workout{
+		ID:        uuid.New().String(),
+		UserID:    session.UserID,
+		Name:      req.Name,
+		Rounds:    req.Rounds,
+		Intervals: req.Intervals,
+		Blocks:    req.Blocks,
+		Kind:      req.Kind,
+		Period:    req.Period,
+		TimeCap:   req.TimeCap,
+		Pomodoro:  req.Pomodoro,
+		CreatedAt: now,
+	}
+	applyKindDefaults(&workout)
+	if workout.Rounds == 0 {
+		workout.Rounds = 1
+	}
+	if msg := blockIntervals(req.Intervals, workout.Blocks); msg != "" {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: msg})
+		return
+	}
+	if msg := validateWorkout(&workout); msg != "" {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: msg})
+		return
+	}
+
+	numberIntervals(&workout)
+	workout.UpdatedAt = now
+	if _, err := h.store.UpsertWorkout(&workout); err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to save workout"})
+		return
+	}
+	h.recordRevision(r, &workout, revisionSourceAPI)
+
+	w.Header().Set("ETag", workoutETag(&workout))
+	writeJSON(w, http.StatusCreated, workout)
+}
+
+// UpdateWorkout handles PUT /api/workouts/:id
+// Replaces the name, rounds and intervals of an existing workout
+func (h *Handler) UpdateWorkout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	workoutID, _ := workoutPath(r)
+	workout, err := h.store.GetWorkout(session.UserID, workoutID)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, models.ErrorResponse{Error: "Workout not found"})
+		return
+	}
+	ifVersion, ok := h.checkIfMatch(w, r, workout)
+	if !ok {
+		return
+	}
+
+	var req models.Workout
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+	workout.Name = req.Name
+	workout.Rounds = req.Rounds
+	workout.Intervals = req.Intervals
+	workout.Blocks = req.Blocks
+	workout.Kind = req.Kind
+	workout.Period = req.Period
+	workout.TimeCap = req.TimeCap
+	workout.Pomodoro = req.Pomodoro
+	applyKindDefaults(workout)
+	if msg := blockIntervals(req.Intervals, workout.Blocks); msg != "" {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: msg})
+		return
+	}
+	if msg := validateWorkout(workout); msg != "" {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: msg})
+		return
+	}
+
+	if !h.saveWorkout(w, r, workout, ifVersion, revisionSourceAPI) {
+		return
+	}
+
+	w.Header().Set("ETag", workoutETag(workout))
+	writeJSON(w, http.StatusOK, workout)
+}
+
+// PatchWorkout handles PATCH /api/workouts/:id
+// Updates only the fields present in the body
+func (h *Handler) PatchWorkout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	workoutID, _ := workoutPath(r)
+	workout, err := h.store.GetWorkout(session.UserID, workoutID)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, models.ErrorResponse{Error: "Workout not found"})
+		return
+	}
+	ifVersion, ok := h.checkIfMatch(w, r, workout)
+	if !ok {
+		return
+	}
+
+	var patch workoutPatch
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+	if patch.Name != nil {
+		workout.Name = *patch.Name
+	}
+	if patch.Rounds != nil {
+		workout.Rounds = *patch.Rounds
+	}
+	if patch.Intervals != nil {
+		workout.Intervals = *patch.Intervals
+	}
+	if patch.Blocks != nil {
+		workout.Blocks = *patch.Blocks
+	}
+	if patch.Kind != nil {
+		workout.Kind = *patch.Kind
+	}
+	if patch.Period != nil {
+		workout.Period = *patch.Period
+	}
+	if patch.TimeCap != nil {
+		workout.TimeCap = *patch.TimeCap
+	}
+	if patch.Pomodoro != nil {
+		workout.Pomodoro = patch.Pomodoro
+	}
+	applyKindDefaults(workout)
+	if patch.Intervals != nil {
+		if msg := blockIntervals(*patch.Intervals, workout.Blocks); msg != "" {
+			writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: msg})
+			return
+		}
+	}
+	if msg := validateWorkout(workout); msg != "" {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: msg})
+		return
+	}
+
+	if !h.saveWorkout(w, r, workout, ifVersion, revisionSourceAPI) {
+		return
+	}
+
+	w.Header().Set("ETag", workoutETag(workout))
+	writeJSON(w, http.StatusOK, workout)
+}
+
+// ListIntervals handles GET /api/workouts/:id/intervals
+func (h *Handler) ListIntervals(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	workoutID, _ := workoutPath(r)
+	workout, err := h.store.GetWorkout(session.UserID, workoutID)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, models.ErrorResponse{Error: "Workout not found"})
+		return
+	}
+	if notModified(w, r, workout) {
+		return
+	}
+
+	intervals := workout.Intervals
+	if intervals == nil {
+		intervals = []models.Interval{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"intervals": intervals,
+	})
+}
+
+// GetTimeline handles GET /api/workouts/:id/timeline
+// Expands the workout into every interval performed, round by round, with
+// progressions and conditions applied
+func (h *Handler) GetTimeline(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	workoutID, _ := workoutPath(r)
+	workout, err := h.store.GetWorkout(session.UserID, workoutID)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, models.ErrorResponse{Error: "Workout not found"})
+		return
+	}
+	if notModified(w, r, workout) {
+		return
+	}
+	if n := len(workout.Intervals); n > 0 && workout.Rounds > maxTimelineSteps/n {
+		writeJSON(w, http.StatusUnprocessableEntity, models.ErrorResponse{Error: "Workout is too long to expand"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"steps":          workout.Expand(),
+		"total_duration": workout.TotalDuration,
+	})
+}
+
+// AddInterval handles POST /api/workouts/:id/intervals
+// Appends an interval, or inserts it at position
+func (h *Handler) AddInterval(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	workoutID, _ := workoutPath(r)
+	workout, err := h.store.GetWorkout(session.UserID, workoutID)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, models.ErrorResponse{Error: "Workout not found"})
+		return
+	}
+	ifVersion, ok := h.checkIfMatch(w, r, workout)
+	if !ok {
+		return
+	}
+	if msg := derivedIntervals(workout); msg != "" {
+		writeJSON(w, http.StatusConflict, models.ErrorResponse{Error: msg})
+		return
+	}
+
+	var req intervalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	interval := models.Interval{ID: uuid.New().String()}
+	req.applyTo(&interval)
+	position := len(workout.Intervals)
+	if req.Position != nil {
+		position = *req.Position
+	}
+	if position < 0 || position > len(workout.Intervals) {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "position is out of range"})
+		return
+	}
+	workout.Intervals = insertInterval(workout.Intervals, position, interval)
+	if msg := validateWorkout(workout); msg != "" {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: msg})
+		return
+	}
+
+	if !h.saveWorkout(w, r, workout, ifVersion, revisionSourceAPI) {
+		return
+	}
+
+	w.Header().Set("ETag", workoutETag(workout))
+	writeJSON(w, http.StatusCreated, workout.Intervals[position])
+}
+
+// PatchInterval handles PATCH /api/workouts/:id/intervals/:intervalId
+// Updates the fields present in the body; position moves the interval
+func (h *Handler) PatchInterval(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	workoutID, intervalID := workoutPath(r)
+	workout, err := h.store.GetWorkout(session.UserID, workoutID)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, models.ErrorResponse{Error: "Workout not found"})
+		return
+	}
+	ifVersion, ok := h.checkIfMatch(w, r, workout)
+	if !ok {
+		return
+	}
+	if msg := derivedIntervals(workout); msg != "" {
+		writeJSON(w, http.StatusConflict, models.ErrorResponse{Error: msg})
+		return
+	}
+	index := findInterval(workout.Intervals, intervalID)
+	if index < 0 {
+		writeJSON(w, http.StatusNotFound, models.ErrorResponse{Error: "Interval not found"})
+		return
+	}
+
+	var req intervalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	interval := workout.Intervals[index]
+	req.applyTo(&interval)
+	position := index
+	if req.Position != nil {
+		position = *req.Position
+	}
+	if position < 0 || position >= len(workout.Intervals) {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "position is out of range"})
+		return
+	}
+	rest := append(append([]models.Interval{}, workout.Intervals[:index]...), workout.Intervals[index+1:]...)
+	workout.Intervals = insertInterval(rest, position, interval)
+	if msg := validateWorkout(workout); msg != "" {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: msg})
+		return
+	}
+
+	if !h.saveWorkout(w, r, workout, ifVersion, revisionSourceAPI) {
+		return
+	}
+
+	w.Header().Set("ETag", workoutETag(workout))
+	writeJSON(w, http.StatusOK, workout.Intervals[position])
+}
+
+// DeleteInterval handles DELETE /api/workouts/:id/intervals/:intervalId
+func (h *Handler) DeleteInterval(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	workoutID, intervalID := workoutPath(r)
+	workout, err := h.store.GetWorkout(session.UserID, workoutID)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, models.ErrorResponse{Error: "Workout not found"})
+		return
+	}
+	ifVersion, ok := h.checkIfMatch(w, r, workout)
+	if !ok {
+		return
+	}
+	if msg := derivedIntervals(workout); msg != "" {
+		writeJSON(w, http.StatusConflict, models.ErrorResponse{Error: msg})
+		return
+	}
+	index := findInterval(workout.Intervals, intervalID)
+	if index < 0 {
+		writeJSON(w, http.StatusNotFound, models.ErrorResponse{Error: "Interval not found"})
+		return
+	}
+
+	workout.Intervals = append(workout.Intervals[:index], workout.Intervals[index+1:]...)
+	if !h.saveWorkout(w, r, workout, ifVersion, revisionSourceAPI) {
+		return
+	}
+
+	w.Header().Set("ETag", workoutETag(workout))
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("{}"))
+}
+
+// saveWorkout writes an existing workout back, bumping updated_at so the
+// change reaches other devices on their next sync, and records a revision
+// from source. A non-zero ifVersion makes the write conditional. It writes
+// the error response and returns false if the workout could not be saved.
+func (h *Handler) saveWorkout(w http.ResponseWriter, r *http.Request, workout *models.Workout, ifVersion int64, source string) bool {
+	numberIntervals(workout)
+	workout.UpdatedAt = time.Now()
+	err := h.store.UpdateWorkout(workout, ifVersion)
+	switch {
+	case err == nil:
+		h.recordRevision(r, workout, source)
+		return true
+	case errors.Is(err, store.ErrVersionConflict):
+		writeJSON(w, http.StatusPreconditionFailed, models.ErrorResponse{Error: "Workout has been modified"})
+	case errors.Is(err, store.ErrNotFound):
+		writeJSON(w, http.StatusNotFound, models.ErrorResponse{Error: "Workout not found"})
+	default:
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to save workout"})
+	}
+	return false
+}
+
+// derivedIntervals explains why a workout's intervals cannot be edited one by
+// one, or returns "" if they can
+func derivedIntervals(workout *models.Workout) string {
+	if len(workout.Blocks) > 0 {
+		return "Workout uses blocks; update its blocks instead"
+	}
+	if workout.Kind == models.WorkoutKindPomodoro {
+		return "Pomodoro intervals follow its settings; update pomodoro instead"
+	}
+	return ""
+}
+
+// blockIntervals explains why intervals sent with a workout's blocks would be
+// lost, or returns "" if there are none or they are what the blocks expand to
+func blockIntervals(intervals []models.Interval, blocks []models.Block) string {
+	if len(blocks) == 0 || len(intervals) == 0 || reflect.DeepEqual(intervals, models.FlattenBlocks(blocks)) {
+		return ""
+	}
+	return "Workout uses blocks, so its intervals are derived from them; update the blocks, or clear them to set intervals"
+}
+
+// numberIntervals assigns missing interval and block IDs and numbers the
+// intervals in order
+func numberIntervals(workout *models.Workout) {
+	for i := range workout.Intervals {
+		if workout.Intervals[i].ID == "" {
+			workout.Intervals[i].ID = uuid.New().String()
+		}
+		workout.Intervals[i].Position = i
+	}
+	for i := range workout.Blocks {
+		numberBlock(&workout.Blocks[i])
+	}
+}
+
+func numberBlock(block *models.Block) {
+	if block.ID == "" {
+		block.ID = uuid.New().String()
+	}
+	for i, item := range block.Items {
+		switch {
+		case item.Interval != nil:
+			if item.Interval.ID == "" {
+				item.Interval.ID = uuid.New().String()
+			}
+			item.Interval.Position = i
+		case item.Block != nil:
+			numberBlock(item.Block)
+		}
+	}
+}
+
+// validateWorkout returns a message describing what is wrong with a workout,
+// or "" if it is valid
+func validateWorkout(workout *models.Workout) string {
+	workout.Name = strings.TrimSpace(workout.Name)
+	if workout.Name == "" {
+		return "Workout name is required"
+	}
+	if workout.Rounds < 1 {
+		return "rounds must be at least 1"
+	}
+	return validateStructure(workout)
+}
+
+// validateStructure checks the intervals and blocks of a workout. Sync runs
+// it too, so the server never expands a workout beyond its limits.
+func validateStructure(workout *models.Workout) string {
+	for i := range workout.Intervals {
+		if msg := validateInterval(&workout.Intervals[i]); msg != "" {
+			return msg
+		}
+	}
+	count := 0
+	for i := range workout.Blocks {
+		if msg := validateBlock(&workout.Blocks[i], 1, &count); msg != "" {
+			return msg
+		}
+	}
+	if workout.Rounds > maxRounds {
+		return "rounds must be at most " + strconv.Itoa(maxRounds)
+	}
+	if workout.Rounds > maxVaryingRounds && workout.VariesByRound() {
+		return "Workouts with progressions or conditions can have at most " + strconv.Itoa(maxVaryingRounds) + " rounds"
+	}
+	if msg := validateLastRound(workout); msg != "" {
+		return msg
+	}
+	return validateKind(workout)
+}
+
+// validateLastRound checks that progressions keep every interval within
+// maxIntervalDuration. They change durations linearly, so the first and last
+// rounds are the extremes, and the first is checked with each interval.
+func validateLastRound(workout *models.Workout) string {
+	last := workout.Rounds - 1
+	if last < 1 {
+		return ""
+	}
+	intervals := workout.Intervals
+	if len(workout.Blocks) > 0 {
+		intervals = models.FlattenBlocks(workout.Blocks)
+	}
+	for i := range intervals {
+		if intervals[i].DurationForRound(last) > maxIntervalDuration {
+			return "Progressions must keep interval durations at most " + strconv.Itoa(maxIntervalDuration) + " seconds in every round"
+		}
+	}
+	return ""
+}
+
+// validateKind checks the settings that belong to the workout's kind
+func validateKind(workout *models.Workout) string {
+	workout.Kind = strings.ToLower(strings.TrimSpace(workout.Kind))
+	if workout.Kind != "" && !isWorkoutKind(workout.Kind) {
+		return "Workout kind must be one of " + strings.Join(models.WorkoutKinds, ", ")
+	}
+	if workout.Period != 0 && workout.Kind != models.WorkoutKindEMOM {
+		return "period is only used by EMOM workouts"
+	}
+	if workout.Period < 0 || workout.Period > maxEMOMPeriod {
+		return "period must be between 1 and " + strconv.Itoa(maxEMOMPeriod) + " seconds"
+	}
+	if workout.TimeCap < 0 {
+		return "time_cap must not be negative"
+	}
+	switch workout.Kind {
+	case models.WorkoutKindAMRAP:
+		if workout.TimeCap == 0 {
+			return "AMRAP workouts need a time_cap"
+		}
+	case models.WorkoutKindForTime:
+	default:
+		if workout.TimeCap != 0 {
+			return "time_cap is only used by AMRAP and For-Time workouts"
+		}
+	}
+	if workout.Pomodoro != nil {
+		p := workout.Pomodoro
+		if workout.Kind != models.WorkoutKindPomodoro {
+			return "pomodoro is only used by pomodoro workouts"
+		}
+		if p.Focus < 0 || p.ShortBreak < 0 || p.LongBreak < 0 || p.LongBreakEvery < 0 {
+			return "Pomodoro durations and long_break_every must not be negative"
+		}
+	}
+	if workout.Kind == models.WorkoutKindEMOM {
+		rounds := 1
+		if workout.VariesByRound() {
+			rounds = workout.Rounds
+		}
+		period := workout.RoundPeriod()
+		for round := 0; round < rounds; round++ {
+			if workout.RoundDuration(round) > period {
+				return "Each EMOM round must fit in its period of " + strconv.Itoa(period) + " seconds"
+			}
+		}
+	}
+	return ""
+}
+
+func isWorkoutKind(kind string) bool {
+	for _, known := range models.WorkoutKinds {
+		if kind == known {
+			return true
+		}
+	}
+	return false
+}
+
+// applyKindDefaults fills in what a workout's kind implies: the Tabata preset
+// of 8 rounds of 20s work and 10s rest for whatever a Tabata workout leaves
+// unset, and for pomodoro workouts the intervals built from their settings
+func applyKindDefaults(workout *models.Workout) {
+	switch strings.ToLower(strings.TrimSpace(workout.Kind)) {
+	case models.WorkoutKindTabata:
+		if workout.Rounds == 0 {
+			workout.Rounds = 8
+		}
+		if len(workout.Intervals) == 0 && len(workout.Blocks) == 0 {
+			workout.Intervals = []models.Interval{
+				{Name: "Work", Duration: 20, Type: models.IntervalTypeWork},
+				{Name: "Rest", Duration: 10, Type: models.IntervalTypeRest},
+			}
+		}
+	case models.WorkoutKindPomodoro:
+		var settings models.Pomodoro
+		if workout.Pomodoro != nil {
+			settings = *workout.Pomodoro
+		}
+		settings = settings.WithDefaults()
+		workout.Pomodoro = &settings
+		// One cycle past the first long break, since none follows the last
+		if workout.Rounds == 0 {
+			workout.Rounds = settings.LongBreakEvery + 1
+		}
+		// Keep the interval IDs so completion timings still refer to them
+		intervals := settings.Intervals()
+		for i := range intervals {
+			if i < len(workout.Intervals) {
+				intervals[i].ID = workout.Intervals[i].ID
+			}
+		}
+		workout.Intervals = intervals
+		workout.Blocks = nil
+	default:
+		workout.Pomodoro = nil
+	}
+}
+
+// validateInterval checks an interval's duration and metadata
+func validateInterval(interval *models.Interval) string {
+	if interval.Duration < 0 || interval.Duration > maxIntervalDuration {
+		return "Interval duration must be between 0 and " + strconv.Itoa(maxIntervalDuration) + " seconds"
+	}
+	interval.Type = strings.ToLower(strings.TrimSpace(interval.Type))
+	if interval.Type != "" && !isIntervalType(interval.Type) {
+		return "Interval type must be one of " + strings.Join(models.IntervalTypes, ", ")
+	}
+	if len(interval.Notes) > maxIntervalNotes {
+		return "Interval notes must be at most " + strconv.Itoa(maxIntervalNotes) + " characters"
+	}
+	if interval.TargetReps < 0 {
+		return "target_reps must not be negative"
+	}
+	if interval.TargetHRZone < 0 || interval.TargetHRZone > 5 {
+		return "target_hr_zone must be between 1 and 5"
+	}
+	if len(interval.ExerciseRef) > maxExerciseRef {
+		return "exercise_ref must be at most " + strconv.Itoa(maxExerciseRef) + " characters"
+	}
+	if interval.TimeCap < 0 || interval.TimeCap > maxIntervalDuration {
+		return "time_cap must be between 0 and " + strconv.Itoa(maxIntervalDuration) + " seconds"
+	}
+	if interval.Manual && interval.Progression != nil {
+		return "Manual intervals cannot have a progression"
+	}
+	if interval.Progression != nil {
+		if msg := validateProgression(interval.Progression); msg != "" {
+			return msg
+		}
+	}
+	if c := interval.Condition; c != nil && (c.FromRound < 0 || c.Every < 0 || c.SkipEvery < 0) {
+		return "Condition from_round, every and skip_every must not be negative"
+	}
+	return ""
+}
+
+// validateProgression checks the per-round duration rules of an interval
+func validateProgression(p *models.Progression) string {
+	switch p.Type {
+	case models.ProgressionStep:
+		if p.Step < -maxIntervalDuration || p.Step > maxIntervalDuration {
+			return "Progression step must be between -" + strconv.Itoa(maxIntervalDuration) + " and " + strconv.Itoa(maxIntervalDuration)
+		}
+	case models.ProgressionList:
+		if len(p.Durations) == 0 || len(p.Durations) > maxVaryingRounds {
+			return "A list progression needs between 1 and " + strconv.Itoa(maxVaryingRounds) + " durations"
+		}
+		for _, d := range p.Durations {
+			if d < 0 || d > maxIntervalDuration {
+				return "Progression durations must be between 0 and " + strconv.Itoa(maxIntervalDuration)
+			}
+		}
+	case models.ProgressionPercent:
+		if p.Percent < -100 || p.Percent > 1000 {
+			return "Progression percent must be between -100 and 1000"
+		}
+	default:
+		return "Progression type must be step, list or percent"
+	}
+	return ""
+}
+
+func isIntervalType(t string) bool {
+	for _, known := range models.IntervalTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// validateBlock checks a block and its sub-blocks, counting the intervals
+// they expand to in count
+func validateBlock(block *models.Block, depth int, count *int) string {
+	if depth > maxBlockDepth {
+		return "Blocks can be nested at most " + strconv.Itoa(maxBlockDepth) + " deep"
+	}
+	if block.Repeat < 1 || block.Repeat > maxExpandedIntervals {
+		return "Block repeat must be between 1 and " + strconv.Itoa(maxExpandedIntervals)
+	}
+	if len(block.Items) == 0 {
+		return "Blocks must contain at least one item"
+	}
+	before := *count
+	for _, item := range block.Items {
+		switch {
+		case (item.Interval == nil) == (item.Block == nil):
+			return "Each block item must be either an interval or a block"
+		case item.Interval != nil:
+			if msg := validateInterval(item.Interval); msg != "" {
+				return msg
+			}
+			*count++
+		default:
+			if msg := validateBlock(item.Block, depth+1, count); msg != "" {
+				return msg
+			}
+		}
+	}
+	// Repeats multiply everything this block added; compare before
+	// multiplying so the count can't overflow
+	added := *count - before
+	if added > (maxExpandedIntervals-before)/block.Repeat {
+		return "Blocks expand to more than " + strconv.Itoa(maxExpandedIntervals) + " intervals"
+	}
+	*count = before + added*block.Repeat
+	return ""
+}
+
+// workoutPath extracts the IDs from /api/workouts/:id[/intervals/:intervalId]
+func workoutPath(r *http.Request) (workoutID string, intervalID string) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/workouts/"), "/")
+	workoutID = parts[0]
+	if len(parts) >= 3 && parts[1] == "intervals" {
+		intervalID = parts[2]
+	}
+	return workoutID, intervalID
+}
+
+func (req *intervalRequest) applyTo(interval *models.Interval) {
+	if req.Name != nil {
+		interval.Name = *req.Name
+	}
+	if req.Duration != nil {
+		interval.Duration = *req.Duration
+	}
+	if req.Color != nil {
+		interval.Color = *req.Color
+	}
+	if req.Type != nil {
+		interval.Type = *req.Type
+	}
+	if req.Notes != nil {
+		interval.Notes = *req.Notes
+	}
+	if req.TargetReps != nil {
+		interval.TargetReps = *req.TargetReps
+	}
+	if req.TargetHRZone != nil {
+		interval.TargetHRZone = *req.TargetHRZone
+	}
+	if req.ExerciseRef != nil {
+		interval.ExerciseRef = *req.ExerciseRef
+	}
+	if req.Manual != nil {
+		interval.Manual = *req.Manual
+	}
+	if req.TimeCap != nil {
+		interval.TimeCap = *req.TimeCap
+	}
+	if req.Progression != nil {
+		interval.Progression = req.Progression
+	}
+	if req.Condition != nil {
+		interval.Condition = req.Condition
+	}
+}
+
+func findInterval(intervals []models.Interval, intervalID string) int {
+	for i, interval := range intervals {
+		if interval.ID == intervalID {
+			return i
+		}
+	}
+	return -1
+}
+
+func insertInterval(intervals []models.Interval, position int, interval models.Interval) []models.Interval {
+	intervals = append(intervals, models.Interval{})
+	copy(intervals[position+1:], intervals[position:])
+	intervals[position] = interval
+	return intervals
+}
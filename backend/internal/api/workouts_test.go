@@ -0,0 +1,771 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"intervals-sync/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// do sends an authenticated request to a handler and decodes the JSON response
+func do(t *testing.T, handler http.HandlerFunc, method, path, token, body string, out interface{}) int {
+	t.Helper()
+	var reader *bytes.Buffer
+	if body != "" {
+		reader = bytes.NewBufferString(body)
+	} else {
+		reader = &bytes.Buffer{}
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	handler(w, req)
+	if out != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: invalid JSON %q", method, path, w.Body.String())
+		}
+	}
+	return w.Code
+}
+
+func TestWorkoutsCRUD(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+	token := login(t, h, "test-profile")
+
+	var created models.Workout
+	body := `{"id":"ignored","name":" Tabata ","intervals":[{"name":"Work","duration":20},{"name":"Rest","duration":10}]}`
+	if code := do(t, h.CreateWorkout, http.MethodPost, "/api/workouts", token, body, &created); code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", code)
+	}
+	if created.ID == "ignored" || created.Name != "Tabata" || created.Rounds != 1 {
+		t.Errorf("unexpected workout: %+v", created)
+	}
+	if len(created.Intervals) != 2 || created.Intervals[1].Position != 1 || created.Intervals[0].ID == "" {
+		t.Errorf("expected numbered intervals with IDs, got %+v", created.Intervals)
+	}
+
+	if code := do(t, h.CreateWorkout, http.MethodPost, "/api/workouts", token, `{"name":""}`, nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for missing name, got %d", code)
+	}
+
+	// Full update replaces everything
+	var updated models.Workout
+	body = `{"name":"EMOM","rounds":10,"intervals":[{"name":"Go","duration":60}]}`
+	if code := do(t, h.UpdateWorkout, http.MethodPut, "/api/workouts/"+created.ID, token, body, &updated); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if updated.Name != "EMOM" || updated.Rounds != 10 || len(updated.Intervals) != 1 || !updated.CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("unexpected workout after PUT: %+v", updated)
+	}
+
+	// Partial update keeps the fields that are not sent
+	var patched models.Workout
+	if code := do(t, h.PatchWorkout, http.MethodPatch, "/api/workouts/"+created.ID, token, `{"rounds":5}`, &patched); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if patched.Name != "EMOM" || patched.Rounds != 5 || len(patched.Intervals) != 1 {
+		t.Errorf("unexpected workout after PATCH: %+v", patched)
+	}
+
+	// Other profiles cannot see or modify the workout
+	other := login(t, h, "other-profile")
+	if code := do(t, h.PatchWorkout, http.MethodPatch, "/api/workouts/"+created.ID, other, `{"rounds":1}`, nil); code != http.StatusNotFound {
+		t.Errorf("expected 404 for another profile, got %d", code)
+	}
+}
+
+func TestWorkoutIntervals(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+	token := login(t, h, "test-profile")
+
+	var workout models.Workout
+	do(t, h.CreateWorkout, http.MethodPost, "/api/workouts", token, `{"name":"Circuit","intervals":[{"name":"A","duration":30},{"name":"B","duration":30}]}`, &workout)
+	base := "/api/workouts/" + workout.ID + "/intervals"
+
+	var added models.Interval
+	if code := do(t, h.AddInterval, http.MethodPost, base, token, `{"name":"Warmup","duration":60,"position":0}`, &added); code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", code)
+	}
+	if added.Position != 0 || added.ID == "" {
+		t.Errorf("expected interval inserted first, got %+v", added)
+	}
+	if code := do(t, h.AddInterval, http.MethodPost, base, token, `{"name":"X","position":9}`, nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for out of range position, got %d", code)
+	}
+
+	// Move the warmup to the end and rename it
+	var moved models.Interval
+	if code := do(t, h.PatchInterval, http.MethodPatch, base+"/"+added.ID, token, `{"name":"Cooldown","position":2}`, &moved); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if moved.Name != "Cooldown" || moved.Duration != 60 || moved.Position != 2 {
+		t.Errorf("unexpected interval after PATCH: %+v", moved)
+	}
+
+	if code := do(t, h.DeleteInterval, http.MethodDelete, base+"/"+added.ID, token, "", nil); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	var list struct {
+		Intervals []models.Interval `json:"intervals"`
+	}
+	do(t, h.ListIntervals, http.MethodGet, base, token, "", &list)
+	if len(list.Intervals) != 2 || list.Intervals[0].Name != "A" || list.Intervals[1].Name != "B" {
+		t.Errorf("unexpected intervals after delete: %+v", list.Intervals)
+	}
+
+	if code := do(t, h.DeleteInterval, http.MethodDelete, base+"/missing", token, "", nil); code != http.StatusNotFound {
+		t.Errorf("expected 404 for missing interval, got %d", code)
+	}
+
+	// Round rules and manual intervals can be set one interval at a time
+	var ramped models.Interval
+	body := `{"progression":{"type":"step","step":5},"condition":{"skip_last_round":true}}`
+	if code := do(t, h.PatchInterval, http.MethodPatch, base+"/"+list.Intervals[0].ID, token, body, &ramped); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if ramped.Progression == nil || ramped.Progression.Step != 5 || ramped.Condition == nil || !ramped.Condition.SkipLastRound {
+		t.Errorf("expected progression and condition after PATCH, got %+v", ramped)
+	}
+	var manual models.Interval
+	if code := do(t, h.AddInterval, http.MethodPost, base, token, `{"name":"Burpees","manual":true,"time_cap":90}`, &manual); code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", code)
+	}
+	if !manual.Manual || manual.TimeCap != 90 {
+		t.Errorf("expected a capped manual interval, got %+v", manual)
+	}
+	for _, bad := range []string{
+		`{"time_cap":-1}`,
+		`{"progression":{"type":"bogus"}}`,
+		`{"condition":{"every":-1}}`,
+		`{"manual":true}`, // manual intervals cannot have the progression set above
+	} {
+		if code := do(t, h.PatchInterval, http.MethodPatch, base+"/"+list.Intervals[0].ID, token, bad, nil); code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %s, got %d", bad, code)
+		}
+	}
+}
+
+func TestListWorkouts(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+	token := login(t, h, "test-profile")
+
+	for _, name := range []string{"Charlie", "alpha", "Bravo", "100% effort"} {
+		do(t, h.CreateWorkout, http.MethodPost, "/api/workouts", token, `{"name":"`+name+`"}`, nil)
+	}
+
+	var page struct {
+		Workouts []models.Workout `json:"workouts"`
+		Total    int              `json:"total"`
+	}
+	if code := do(t, h.ListWorkouts, http.MethodGet, "/api/workouts?sort=name&limit=2&offset=1", token, "", &page); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if page.Total != 4 || len(page.Workouts) != 2 || page.Workouts[0].Name != "alpha" || page.Workouts[1].Name != "Bravo" {
+		t.Errorf("unexpected page: total=%d %+v", page.Total, page.Workouts)
+	}
+
+	// LIKE wildcards in the query are matched literally
+	do(t, h.ListWorkouts, http.MethodGet, "/api/workouts?q=%25", token, "", &page)
+	if page.Total != 1 || page.Workouts[0].Name != "100% effort" {
+		t.Errorf("expected only the literal %% match, got %+v", page.Workouts)
+	}
+
+	do(t, h.ListWorkouts, http.MethodGet, "/api/workouts?q=ALPHA&sort=-created_at", token, "", &page)
+	if page.Total != 1 || page.Workouts[0].Name != "alpha" {
+		t.Errorf("expected case-insensitive match, got %+v", page.Workouts)
+	}
+
+	for _, query := range []string{"sort=color", "limit=0", "limit=1000", "offset=-1", "updated_after=yesterday"} {
+		if code := do(t, h.ListWorkouts, http.MethodGet, "/api/workouts?"+query, token, "", nil); code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, code)
+		}
+	}
+}
+
+func TestWorkoutETags(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+	token := login(t, h, "test-profile")
+
+	var workout models.Workout
+	do(t, h.CreateWorkout, http.MethodPost, "/api/workouts", token, `{"name":"Tabata"}`, &workout)
+	path := "/api/workouts/" + workout.ID
+
+	send := func(handler http.HandlerFunc, method, header, value, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	res := send(h.GetWorkout, http.MethodGet, "", "", "")
+	etag := res.Header().Get("ETag")
+	if etag != `"1"` {
+		t.Fatalf("expected ETag \"1\", got %q", etag)
+	}
+	if res := send(h.GetWorkout, http.MethodGet, "If-None-Match", "W/"+etag, ""); res.Code != http.StatusNotModified || res.Body.Len() != 0 {
+		t.Errorf("expected empty 304 for a matching If-None-Match, got %d", res.Code)
+	}
+
+	// A write with the current ETag succeeds and returns the new one
+	res = send(h.PatchWorkout, http.MethodPatch, "If-Match", etag, `{"rounds":3}`)
+	if res.Code != http.StatusOK || res.Header().Get("ETag") != `"2"` {
+		t.Fatalf("expected 200 with ETag \"2\", got %d %q", res.Code, res.Header().Get("ETag"))
+	}
+
+	// The old ETag is now stale
+	if res := send(h.PatchWorkout, http.MethodPatch, "If-Match", etag, `{"rounds":4}`); res.Code != http.StatusPreconditionFailed {
+		t.Errorf("expected 412 for a stale If-Match, got %d", res.Code)
+	}
+	if res := send(h.DeleteWorkout, http.MethodDelete, "If-Match", etag, ""); res.Code != http.StatusPreconditionFailed {
+		t.Errorf("expected 412 deleting with a stale If-Match, got %d", res.Code)
+	}
+	if res := send(h.GetWorkout, http.MethodGet, "If-None-Match", etag, ""); res.Code != http.StatusOK {
+		t.Errorf("expected 200 for a stale If-None-Match, got %d", res.Code)
+	}
+
+	h.RequireIfMatch(true)
+	if res := send(h.PatchWorkout, http.MethodPatch, "", "", `{"rounds":4}`); res.Code != http.StatusPreconditionRequired {
+		t.Errorf("expected 428 without If-Match, got %d", res.Code)
+	}
+	if res := send(h.DeleteWorkout, http.MethodDelete, "If-Match", `"2"`, ""); res.Code != http.StatusOK {
+		t.Errorf("expected delete with the current ETag to succeed, got %d", res.Code)
+	}
+}
+
+func TestWorkoutBlocks(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+	token := login(t, h, "test-profile")
+
+	// Warmup, then 3 x (work, rest, 2 x sprint), then cooldown, twice over
+	body := `{"name":"Ladder","rounds":2,"blocks":[
+		{"name":"Warmup","repeat":1,"items":[{"interval":{"name":"Warmup","duration":60}}]},
+		{"name":"Main","repeat":3,"items":[
+			{"interval":{"name":"Work","duration":30}},
+			{"interval":{"name":"Rest","duration":15}},
+			{"block":{"name":"Sprints","repeat":2,"items":[{"interval":{"name":"Sprint","duration":10}}]}}
+		]},
+		{"name":"Cooldown","repeat":1,"items":[{"interval":{"name":"Cooldown","duration":60}}]}
+	]}`
+	var created models.Workout
+	if code := do(t, h.CreateWorkout, http.MethodPost, "/api/workouts", token, body, &created); code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", code)
+	}
+	if want := (60 + 3*(30+15+2*10) + 60) * 2; created.TotalDuration != want {
+		t.Errorf("expected total_duration %d, got %d", want, created.TotalDuration)
+	}
+	if created.Blocks[1].ID == "" || created.Blocks[1].Items[2].Block.Items[0].Interval.ID == "" {
+		t.Errorf("expected IDs on blocks and nested intervals, got %+v", created.Blocks)
+	}
+
+	// Flat clients see the expanded sequence with unique IDs
+	var fetched models.Workout
+	do(t, h.GetWorkout, http.MethodGet, "/api/workouts/"+created.ID, token, "", &fetched)
+	if len(fetched.Blocks) != 3 || len(fetched.Intervals) != 1+3*4+1 {
+		t.Fatalf("expected 3 blocks and 14 flattened intervals, got %d and %d", len(fetched.Blocks), len(fetched.Intervals))
+	}
+	ids := map[string]bool{}
+	for i, interval := range fetched.Intervals {
+		if ids[interval.ID] || interval.Position != i {
+			t.Errorf("interval %d has duplicate ID or wrong position: %+v", i, interval)
+		}
+		ids[interval.ID] = true
+	}
+	if fetched.Intervals[4].Name != "Sprint" || fetched.Intervals[13].Name != "Cooldown" {
+		t.Errorf("unexpected flattened order: %+v", fetched.Intervals)
+	}
+
+	// Intervals of block workouts are edited through the blocks
+	base := "/api/workouts/" + created.ID + "/intervals"
+	if code := do(t, h.AddInterval, http.MethodPost, base, token, `{"name":"X","duration":5}`, nil); code != http.StatusConflict {
+		t.Errorf("expected 409 adding an interval to a block workout, got %d", code)
+	}
+
+	// Intervals sent with blocks must be the ones the blocks expand to
+	roundTrip, _ := json.Marshal(fetched)
+	if code := do(t, h.UpdateWorkout, http.MethodPut, "/api/workouts/"+created.ID, token, string(roundTrip), nil); code != http.StatusOK {
+		t.Errorf("expected a fetched block workout to be saved back, got %d", code)
+	}
+	blocks, _ := json.Marshal(fetched.Blocks)
+	edited := `{"name":"Ladder","rounds":2,"blocks":` + string(blocks) + `,"intervals":[{"name":"X","duration":5}]}`
+	if code := do(t, h.UpdateWorkout, http.MethodPut, "/api/workouts/"+created.ID, token, edited, nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for intervals sent with blocks, got %d", code)
+	}
+	if code := do(t, h.PatchWorkout, http.MethodPatch, "/api/workouts/"+created.ID, token, `{"intervals":[{"name":"X","duration":5}]}`, nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 patching the intervals of a block workout, got %d", code)
+	}
+
+	// Clearing the blocks keeps the flattened intervals as a flat workout
+	var flat models.Workout
+	if code := do(t, h.PatchWorkout, http.MethodPatch, "/api/workouts/"+created.ID, token, `{"blocks":[]}`, &flat); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if len(flat.Blocks) != 0 || len(flat.Intervals) != 14 || flat.TotalDuration != created.TotalDuration {
+		t.Errorf("unexpected workout after clearing blocks: %+v", flat)
+	}
+
+	for _, bad := range []string{
+		`{"name":"X","blocks":[{"repeat":0,"items":[]}]}`,
+		`{"name":"X","blocks":[{"repeat":1,"items":[{}]}]}`,
+		`{"name":"X","blocks":[{"repeat":1,"items":[{"interval":{"duration":-1}}]}]}`,
+		`{"name":"X","blocks":[{"repeat":100,"items":[{"block":{"repeat":100,"items":[{"interval":{"duration":1}}]}}]}]}`,
+		`{"name":"X","blocks":[{"repeat":9000000000000000000,"items":[]}]}`,
+		`{"name":"X","blocks":[{"repeat":1,"items":[{"block":{"repeat":2,"items":[]}}]}]}`,
+		// 4 × 2^62 overflows to 0 if multiplied first
+		`{"name":"X","blocks":[{"repeat":4611686018427387904,"items":[{"interval":{"duration":1}},{"interval":{"duration":1}},{"interval":{"duration":1}},{"interval":{"duration":1}}]}]}`,
+		`{"name":"X","blocks":[{"repeat":2,"items":[{"block":{"repeat":4611686018427387904,"items":[{"interval":{"duration":1}},{"interval":{"duration":1}}]}}]}]}`,
+	} {
+		if code := do(t, h.CreateWorkout, http.MethodPost, "/api/workouts", token, bad, nil); code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %s, got %d", bad, code)
+		}
+	}
+}
+
+func TestIntervalMetadata(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+	token := login(t, h, "test-profile")
+
+	body := `{"name":"Circuit","rounds":3,"intervals":[
+		{"name":"Warmup","duration":60,"type":"warmup"},
+		{"name":"Squats","duration":40,"type":"Work","notes":"Keep heels down","target_reps":15,"target_hr_zone":4,"exercise_ref":"squat"},
+		{"name":"Rest","duration":20,"type":"rest"},
+		{"name":"Stretch","duration":30}
+	]}`
+	var created models.Workout
+	if code := do(t, h.CreateWorkout, http.MethodPost, "/api/workouts", token, body, &created); code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", code)
+	}
+
+	var fetched models.Workout
+	do(t, h.GetWorkout, http.MethodGet, "/api/workouts/"+created.ID, token, "", &fetched)
+	squats := fetched.Intervals[1]
+	if squats.Type != "work" || squats.Notes != "Keep heels down" || squats.TargetReps != 15 || squats.TargetHRZone != 4 || squats.ExerciseRef != "squat" {
+		t.Errorf("metadata not stored: %+v", squats)
+	}
+	want := map[string]int{"warmup": 180, "work": 120, "rest": 60}
+	for k, v := range want {
+		if fetched.DurationByType[k] != v {
+			t.Errorf("expected %s time %d, got %v", k, v, fetched.DurationByType)
+		}
+	}
+	if len(fetched.DurationByType) != 3 || fetched.TotalDuration != 450 {
+		t.Errorf("unexpected durations: total %d, by type %v", fetched.TotalDuration, fetched.DurationByType)
+	}
+
+	// The interval routes accept the same fields
+	var patched models.Interval
+	path := "/api/workouts/" + created.ID + "/intervals/" + created.Intervals[3].ID
+	if code := do(t, h.PatchInterval, http.MethodPatch, path, token, `{"type":"cooldown"}`, &patched); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if patched.Type != "cooldown" || patched.Duration != 30 {
+		t.Errorf("unexpected interval after PATCH: %+v", patched)
+	}
+
+	for _, bad := range []string{
+		`{"name":"X","intervals":[{"duration":10,"type":"nap"}]}`,
+		`{"name":"X","intervals":[{"duration":10,"target_hr_zone":6}]}`,
+		`{"name":"X","intervals":[{"duration":10,"target_reps":-1}]}`,
+	} {
+		if code := do(t, h.CreateWorkout, http.MethodPost, "/api/workouts", token, bad, nil); code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %s, got %d", bad, code)
+		}
+	}
+}
+
+func TestIntervalProgression(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+	token := login(t, h, "test-profile")
+
+	// A 20/30/40/30/20 pyramid, a ladder shortening by 5s and a rest growing 50% per round
+	body := `{"name":"Pyramid","rounds":5,"intervals":[
+		{"name":"Work","duration":20,"type":"work","progression":{"type":"list","durations":[20,30,40,30,20]}},
+		{"name":"Ladder","duration":20,"progression":{"type":"step","step":-5}},
+		{"name":"Rest","duration":10,"type":"rest","progression":{"type":"percent","percent":50}}
+	]}`
+	var created models.Workout
+	if code := do(t, h.CreateWorkout, http.MethodPost, "/api/workouts", token, body, &created); code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", code)
+	}
+	work, ladder, rest := 140, 20+15+10+5+0, 10+15+20+25+30
+	if created.TotalDuration != work+ladder+rest {
+		t.Errorf("expected total_duration %d, got %d", work+ladder+rest, created.TotalDuration)
+	}
+	if created.DurationByType["work"] != work || created.DurationByType["rest"] != rest {
+		t.Errorf("unexpected duration_by_type: %v", created.DurationByType)
+	}
+
+	var fetched models.Workout
+	do(t, h.GetWorkout, http.MethodGet, "/api/workouts/"+created.ID, token, "", &fetched)
+	if p := fetched.Intervals[0].Progression; p == nil || p.Type != "list" || len(p.Durations) != 5 {
+		t.Errorf("progression not stored: %+v", p)
+	}
+
+	// Progressions inside blocks follow the workout round too
+	body = `{"name":"Blocks","rounds":3,"blocks":[{"repeat":2,"items":[
+		{"interval":{"duration":10,"progression":{"type":"step","step":10}}}
+	]}]}`
+	if code := do(t, h.CreateWorkout, http.MethodPost, "/api/workouts", token, body, &created); code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", code)
+	}
+	if want := 2 * (10 + 20 + 30); created.TotalDuration != want {
+		t.Errorf("expected total_duration %d, got %d", want, created.TotalDuration)
+	}
+
+	for _, bad := range []string{
+		`{"name":"X","intervals":[{"duration":10,"progression":{"type":"wave"}}]}`,
+		`{"name":"X","intervals":[{"duration":10,"progression":{"type":"list"}}]}`,
+		`{"name":"X","intervals":[{"duration":10,"progression":{"type":"list","durations":[10,-1]}}]}`,
+		`{"name":"X","intervals":[{"duration":10,"progression":{"type":"percent","percent":-200}}]}`,
+		`{"name":"X","rounds":5000,"intervals":[{"duration":10,"progression":{"type":"step","step":1}}]}`,
+		`{"name":"X","intervals":[{"duration":86401}]}`,
+		`{"name":"X","intervals":[{"duration":10,"progression":{"type":"step","step":9223372036854775807}}]}`,
+		`{"name":"X","intervals":[{"duration":10,"progression":{"type":"list","durations":[86401]}}]}`,
+		// Within limits per interval, but 86000 + 100*(1000-1) is over a day by the last round
+		`{"name":"X","rounds":1000,"intervals":[{"duration":86000,"progression":{"type":"step","step":100}}]}`,
+		`{"name":"X","rounds":3,"blocks":[{"repeat":1,"items":[{"interval":{"duration":80000,"progression":{"type":"percent","percent":10}}}]}]}`,
+	} {
+		if code := do(t, h.CreateWorkout, http.MethodPost, "/api/workouts", token, bad, nil); code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %s, got %d", bad, code)
+		}
+	}
+
+	// Sync skips workouts the server could not expand and stores the rest
+	body = `{"last_synced_at":0,"workouts":[
+		{"id":"w1","name":"Huge","rounds":1,"blocks":[{"repeat":100000,"items":[{"interval":{"duration":1}}]}]},
+		{"id":"w2","name":"Fine","rounds":1,"intervals":[{"id":"i1","name":"Work","duration":30}]}
+	],"completions":[{"id":"c1","workout_id":"w1","started_at":"2026-03-02T09:00:00Z"}]}`
+	var synced models.SyncPayload
+	if code := do(t, h.Sync, http.MethodPost, "/api/sync", token, body, &synced); code != http.StatusOK {
+		t.Fatalf("expected 200 syncing an oversized workout, got %d", code)
+	}
+	if len(synced.Rejected) != 1 || synced.Rejected[0].ID != "w1" || synced.Rejected[0].Error == "" {
+		t.Errorf("expected w1 to be rejected, got %+v", synced.Rejected)
+	}
+	if _, err := h.store.GetWorkout("test-profile", "w1"); err == nil {
+		t.Error("expected the oversized workout not to be stored")
+	}
+	if _, err := h.store.GetWorkout("test-profile", "w2"); err != nil {
+		t.Errorf("expected the valid workout to be stored: %v", err)
+	}
+	if _, err := h.store.GetCompletion("test-profile", "c1"); err != nil {
+		t.Errorf("expected the completion to be stored: %v", err)
+	}
+}
+
+func TestIntervalConditions(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+	token := login(t, h, "test-profile")
+
+	body := `{"name":"Conditional","rounds":3,"intervals":[
+		{"name":"Work","duration":20,"type":"work"},
+		{"name":"Rest","duration":10,"type":"rest","condition":{"skip_last_round":true}},
+		{"name":"Bonus","duration":15,"condition":{"every":2}},
+		{"name":"Finisher","duration":30,"progression":{"type":"step","step":5},"condition":{"from_round":3}}
+	]}`
+	var created models.Workout
+	if code := do(t, h.CreateWorkout, http.MethodPost, "/api/workouts", token, body, &created); code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", code)
+	}
+	// Finisher runs only in round 3, where its progression makes it 40s
+	if want := 3*20 + 2*10 + 2*15 + 40; created.TotalDuration != want {
+		t.Errorf("expected total_duration %d, got %d", want, created.TotalDuration)
+	}
+	if created.DurationByType["rest"] != 20 {
+		t.Errorf("expected 20s of rest, got %v", created.DurationByType)
+	}
+
+	var timeline struct {
+		Steps         []models.Step `json:"steps"`
+		TotalDuration int           `json:"total_duration"`
+	}
+	path := "/api/workouts/" + created.ID + "/timeline"
+	if code := do(t, h.GetTimeline, http.MethodGet, path, token, "", &timeline); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	var names []string
+	for _, step := range timeline.Steps {
+		names = append(names, step.Name)
+	}
+	want := []string{"Work", "Rest", "Bonus", "Work", "Rest", "Work", "Bonus", "Finisher"}
+	if len(names) != len(want) {
+		t.Fatalf("expected steps %v, got %v", want, names)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("expected steps %v, got %v", want, names)
+		}
+	}
+	last := timeline.Steps[len(timeline.Steps)-1]
+	if last.Round != 3 || last.Duration != 40 || last.Start != created.TotalDuration-40 || last.Condition != nil {
+		t.Errorf("unexpected last step: %+v", last)
+	}
+
+	// Conditions are kept on the stored intervals
+	var fetched models.Workout
+	do(t, h.GetWorkout, http.MethodGet, "/api/workouts/"+created.ID, token, "", &fetched)
+	if c := fetched.Intervals[2].Condition; c == nil || c.Every != 2 {
+		t.Errorf("condition not stored: %+v", c)
+	}
+
+	if code := do(t, h.CreateWorkout, http.MethodPost, "/api/workouts", token, `{"name":"X","intervals":[{"duration":10,"condition":{"every":-1}}]}`, nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a negative condition, got %d", code)
+	}
+
+	// Rounds are capped, and 2^62 rounds of 4 intervals must not overflow
+	// the timeline limit for workouts stored before the cap
+	huge := `{"name":"X","rounds":4611686018427387904,"intervals":[{"duration":1},{"duration":1},{"duration":1},{"duration":1}]}`
+	if code := do(t, h.CreateWorkout, http.MethodPost, "/api/workouts", token, huge, nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for too many rounds, got %d", code)
+	}
+	old := models.Workout{ID: "old", UserID: "test-profile", Name: "Old", Rounds: 1 << 62, Intervals: []models.Interval{
+		{ID: "a", Duration: 1}, {ID: "b", Duration: 1}, {ID: "c", Duration: 1}, {ID: "d", Duration: 1},
+	}}
+	if _, err := h.store.UpsertWorkout(&old); err != nil {
+		t.Fatal(err)
+	}
+	if code := do(t, h.GetTimeline, http.MethodGet, "/api/workouts/old/timeline", token, "", nil); code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for an oversized timeline, got %d", code)
+	}
+}
+
+func TestManualIntervals(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+	token := login(t, h, "test-profile")
+
+	body := `{"name":"Circuit","rounds":2,"intervals":[
+		{"name":"Push-ups","manual":true,"time_cap":60,"target_reps":10},
+		{"name":"Rest","duration":30,"condition":{"skip_last_round":true}},
+		{"name":"Squats","manual":true,"time_cap":90}
+	]}`
+	var capped models.Workout
+	if code := do(t, h.CreateWorkout, http.MethodPost, "/api/workouts", token, body, &capped); code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", code)
+	}
+	r := capped.DurationRange
+	if capped.TotalDuration != 30 || r == nil || r.Min != 30 || r.Max == nil || *r.Max != 30+2*(60+90) {
+		t.Errorf("unexpected duration range: total %d, range %+v", capped.TotalDuration, r)
+	}
+
+	// A manual interval without a cap leaves the range open-ended
+	var open models.Workout
+	body = `{"name":"Open","intervals":[{"name":"Work","duration":20},{"name":"Max reps","manual":true}]}`
+	do(t, h.CreateWorkout, http.MethodPost, "/api/workouts", token, body, &open)
+	if r := open.DurationRange; r == nil || r.Min != 20 || r.Max != nil {
+		t.Errorf("expected an open-ended range from 20, got %+v", r)
+	}
+
+	// A manual interval's duration is ignored in the per-type totals too
+	var typed models.Workout
+	body = `{"name":"Typed","rounds":3,"intervals":[{"manual":true,"duration":100,"type":"work"},{"duration":10,"type":"rest"}]}`
+	do(t, h.CreateWorkout, http.MethodPost, "/api/workouts", token, body, &typed)
+	if typed.DurationByType["work"] != 0 || typed.DurationByType["rest"] != 30 || typed.TotalDuration != 30 {
+		t.Errorf("expected only the rest to count, got total %d and %v", typed.TotalDuration, typed.DurationByType)
+	}
+
+	// Timed workouts have no range
+	var timed models.Workout
+	do(t, h.CreateWorkout, http.MethodPost, "/api/workouts", token, `{"name":"Timed","intervals":[{"duration":20}]}`, &timed)
+	if timed.DurationRange != nil {
+		t.Errorf("expected no range for a timed workout, got %+v", timed.DurationRange)
+	}
+
+	var timeline struct {
+		Steps []models.Step `json:"steps"`
+	}
+	do(t, h.GetTimeline, http.MethodGet, "/api/workouts/"+capped.ID+"/timeline", token, "", &timeline)
+	if len(timeline.Steps) != 5 || !timeline.Steps[0].Manual || timeline.Steps[0].Duration != 0 || timeline.Steps[0].TimeCap != 60 {
+		t.Errorf("unexpected timeline: %+v", timeline.Steps)
+	}
+
+	for _, bad := range []string{
+		`{"name":"X","intervals":[{"manual":true,"time_cap":-1}]}`,
+		`{"name":"X","intervals":[{"manual":true,"progression":{"type":"step","step":5}}]}`,
+	} {
+		if code := do(t, h.CreateWorkout, http.MethodPost, "/api/workouts", token, bad, nil); code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %s, got %d", bad, code)
+		}
+	}
+
+	// Completions record how long the manual intervals took
+	pushups := capped.Intervals[0].ID
+	body = `{"completions":[{"id":"c1","workout_id":"` + capped.ID + `","started_at":"2026-03-01T09:00:00Z","updated_at":"2026-03-01T09:05:00Z",
+		"interval_timings":[{"interval_id":"` + pushups + `","round":1,"duration":42},{"interval_id":"` + pushups + `","round":2,"duration":51}]}]}`
+	if code := do(t, h.Sync, http.MethodPost, "/api/sync", token, body, nil); code != http.StatusOK {
+		t.Fatalf("sync failed: %d", code)
+	}
+	// A later update without timings keeps them
+	h.rl = NewRateLimiter()
+	body = `{"completions":[{"id":"c1","workout_id":"` + capped.ID + `","started_at":"2026-03-01T09:00:00Z","updated_at":"2026-03-01T09:10:00Z","completed":true}]}`
+	do(t, h.Sync, http.MethodPost, "/api/sync", token, body, nil)
+
+	var c models.Completion
+	do(t, h.GetCompletion, http.MethodGet, "/api/completions/c1", token, "", &c)
+	if !c.Completed || len(c.IntervalTimings) != 2 || c.IntervalTimings[1].Round != 2 || c.IntervalTimings[1].Duration != 51 {
+		t.Errorf("unexpected interval timings: %+v", c)
+	}
+}
+
+func TestWorkoutKinds(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+	token := login(t, h, "test-profile")
+
+	// Tabata fills in the classic preset
+	var tabata models.Workout
+	if code := do(t, h.CreateWorkout, http.MethodPost, "/api/workouts", token, `{"name":"Tabata","kind":"Tabata"}`, &tabata); code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", code)
+	}
+	if tabata.Kind != "tabata" || tabata.Rounds != 8 || len(tabata.Intervals) != 2 || tabata.TotalDuration != 240 {
+		t.Errorf("unexpected tabata workout: %+v", tabata)
+	}
+
+	// EMOM rounds last a full period whatever the work takes
+	var emom models.Workout
+	body := `{"name":"EMOM","kind":"emom","rounds":10,"intervals":[{"name":"Burpees","duration":40}]}`
+	if code := do(t, h.CreateWorkout, http.MethodPost, "/api/workouts", token, body, &emom); code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", code)
+	}
+	if emom.TotalDuration != 600 {
+		t.Errorf("expected 600s, got %d", emom.TotalDuration)
+	}
+	var timeline struct {
+		Steps []models.Step `json:"steps"`
+	}
+	do(t, h.GetTimeline, http.MethodGet, "/api/workouts/"+emom.ID+"/timeline", token, "", &timeline)
+	if len(timeline.Steps) != 10 || timeline.Steps[1].Start != 60 {
+		t.Errorf("expected rounds starting every minute, got %+v", timeline.Steps)
+	}
+
+	// AMRAP lasts its cap
+	var amrap models.Workout
+	body = `{"name":"Cindy","kind":"amrap","time_cap":1200,"intervals":[{"name":"Pull-ups","manual":true,"type":"work"},{"name":"Push-ups","manual":true}]}`
+	if code := do(t, h.CreateWorkout, http.MethodPost, "/api/workouts", token, body, &amrap); code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", code)
+	}
+	if amrap.TotalDuration != 1200 || amrap.DurationRange != nil || amrap.DurationByType != nil {
+		t.Errorf("unexpected AMRAP workout: %+v", amrap)
+	}
+
+	// For-Time stops at its cap
+	var forTime models.Workout
+	body = `{"name":"Fran","kind":"for_time","rounds":3,"time_cap":300,"intervals":[{"name":"Thrusters","manual":true}]}`
+	do(t, h.CreateWorkout, http.MethodPost, "/api/workouts", token, body, &forTime)
+	if r := forTime.DurationRange; r == nil || r.Min != 0 || r.Max == nil || *r.Max != 300 {
+		t.Errorf("expected a range up to the cap, got %+v", r)
+	}
+
+	for _, bad := range []string{
+		`{"name":"X","kind":"chipper"}`,
+		`{"name":"X","kind":"amrap","intervals":[{"duration":60}]}`,
+		`{"name":"X","time_cap":60,"intervals":[{"duration":60}]}`,
+		`{"name":"X","period":30,"intervals":[{"duration":20}]}`,
+		`{"name":"X","kind":"emom","intervals":[{"duration":70}]}`,
+		`{"name":"X","kind":"emom","period":30,"intervals":[{"duration":40}]}`,
+	} {
+		if code := do(t, h.CreateWorkout, http.MethodPost, "/api/workouts", token, bad, nil); code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %s, got %d", bad, code)
+		}
+	}
+
+	// Changing the kind is recorded in the history
+	var patched models.Workout
+	if code := do(t, h.PatchWorkout, http.MethodPatch, "/api/workouts/"+emom.ID, token, `{"kind":"","rounds":5}`, &patched); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if patched.Kind != "" || patched.TotalDuration != 200 {
+		t.Errorf("unexpected workout after PATCH: %+v", patched)
+	}
+	var diff models.WorkoutDiff
+	do(t, h.DiffWorkoutRevisions, http.MethodGet, "/api/workouts/"+emom.ID+"/revisions/diff?from=1", token, "", &diff)
+	if diff.Kind == nil || diff.Kind.From != "emom" {
+		t.Errorf("expected a kind change in the diff, got %+v", diff)
+	}
+
+	// Completions keep the score
+	body = `{"completions":[{"id":"c1","workout_id":"` + amrap.ID + `","started_at":"2026-03-01T09:00:00Z","completed":true,"score":{"rounds":7,"reps":12}}]}`
+	if code := do(t, h.Sync, http.MethodPost, "/api/sync", token, body, nil); code != http.StatusOK {
+		t.Fatalf("sync failed: %d", code)
+	}
+	var c models.Completion
+	do(t, h.GetCompletion, http.MethodGet, "/api/completions/c1", token, "", &c)
+	if c.Score == nil || c.Score.Rounds != 7 || c.Score.Reps != 12 {
+		t.Errorf("unexpected score: %+v", c.Score)
+	}
+}
+
+func TestPomodoroWorkout(t *testing.T) {
+	h, cleanup := setupTestHandler(t)
+	defer cleanup()
+	token := login(t, h, "test-profile")
+
+	// Defaults: 5 cycles of 25 minutes with 5 minute breaks in between and a
+	// 15 minute break after the fourth
+	var created models.Workout
+	if code := do(t, h.CreateWorkout, http.MethodPost, "/api/workouts", token, `{"name":"Deep work","kind":"pomodoro"}`, &created); code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", code)
+	}
+	if created.Rounds != 5 || len(created.Intervals) != 3 || created.Pomodoro == nil || created.Pomodoro.LongBreak != 900 {
+		t.Fatalf("unexpected pomodoro workout: %+v", created)
+	}
+	if want := 5*1500 + 3*300 + 900; created.TotalDuration != want {
+		t.Errorf("expected total_duration %d, got %d", want, created.TotalDuration)
+	}
+	var timeline struct {
+		Steps []models.Step `json:"steps"`
+	}
+	do(t, h.GetTimeline, http.MethodGet, "/api/workouts/"+created.ID+"/timeline", token, "", &timeline)
+	if len(timeline.Steps) != 9 || timeline.Steps[7].Name != "Long break" || timeline.Steps[7].Round != 4 || timeline.Steps[8].Name != "Focus" {
+		t.Errorf("expected a long break after the fourth cycle, got %+v", timeline.Steps)
+	}
+
+	// Eight cycles get a long break after the fourth
+	var patched models.Workout
+	if code := do(t, h.PatchWorkout, http.MethodPatch, "/api/workouts/"+created.ID, token, `{"rounds":8}`, &patched); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if want := 8*1500 + 6*300 + 900; patched.TotalDuration != want {
+		t.Errorf("expected total_duration %d, got %d", want, patched.TotalDuration)
+	}
+	if patched.DurationByType["work"] != 8*1500 {
+		t.Errorf("expected 8 focus cycles, got %v", patched.DurationByType)
+	}
+	if patched.Intervals[0].ID != created.Intervals[0].ID {
+		t.Errorf("expected interval IDs to be kept, got %q and %q", created.Intervals[0].ID, patched.Intervals[0].ID)
+	}
+
+	// Custom settings rebuild the intervals
+	body := `{"pomodoro":{"focus":3000,"short_break":600,"long_break":1800,"long_break_every":2}}`
+	do(t, h.PatchWorkout, http.MethodPatch, "/api/workouts/"+created.ID, token, body, &patched)
+	if patched.Intervals[0].Duration != 3000 || patched.TotalDuration != 8*3000+4*600+3*1800 {
+		t.Errorf("unexpected workout after changing settings: %+v", patched)
+	}
+
+	if code := do(t, h.AddInterval, http.MethodPost, "/api/workouts/"+created.ID+"/intervals", token, `{"duration":60}`, nil); code != http.StatusConflict {
+		t.Errorf("expected 409 adding an interval to a pomodoro workout, got %d", code)
+	}
+	if code := do(t, h.CreateWorkout, http.MethodPost, "/api/workouts", token, `{"name":"X","kind":"pomodoro","pomodoro":{"focus":-1}}`, nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a negative focus time, got %d", code)
+	}
+
+	// Switching to another kind drops the settings
+	var plain models.Workout
+	do(t, h.PatchWorkout, http.MethodPatch, "/api/workouts/"+created.ID, token, `{"kind":""}`, &plain)
+	if plain.Kind != "" || plain.Pomodoro != nil || len(plain.Intervals) != 3 {
+		t.Errorf("expected plain intervals without settings, got %+v", plain)
+	}
+}
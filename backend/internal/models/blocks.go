@@ -0,0 +1,158 @@
+package models
+
+import "strconv"
+
+// Duration is the length of one pass through the block's items times Repeat,
+// in seconds, during the given 0-indexed round of a workout with the given
+// number of rounds
+func (b *Block) Duration(round, rounds int) int {
+	total := 0
+	for _, item := range b.Items {
+		switch {
+		case item.Interval != nil:
+			total += item.Interval.durationInRound(round, rounds)
+		case item.Block != nil:
+			total += item.Block.Duration(round, rounds)
+		}
+	}
+	return total * b.Repeat
+}
+
+// RoundDuration is the length in seconds of the given 0-indexed round: its
+// blocks, or for flat workouts its intervals
+func (w *Workout) RoundDuration(round int) int {
+	total := 0
+	if len(w.Blocks) > 0 {
+		for i := range w.Blocks {
+			total += w.Blocks[i].Duration(round, w.Rounds)
+		}
+	} else {
+		for i := range w.Intervals {
+			total += w.Intervals[i].durationInRound(round, w.Rounds)
+		}
+	}
+	return total
+}
+
+// Duration is the total length of the workout in seconds. EMOM workouts last
+// Rounds periods and AMRAP workouts their time cap; For-Time workouts stop at
+// their cap if they have one.
+func (w *Workout) Duration() int {
+	switch w.Kind {
+	case WorkoutKindEMOM:
+		return w.Rounds * w.RoundPeriod()
+	case WorkoutKindAMRAP:
+		return w.TimeCap
+	}
+	total := w.intervalDuration()
+	if w.Kind == WorkoutKindForTime && w.TimeCap > 0 && total > w.TimeCap {
+		return w.TimeCap
+	}
+	return total
+}
+
+// RoundPeriod is the length in seconds of each EMOM round
+func (w *Workout) RoundPeriod() int {
+	if w.Period > 0 {
+		return w.Period
+	}
+	return 60
+}
+
+// intervalDuration is the length in seconds of all rounds of intervals
+func (w *Workout) intervalDuration() int {
+	if !w.VariesByRound() {
+		return w.RoundDuration(0) * w.Rounds
+	}
+	total := 0
+	for round := 0; round < w.Rounds; round++ {
+		total += w.RoundDuration(round)
+	}
+	return total
+}
+
+// VariesByRound reports whether any interval has a progression or a
+// condition, so rounds may differ from each other
+func (w *Workout) VariesByRound() bool {
+	varies := func(i *Interval) bool { return i.Progression != nil || i.Condition != nil }
+	for i := range w.Intervals {
+		if varies(&w.Intervals[i]) {
+			return true
+		}
+	}
+	var walk func(blocks []Block) bool
+	walk = func(blocks []Block) bool {
+		for _, b := range blocks {
+			for _, item := range b.Items {
+				if item.Interval != nil && varies(item.Interval) {
+					return true
+				}
+				if item.Block != nil && walk([]Block{*item.Block}) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return walk(w.Blocks)
+}
+
+// TypeDurations sums the workout's time per interval type over all rounds.
+// For block workouts it expects Intervals to hold the flattened blocks.
+// Manual intervals have no set duration and are left out. AMRAP workouts
+// have no fixed number of rounds and return nil.
+func (w *Workout) TypeDurations() map[string]int {
+	if w.Kind == WorkoutKindAMRAP {
+		return nil
+	}
+	var totals map[string]int
+	for i := range w.Intervals {
+		interval := &w.Intervals[i]
+		if interval.Type == "" || interval.Manual {
+			continue
+		}
+		if totals == nil {
+			totals = map[string]int{}
+		}
+		if interval.Progression == nil && interval.Condition == nil {
+			totals[interval.Type] += interval.Duration * w.Rounds
+			continue
+		}
+		for round := 0; round < w.Rounds; round++ {
+			totals[interval.Type] += interval.durationInRound(round, w.Rounds)
+		}
+	}
+	return totals
+}
+
+// FlattenBlocks expands blocks and their repeats into the sequence of
+// intervals performed in one round, for clients that only understand flat
+// workouts. Repeated intervals get their ID suffixed with "#<n>" so IDs stay
+// unique, and positions follow the expanded order.
+func FlattenBlocks(blocks []Block) []Interval {
+	intervals := []Interval{}
+	seen := map[string]int{}
+	var walk func(b *Block)
+	walk = func(b *Block) {
+		for r := 0; r < b.Repeat; r++ {
+			for _, item := range b.Items {
+				switch {
+				case item.Interval != nil:
+					interval := *item.Interval
+					seen[interval.ID]++
+					if n := seen[interval.ID]; n > 1 {
+						interval.ID += "#" + strconv.Itoa(n)
+					}
+					interval.Position = len(intervals)
+					intervals = append(intervals, interval)
+				case item.Block != nil:
+					walk(item.Block)
+				}
+			}
+		}
+	}
+	for i := range blocks {
+		walk(&blocks[i])
+	}
+	return intervals
+}
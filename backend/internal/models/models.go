@@ -7,18 +7,93 @@ type Session struct {
 	Token     string    `json:"token"`
 	UserID    string    `json:"user_id"` // profile name hash
 	CreatedAt time.Time `json:"created_at"`
+	Scopes    []string  `json:"scopes,omitempty"` // empty for full-access sessions
+}
+
+// APIToken represents a long-lived personal access token for a profile
+type APIToken struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"` // profile name hash
+	Name      string    `json:"name"`
+	TokenHash string    `json:"-"` // SHA-256 hash of the token, never returned
+	Scopes    []string  `json:"scopes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Group is a tenant with its own password and profile namespace
+type Group struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	PasswordHash string    `json:"-"` // SHA-256 hash of the group password
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ServerPassword is an accepted password for the default group. Rotated
+// passwords keep working until ExpiresAt.
+type ServerPassword struct {
+	PasswordHash string     `json:"-"` // SHA-256 hash of the password
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// LoginFailure tracks failed password attempts for one client IP or profile
+type LoginFailure struct {
+	Key           string    `json:"key"` // "ip:<addr>" or "profile:<name>"
+	Failures      int       `json:"failures"`
+	LastFailureAt time.Time `json:"last_failure_at"`
+	LockedUntil   time.Time `json:"locked_until"`
+}
+
+// SessionRevocation invalidates signed session tokens before they expire.
+// It names either a single token (TokenID) or every token issued to UserID
+// before RevokedAt. A group-only UserID ("group:<id>:") covers the whole group.
+type SessionRevocation struct {
+	TokenID   string    `json:"token_id,omitempty"`
+	UserID    string    `json:"user_id,omitempty"`
+	RevokedAt time.Time `json:"revoked_at"`
+	ExpiresAt time.Time `json:"expires_at"` // when the revoked tokens would have expired anyway
 }
 
 // Workout represents a workout/interval timer configuration
 type Workout struct {
-	ID        string      `json:"id"`
-	UserID    string      `json:"user_id"` // profile name hash
-	Name      string      `json:"name"`
-	Rounds    int         `json:"rounds"`
-	Intervals []Interval  `json:"intervals"`
-	CreatedAt time.Time   `json:"created_at"`
-	UpdatedAt time.Time   `json:"updated_at"`
-	DeletedAt *time.Time  `json:"deleted_at,omitempty"`
+	ID             string         `json:"id"`
+	UserID         string         `json:"user_id"` // profile name hash
+	Name           string         `json:"name"`
+	Rounds         int            `json:"rounds"`
+	Intervals      []Interval     `json:"intervals"`
+	Blocks         []Block        `json:"blocks,omitempty"`           // nested structure; Intervals is derived from it when set
+	Kind           string         `json:"kind,omitempty"`             // one of the WorkoutKind* values, "" for plain rounds of intervals
+	Period         int            `json:"period,omitempty"`           // EMOM: seconds per round, 60 if unset
+	TimeCap        int            `json:"time_cap,omitempty"`         // AMRAP: length in seconds; For-Time: optional limit
+	Pomodoro       *Pomodoro      `json:"pomodoro,omitempty"`         // pomodoro: cycle rules the intervals are built from
+	Version        int64          `json:"version"`                    // incremented on every write
+	TotalDuration  int            `json:"total_duration"`             // seconds, computed by the server
+	DurationByType map[string]int `json:"duration_by_type,omitempty"` // seconds per interval type; untyped intervals are not counted
+	DurationRange  *DurationRange `json:"duration_range,omitempty"`   // set when the workout has manual intervals
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      *time.Time     `json:"deleted_at,omitempty"`
+}
+
+// Workout kinds
+const (
+	WorkoutKindEMOM     = "emom"     // each round starts on the next Period, e.g. every minute
+	WorkoutKindAMRAP    = "amrap"    // as many rounds as possible within TimeCap
+	WorkoutKindTabata   = "tabata"   // 8 rounds of 20s work and 10s rest unless set otherwise
+	WorkoutKindForTime  = "for_time" // finish all rounds as fast as possible, within TimeCap if set
+	WorkoutKindPomodoro = "pomodoro" // Rounds focus cycles with short and long breaks
+)
+
+// WorkoutKinds lists the valid workout kinds
+var WorkoutKinds = []string{WorkoutKindEMOM, WorkoutKindAMRAP, WorkoutKindTabata, WorkoutKindForTime, WorkoutKindPomodoro}
+
+// Pomodoro holds the cycle rules of a pomodoro workout, in seconds. Unset
+// values take the classic 25/5/15 minutes with a long break every 4 cycles.
+type Pomodoro struct {
+	Focus          int `json:"focus"`
+	ShortBreak     int `json:"short_break"`
+	LongBreak      int `json:"long_break"`
+	LongBreakEvery int `json:"long_break_every"` // cycles
 }
 
 // Interval represents a single interval within a workout
@@ -28,6 +103,156 @@ type Interval struct {
 	Duration int    `json:"duration"` // seconds
 	Color    string `json:"color"`
 	Position int    `json:"position"` // 0-indexed order
+
+	// Optional metadata
+	Type         string `json:"type,omitempty"` // one of the IntervalType* values
+	Notes        string `json:"notes,omitempty"`
+	TargetReps   int    `json:"target_reps,omitempty"`
+	TargetHRZone int    `json:"target_hr_zone,omitempty"` // 1-5
+	ExerciseRef  string `json:"exercise_ref,omitempty"`   // free-form exercise ID or URL
+
+	// Manual intervals have no fixed duration and end when the user moves on,
+	// e.g. "10 push-ups". Duration is ignored; TimeCap, if set, is the most
+	// they may take in seconds.
+	Manual  bool `json:"manual,omitempty"`
+	TimeCap int  `json:"time_cap,omitempty"`
+
+	// Progression changes the duration from round to round; nil keeps
+	// Duration in every round
+	Progression *Progression `json:"progression,omitempty"`
+	// Condition limits the rounds the interval is performed in; nil
+	// performs it in every round
+	Condition *Condition `json:"condition,omitempty"`
+}
+
+// Condition selects the workout rounds an interval is performed in. Rounds
+// are numbered from 1; an interval runs in rounds FromRound, FromRound+Every,
+// FromRound+2*Every... (every round from FromRound when Every is 0).
+type Condition struct {
+	SkipLastRound bool `json:"skip_last_round,omitempty"`
+	FromRound     int  `json:"from_round,omitempty"` // 0 means 1
+	Every         int  `json:"every,omitempty"`
+	SkipEvery     int  `json:"skip_every,omitempty"` // skips rounds that are multiples of it
+}
+
+// DurationRange bounds the length in seconds of a workout with manual
+// intervals: Min counts them as taking no time, Max at their time caps
+type DurationRange struct {
+	Min int  `json:"min"`
+	Max *int `json:"max"` // nil when a manual interval has no time cap
+}
+
+// Step is one interval as performed in an expanded workout, with the
+// duration it has in that round
+type Step struct {
+	Interval
+	Round int `json:"round"` // 1-indexed
+	Start int `json:"start"` // seconds from the start of the workout
+}
+
+// Progression describes how an interval's duration changes per workout round
+type Progression struct {
+	Type      string  `json:"type"`                // one of the Progression* values
+	Step      int     `json:"step,omitempty"`      // seconds added each round, may be negative
+	Durations []int   `json:"durations,omitempty"` // seconds per round; the last value repeats
+	Percent   float64 `json:"percent,omitempty"`   // percent of Duration added each round, may be negative
+}
+
+// Progression types
+const (
+	ProgressionStep    = "step"
+	ProgressionList    = "list"
+	ProgressionPercent = "percent"
+)
+
+// Interval types
+const (
+	IntervalTypeWork       = "work"
+	IntervalTypeRest       = "rest"
+	IntervalTypeWarmup     = "warmup"
+	IntervalTypeCooldown   = "cooldown"
+	IntervalTypeTransition = "transition"
+)
+
+// IntervalTypes lists the valid interval types
+var IntervalTypes = []string{
+	IntervalTypeWork, IntervalTypeRest, IntervalTypeWarmup, IntervalTypeCooldown, IntervalTypeTransition,
+}
+
+// Block is a group of intervals and sub-blocks performed Repeat times, e.g.
+// 4x(20s on, 10s off)
+type Block struct {
+	ID     string      `json:"id"`
+	Name   string      `json:"name,omitempty"`
+	Repeat int         `json:"repeat"`
+	Items  []BlockItem `json:"items"`
+}
+
+// BlockItem is one step of a block: either an interval or a nested block
+type BlockItem struct {
+	Interval *Interval `json:"interval,omitempty"`
+	Block    *Block    `json:"block,omitempty"`
+}
+
+// WorkoutListOptions filters, sorts and paginates ListWorkouts
+type WorkoutListOptions struct {
+	Query        string    // case-insensitive substring of the name
+	UpdatedAfter time.Time // zero for no bound
+	Sort         string    // name, created_at or updated_at; "-" prefix for descending
+	Limit        int
+	Offset       int
+}
+
+// WorkoutSnapshot is the editable content of a workout at one version
+type WorkoutSnapshot struct {
+	Name      string     `json:"name"`
+	Rounds    int        `json:"rounds"`
+	Intervals []Interval `json:"intervals"`
+	Blocks    []Block    `json:"blocks,omitempty"`
+	Kind      string     `json:"kind,omitempty"`
+	Period    int        `json:"period,omitempty"`
+	TimeCap   int        `json:"time_cap,omitempty"`
+	Pomodoro  *Pomodoro  `json:"pomodoro,omitempty"`
+}
+
+// WorkoutRevision records a workout's content after a change
+type WorkoutRevision struct {
+	WorkoutID string          `json:"workout_id"`
+	UserID    string          `json:"user_id"`
+	Version   int64           `json:"version"` // workout version the snapshot was saved as
+	Device    string          `json:"device"`  // from X-Device-Name, "" if unknown
+	Source    string          `json:"source"`  // sync, api or restore
+	Snapshot  WorkoutSnapshot `json:"snapshot"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// ValueChange is a field that differs between two revisions
+type ValueChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// IntervalChange is an interval present in both revisions with different content
+type IntervalChange struct {
+	ID   string   `json:"id"`
+	From Interval `json:"from"`
+	To   Interval `json:"to"`
+}
+
+// WorkoutDiff describes the changes between two revisions of a workout
+type WorkoutDiff struct {
+	From             int64            `json:"from"`
+	To               int64            `json:"to"`
+	Name             *ValueChange     `json:"name,omitempty"`
+	Rounds           *ValueChange     `json:"rounds,omitempty"`
+	Blocks           *ValueChange     `json:"blocks,omitempty"`
+	Kind             *ValueChange     `json:"kind,omitempty"`
+	Period           *ValueChange     `json:"period,omitempty"`
+	TimeCap          *ValueChange     `json:"time_cap,omitempty"`
+	Pomodoro         *ValueChange     `json:"pomodoro,omitempty"`
+	AddedIntervals   []Interval       `json:"added_intervals"`
+	RemovedIntervals []Interval       `json:"removed_intervals"`
+	ChangedIntervals []IntervalChange `json:"changed_intervals"`
 }
 
 // Completion represents a completed workout
@@ -43,6 +268,134 @@ type Completion struct {
 	CompletedAt     *time.Time `json:"completed_at"`
 	UpdatedAt       time.Time  `json:"updated_at"`
 	DeletedAt       *time.Time `json:"deleted_at,omitempty"`
+
+	// Snapshot is the workout as it was performed, saved once and never
+	// changed. SnapshotHash identifies it among the profile's snapshots.
+	Snapshot     *WorkoutSnapshot `json:"snapshot,omitempty"`
+	SnapshotHash string           `json:"snapshot_hash,omitempty"`
+
+	// IntervalTimings records how long intervals actually took, e.g. manual
+	// ones
+	IntervalTimings []IntervalTiming `json:"interval_timings,omitempty"`
+
+	// Score is the result for AMRAP, For-Time and other scored kinds
+	Score *Score `json:"score,omitempty"`
+
+	// Focus time and interruptions of pomodoro sessions
+	FocusSeconds  int `json:"focus_seconds,omitempty"`
+	Interruptions int `json:"interruptions,omitempty"`
+
+	// Optional task and project the time was spent on
+	ProjectID string `json:"project_id,omitempty"`
+	TaskID    string `json:"task_id,omitempty"`
+}
+
+// Project groups tasks and completions for time reports
+type Project struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"` // profile name hash
+	Name      string    `json:"name"`
+	Color     string    `json:"color"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Task is a piece of work completions can be tagged with, optionally in a
+// project
+type Task struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"` // profile name hash
+	ProjectID string    `json:"project_id"`
+	Name      string    `json:"name"`
+	Done      bool      `json:"done"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Score is the kind-specific result of a completion
+type Score struct {
+	Rounds int  `json:"rounds,omitempty"` // AMRAP: full rounds completed; EMOM: rounds made in time
+	Reps   int  `json:"reps,omitempty"`   // AMRAP: reps into the unfinished round; otherwise total reps
+	Time   int  `json:"time,omitempty"`   // For-Time: seconds to finish
+	Capped bool `json:"capped,omitempty"` // For-Time: the time cap ran out before the finish
+}
+
+// IntervalTiming is the time one interval took in one round of a completion
+type IntervalTiming struct {
+	IntervalID string `json:"interval_id"`
+	Round      int    `json:"round"`    // 1-indexed
+	Duration   int    `json:"duration"` // seconds
+}
+
+// CompletionListOptions filters and paginates ListCompletions
+type CompletionListOptions struct {
+	From      time.Time // StartedAt lower bound (inclusive), zero for none
+	To        time.Time // StartedAt upper bound (exclusive), zero for none
+	WorkoutID string
+	ProjectID string
+	TaskID    string
+	Completed *bool  // nil for both completed and partial
+	Ascending bool   // oldest first; newest first by default
+	Cursor    string // opaque cursor from the previous page
+	Limit     int
+}
+
+// FocusPeriod is the focus time of pomodoro sessions started in one day or week
+type FocusPeriod struct {
+	Start         string `json:"start"` // YYYY-MM-DD, the Monday for weeks
+	FocusSeconds  int    `json:"focus_seconds"`
+	Sessions      int    `json:"sessions"`
+	Interruptions int    `json:"interruptions"`
+}
+
+// FocusStats is the response of the focus statistics endpoint
+type FocusStats struct {
+	Period   string        `json:"period"` // day or week
+	TimeZone string        `json:"time_zone"`
+	Periods  []FocusPeriod `json:"periods"`
+	Total    FocusPeriod   `json:"total"` // Start is the first period's
+}
+
+// TimeReportRow is the time spent on one project in one day or week
+type TimeReportRow struct {
+	Start       string `json:"start"`      // YYYY-MM-DD, the Monday for weeks
+	ProjectID   string `json:"project_id"` // "" for time without a project
+	ProjectName string `json:"project_name"`
+	Seconds     int    `json:"seconds"`
+	Sessions    int    `json:"sessions"`
+}
+
+// TimeReport is the response of the time report endpoint
+type TimeReport struct {
+	Period   string          `json:"period"` // day or week
+	TimeZone string          `json:"time_zone"`
+	Rows     []TimeReportRow `json:"rows"`   // by period, then project name
+	Totals   []TimeReportRow `json:"totals"` // per project over the whole range; Start is empty
+}
+
+// ProfileSummary describes a profile's data for administrators
+type ProfileSummary struct {
+	UserID       string `json:"user_id"`
+	Workouts     int    `json:"workouts"`
+	Completions  int    `json:"completions"`
+	Sessions     int    `json:"sessions"`
+	LastSyncTime int64  `json:"last_sync_time"` // unix millis, 0 if never synced
+}
+
+// StorageUsage reports row counts and database size
+type StorageUsage struct {
+	Tables    map[string]int64 `json:"tables"`
+	SizeBytes int64            `json:"size_bytes"`
+}
+
+// AuditEntry records an administrative action
+type AuditEntry struct {
+	ID         int64     `json:"id"`
+	Action     string    `json:"action"`
+	Target     string    `json:"target,omitempty"`
+	Detail     string    `json:"detail,omitempty"`
+	RemoteAddr string    `json:"remote_addr"`
+	CreatedAt  time.Time `json:"created_at"`
 }
 
 // SyncPayload is the request/response for sync operations
@@ -50,6 +403,15 @@ type SyncPayload struct {
 	LastSyncedAt int64        `json:"last_synced_at"`
 	Workouts     []Workout    `json:"workouts"`
 	Completions  []Completion `json:"completions"`
+
+	// Rejected lists workouts the server did not store, in responses only
+	Rejected []SyncRejection `json:"rejected,omitempty"`
+}
+
+// SyncRejection explains why a synced workout was not stored
+type SyncRejection struct {
+	ID    string `json:"id"`
+	Error string `json:"error"`
 }
 
 // AuthRequest is used to initialize a session
@@ -60,7 +422,26 @@ type AuthRequest struct {
 
 // AuthResponse is returned after successful authentication
 type AuthResponse struct {
-	Token string `json:"token"`
+	Token       string `json:"token"`
+	ProfileName string `json:"profile_name,omitempty"`
+}
+
+// APITokenRequest is used to create a personal access token
+type APITokenRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// APITokenResponse is returned once after creating a personal access token
+type APITokenResponse struct {
+	APIToken
+	Token string `json:"token"` // plaintext token, only shown at creation
+}
+
+// GroupRequest is used to create a tenant group
+type GroupRequest struct {
+	Name     string `json:"name"`
+	Password string `json:"password"` // plaintext, hashed by the server
 }
 
 // ErrorResponse is returned for errors
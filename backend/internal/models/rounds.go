@@ -0,0 +1,194 @@
+package models
+
+import "math"
+
+// DurationForRound returns the interval's duration in seconds in the given
+// 0-indexed workout round, never less than zero. Manual intervals count as 0.
+func (i *Interval) DurationForRound(round int) int {
+	if i.Manual {
+		return 0
+	}
+	p := i.Progression
+	if p == nil {
+		return i.Duration
+	}
+	d := i.Duration
+	switch p.Type {
+	case ProgressionStep:
+		d = i.Duration + p.Step*round
+	case ProgressionList:
+		if len(p.Durations) > 0 {
+			if round >= len(p.Durations) {
+				round = len(p.Durations) - 1
+			}
+			d = p.Durations[round]
+		}
+	case ProgressionPercent:
+		d = int(math.Round(float64(i.Duration) * (1 + p.Percent*float64(round)/100)))
+	}
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// InRound reports whether the interval is performed in the given 0-indexed
+// round of a workout with the given number of rounds
+func (i *Interval) InRound(round, rounds int) bool {
+	c := i.Condition
+	if c == nil {
+		return true
+	}
+	if c.SkipLastRound && round == rounds-1 {
+		return false
+	}
+	if c.SkipEvery > 0 && (round+1)%c.SkipEvery == 0 {
+		return false
+	}
+	from := c.FromRound
+	if from < 1 {
+		from = 1
+	}
+	n := round + 1
+	if n < from {
+		return false
+	}
+	return c.Every <= 1 || (n-from)%c.Every == 0
+}
+
+// durationInRound is the interval's duration in the given 0-indexed round,
+// or 0 if it is skipped
+func (i *Interval) durationInRound(round, rounds int) int {
+	if !i.InRound(round, rounds) {
+		return 0
+	}
+	return i.DurationForRound(round)
+}
+
+// Expand lists every interval performed, round by round, skipping the ones
+// whose condition excludes the round. Block workouts are flattened first.
+// EMOM rounds start on each period; AMRAP workouts list the one round that
+// is repeated until the cap.
+func (w *Workout) Expand() []Step {
+	intervals := w.flatIntervals()
+	rounds := w.Rounds
+	if w.Kind == WorkoutKindAMRAP {
+		rounds = 1
+	}
+	steps := []Step{}
+	start := 0
+	for round := 0; round < rounds; round++ {
+		if w.Kind == WorkoutKindEMOM {
+			start = round * w.RoundPeriod()
+		}
+		for i := range intervals {
+			interval := intervals[i]
+			if !interval.InRound(round, w.Rounds) {
+				continue
+			}
+			interval.Duration = interval.DurationForRound(round)
+			interval.Progression = nil
+			interval.Condition = nil
+			steps = append(steps, Step{Interval: interval, Round: round + 1, Start: start})
+			start += interval.Duration
+		}
+	}
+	return steps
+}
+
+// Bounds returns the range of the workout's length, or nil if it has no
+// manual intervals and its length is exactly Duration. EMOM and AMRAP
+// workouts always last exactly Duration.
+func (w *Workout) Bounds() *DurationRange {
+	if w.Kind == WorkoutKindEMOM || w.Kind == WorkoutKindAMRAP {
+		return nil
+	}
+	var manual []Interval
+	for _, interval := range w.flatIntervals() {
+		if !interval.Manual {
+			continue
+		}
+		manual = append(manual, interval)
+	}
+	if len(manual) == 0 {
+		return nil
+	}
+	bounds := &DurationRange{Min: w.Duration()}
+	varies := w.VariesByRound()
+	caps := 0
+	for round := 0; round < w.Rounds; round++ {
+		for i := range manual {
+			if !manual[i].InRound(round, w.Rounds) {
+				continue
+			}
+			if manual[i].TimeCap == 0 {
+				return w.capBounds(bounds)
+			}
+			caps += manual[i].TimeCap
+		}
+		if !varies {
+			caps *= w.Rounds
+			break
+		}
+	}
+	upper := bounds.Min + caps
+	bounds.Max = &upper
+	return w.capBounds(bounds)
+}
+
+// capBounds limits the upper bound of a For-Time workout to its time cap
+func (w *Workout) capBounds(bounds *DurationRange) *DurationRange {
+	if w.Kind != WorkoutKindForTime || w.TimeCap == 0 {
+		return bounds
+	}
+	if bounds.Max == nil || *bounds.Max > w.TimeCap {
+		upper := w.TimeCap
+		bounds.Max = &upper
+	}
+	return bounds
+}
+
+// flatIntervals returns the intervals of one round, expanding blocks
+func (w *Workout) flatIntervals() []Interval {
+	if len(w.Blocks) > 0 {
+		return FlattenBlocks(w.Blocks)
+	}
+	return w.Intervals
+}
+
+// Pomodoro defaults, in seconds
+const (
+	DefaultPomodoroFocus      = 25 * 60
+	DefaultPomodoroShortBreak = 5 * 60
+	DefaultPomodoroLongBreak  = 15 * 60
+	DefaultPomodoroCycles     = 4
+)
+
+// WithDefaults returns the settings with unset values filled in
+func (p Pomodoro) WithDefaults() Pomodoro {
+	if p.Focus == 0 {
+		p.Focus = DefaultPomodoroFocus
+	}
+	if p.ShortBreak == 0 {
+		p.ShortBreak = DefaultPomodoroShortBreak
+	}
+	if p.LongBreak == 0 {
+		p.LongBreak = DefaultPomodoroLongBreak
+	}
+	if p.LongBreakEvery == 0 {
+		p.LongBreakEvery = DefaultPomodoroCycles
+	}
+	return p
+}
+
+// Intervals builds one pomodoro cycle: focus, then a short break, or a long
+// break every LongBreakEvery cycles. No break follows the last cycle.
+func (p Pomodoro) Intervals() []Interval {
+	return []Interval{
+		{Name: "Focus", Duration: p.Focus, Type: IntervalTypeWork},
+		{Name: "Short break", Duration: p.ShortBreak, Type: IntervalTypeRest,
+			Condition: &Condition{SkipLastRound: true, SkipEvery: p.LongBreakEvery}},
+		{Name: "Long break", Duration: p.LongBreak, Type: IntervalTypeRest,
+			Condition: &Condition{SkipLastRound: true, FromRound: p.LongBreakEvery, Every: p.LongBreakEvery}},
+	}
+}
@@ -0,0 +1,150 @@
+package store
+
+import (
+	"container/list"
+	"intervals-sync/internal/models"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheStats reports session cache effectiveness
+type CacheStats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Size      int    `json:"size"`
+	Capacity  int    `json:"capacity"`
+}
+
+// SessionCache wraps a Store, serving VerifySession from a bounded in-memory
+// LRU so that remote databases are not queried on every request. Entries
+// expire after the TTL and are dropped when this process deletes the session;
+// sessions deleted by another instance stay valid here for at most the TTL.
+type SessionCache struct {
+	Store
+
+	capacity int
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // front is most recently used
+	gen     uint64     // bumped by every invalidation
+	stats   CacheStats
+}
+
+type sessionCacheEntry struct {
+	token     string
+	session   models.Session
+	expiresAt time.Time
+}
+
+// NewSessionCache wraps a store with a session cache holding up to capacity
+// sessions for ttl each
+func NewSessionCache(s Store, capacity int, ttl time.Duration) *SessionCache {
+	return &SessionCache{
+		Store:    s,
+		capacity: capacity,
+		ttl:      ttl,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// VerifySession returns a cached session, falling back to the wrapped store.
+// Invalid tokens are never cached.
+func (c *SessionCache) VerifySession(token string) (*models.Session, error) {
+	c.mu.Lock()
+	if el, ok := c.entries[token]; ok {
+		entry := el.Value.(*sessionCacheEntry)
+		if time.Now().Before(entry.expiresAt) {
+			c.lru.MoveToFront(el)
+			c.stats.Hits++
+			session := entry.session
+			c.mu.Unlock()
+			return &session, nil
+		}
+		c.remove(el)
+	}
+	c.stats.Misses++
+	gen := c.gen
+	c.mu.Unlock()
+
+	session, err := c.Store.VerifySession(token)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// A session deleted while it was being looked up must not be cached
+	if c.gen != gen {
+		return session, nil
+	}
+	if el, ok := c.entries[token]; ok {
+		c.remove(el)
+	}
+	c.entries[token] = c.lru.PushFront(&sessionCacheEntry{
+		token:     token,
+		session:   *session,
+		expiresAt: time.Now().Add(c.ttl),
+	})
+	for c.lru.Len() > c.capacity {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+	return session, nil
+}
+
+// DeleteSession deletes a session and drops it from the cache
+func (c *SessionCache) DeleteSession(token string) error {
+	err := c.Store.DeleteSession(token)
+	c.invalidate(func(e *sessionCacheEntry) bool { return e.token == token })
+	return err
+}
+
+// DeleteUserSessions deletes a profile's sessions and drops them from the cache
+func (c *SessionCache) DeleteUserSessions(userID string) (int64, error) {
+	n, err := c.Store.DeleteUserSessions(userID)
+	c.invalidate(func(e *sessionCacheEntry) bool { return e.session.UserID == userID })
+	return n, err
+}
+
+// DeleteGroup deletes a group and drops its profiles' sessions from the cache
+func (c *SessionCache) DeleteGroup(groupID string) error {
+	err := c.Store.DeleteGroup(groupID)
+	prefix := GroupUserID(groupID, "")
+	c.invalidate(func(e *sessionCacheEntry) bool { return strings.HasPrefix(e.session.UserID, prefix) })
+	return err
+}
+
+// Stats returns hit/miss counters and the current size
+func (c *SessionCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Size = c.lru.Len()
+	stats.Capacity = c.capacity
+	return stats
+}
+
+// invalidate drops every cached session matching a predicate
+func (c *SessionCache) invalidate(match func(*sessionCacheEntry) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for el := c.lru.Front(); el != nil; {
+		next := el.Next()
+		if match(el.Value.(*sessionCacheEntry)) {
+			c.remove(el)
+		}
+		el = next
+	}
+}
+
+// remove drops an entry; c.mu must be held
+func (c *SessionCache) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.entries, el.Value.(*sessionCacheEntry).token)
+}
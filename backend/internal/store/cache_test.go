@@ -0,0 +1,76 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionCache(t *testing.T) {
+	s, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	cache := NewSessionCache(s, 2, time.Minute)
+	for _, token := range []string{"tok-1", "tok-2", "tok-3"} {
+		if _, err := cache.CreateSession(token, "user-123"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := cache.VerifySession("tok-1"); err != nil {
+		t.Fatalf("failed to verify session: %v", err)
+	}
+	session, err := cache.VerifySession("tok-1")
+	if err != nil || session.UserID != "user-123" {
+		t.Fatalf("expected cached session, got %+v %v", session, err)
+	}
+	if stats := cache.Stats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("expected 1 hit and 1 miss, got %+v", stats)
+	}
+
+	// Invalid tokens are not cached
+	if _, err := cache.VerifySession("nope"); err == nil {
+		t.Error("expected invalid token to fail")
+	}
+
+	// The least recently used session is evicted at capacity
+	cache.VerifySession("tok-2")
+	cache.VerifySession("tok-1")
+	cache.VerifySession("tok-3")
+	if stats := cache.Stats(); stats.Size != 2 || stats.Evictions != 1 {
+		t.Errorf("expected size 2 with 1 eviction, got %+v", stats)
+	}
+	if _, ok := cache.entries["tok-2"]; ok {
+		t.Error("expected tok-2 to be evicted")
+	}
+
+	// Deleting a session invalidates it immediately
+	if err := cache.DeleteSession("tok-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.VerifySession("tok-1"); err == nil {
+		t.Error("expected deleted session to be rejected")
+	}
+
+	if _, err := cache.DeleteUserSessions("user-123"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.VerifySession("tok-3"); err == nil {
+		t.Error("expected session to be rejected after DeleteUserSessions")
+	}
+}
+
+func TestSessionCacheExpiry(t *testing.T) {
+	s, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	cache := NewSessionCache(s, 10, time.Millisecond)
+	s.CreateSession("tok-1", "user-123")
+	cache.VerifySession("tok-1")
+
+	// Deleted behind the cache's back, the session lives until the TTL
+	s.DeleteSession("tok-1")
+	time.Sleep(2 * time.Millisecond)
+	if _, err := cache.VerifySession("tok-1"); err == nil {
+		t.Error("expected expired cache entry to be re-verified")
+	}
+}
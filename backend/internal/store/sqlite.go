@@ -2,6 +2,7 @@ package store
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"intervals-sync/internal/models"
@@ -146,10 +147,101 @@ func (s *SQLiteStore) Migrate() error {
 		`CREATE INDEX IF NOT EXISTS idx_completions_user_id ON completions(user_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_completions_updated_at ON completions(updated_at)`,
 		`CREATE INDEX IF NOT EXISTS idx_completions_deleted_at ON completions(deleted_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_completions_user_started_at ON completions(user_id, started_at)`,
+		`CREATE TABLE IF NOT EXISTS workout_revisions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			workout_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			version INTEGER NOT NULL,
+			device TEXT NOT NULL,
+			source TEXT NOT NULL,
+			snapshot TEXT NOT NULL,
+			created_at DATETIME NOT NULL
+		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_workout_revisions_workout ON workout_revisions(user_id, workout_id, version)`,
+		`CREATE TABLE IF NOT EXISTS workout_snapshots (
+			user_id TEXT NOT NULL,
+			hash TEXT NOT NULL,
+			snapshot TEXT NOT NULL,
+			created_at DATETIME NOT NULL,
+			PRIMARY KEY (user_id, hash)
+		)`,
+		`CREATE TABLE IF NOT EXISTS projects (
+			id TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			color TEXT NOT NULL,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_projects_user_id ON projects(user_id)`,
+		`CREATE TABLE IF NOT EXISTS tasks (
+			id TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL,
+			project_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			done BOOLEAN NOT NULL,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_tasks_user_id ON tasks(user_id)`,
 		`CREATE TABLE IF NOT EXISTS sync_metadata (
 			user_id TEXT PRIMARY KEY,
 			last_sync_time INTEGER NOT NULL
 		)`,
+		`CREATE TABLE IF NOT EXISTS api_tokens (
+			id TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			token_hash TEXT NOT NULL UNIQUE,
+			scopes TEXT NOT NULL,
+			created_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_api_tokens_user_id ON api_tokens(user_id)`,
+		`CREATE TABLE IF NOT EXISTS external_identities (
+			issuer TEXT NOT NULL,
+			subject TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			created_at DATETIME NOT NULL,
+			PRIMARY KEY (issuer, subject)
+		)`,
+		`CREATE TABLE IF NOT EXISTS pairing_codes (
+			code_hash TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL,
+			expires_at INTEGER NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS tenant_groups (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			password_hash TEXT NOT NULL UNIQUE,
+			created_at DATETIME NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS server_passwords (
+			password_hash TEXT PRIMARY KEY,
+			expires_at INTEGER,
+			created_at DATETIME NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS login_failures (
+			lockout_key TEXT PRIMARY KEY,
+			failures INTEGER NOT NULL,
+			last_failure_at INTEGER NOT NULL,
+			locked_until INTEGER NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS session_revocations (
+			id TEXT PRIMARY KEY,
+			token_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			revoked_at INTEGER NOT NULL,
+			expires_at INTEGER NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS admin_audit (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			action TEXT NOT NULL,
+			target TEXT NOT NULL,
+			detail TEXT NOT NULL,
+			remote_addr TEXT NOT NULL,
+			created_at DATETIME NOT NULL
+		)`,
 	}
 
 	for _, stmt := range statements {
@@ -157,7 +249,12 @@ func (s *SQLiteStore) Migrate() error {
 			return err
 		}
 	}
-	return nil
+
+	// Columns added after the first release
+	if err := migrateColumns(s.db); err != nil {
+		return err
+	}
+	return normalizeStartedAt(s.db, sqliteStartedAtLayout)
 }
 
 // Close closes the database connection
@@ -209,14 +306,644 @@ func (s *SQLiteStore) DeleteSession(token string) error {
 	return err
 }
 
-// UpsertWorkout inserts or updates a workout
-func (s *SQLiteStore) UpsertWorkout(workout *models.Workout) error {
+// CreateAPIToken stores a new personal API token
+func (s *SQLiteStore) CreateAPIToken(token *models.APIToken) error {
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO api_tokens (id, user_id, name, token_hash, scopes, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, token.ID, token.UserID, token.Name, token.TokenHash,
+		strings.Join(token.Scopes, ","), token.CreatedAt)
+	return err
+}
+
+// GetAPIToken looks up a personal API token by its hash
+func (s *SQLiteStore) GetAPIToken(tokenHash string) (*models.APIToken, error) {
+	var t models.APIToken
+	var scopes, createdAtStr string
+	err := s.db.QueryRow(`
+		SELECT id, user_id, name, token_hash, scopes, created_at
+		FROM api_tokens
+		WHERE token_hash = ?
+	`, tokenHash).Scan(&t.ID, &t.UserID, &t.Name, &t.TokenHash, &scopes, &createdAtStr)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	t.Scopes = splitScopes(scopes)
+	t.CreatedAt, _ = parseTime(createdAtStr)
+	return &t, nil
+}
+
+// ListAPITokens returns all personal API tokens for a user
+func (s *SQLiteStore) ListAPITokens(userID string) ([]models.APIToken, error) {
+	rows, err := s.db.Query(`
+		SELECT id, user_id, name, token_hash, scopes, created_at
+		FROM api_tokens
+		WHERE user_id = ?
+		ORDER BY created_at ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []models.APIToken{}
+	for rows.Next() {
+		var t models.APIToken
+		var scopes, createdAtStr string
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Name, &t.TokenHash, &scopes, &createdAtStr); err != nil {
+			return nil, err
+		}
+		t.Scopes = splitScopes(scopes)
+		t.CreatedAt, _ = parseTime(createdAtStr)
+		tokens = append(tokens, t)
+	}
+
+	return tokens, rows.Err()
+}
+
+// DeleteAPIToken revokes a personal API token
+func (s *SQLiteStore) DeleteAPIToken(userID string, tokenID string) error {
+	res, err := s.db.Exec("DELETE FROM api_tokens WHERE id = ? AND user_id = ?", tokenID, userID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// GetIdentityProfile returns the profile linked to an external identity
+func (s *SQLiteStore) GetIdentityProfile(issuer string, subject string) (string, error) {
+	var userID string
+	err := s.db.QueryRow(
+		"SELECT user_id FROM external_identities WHERE issuer = ? AND subject = ?",
+		issuer, subject,
+	).Scan(&userID)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+
+	return userID, nil
+}
+
+// LinkIdentity links an external identity to a profile
+func (s *SQLiteStore) LinkIdentity(issuer string, subject string, userID string) error {
+	_, err := s.db.Exec(`
+		INSERT INTO external_identities (issuer, subject, user_id, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(issuer, subject) DO UPDATE SET
+			user_id = excluded.user_id
+	`, issuer, subject, userID, time.Now())
+	return err
+}
+
+// ProfileInUse reports whether any data, session or identity belongs to a
+// profile
+func (s *SQLiteStore) ProfileInUse(userID string) (bool, error) {
+	for _, table := range groupTables {
+		var found int
+		err := s.db.QueryRow("SELECT 1 FROM "+table+" WHERE user_id = ? LIMIT 1", userID).Scan(&found)
+		if err == nil {
+			return true, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// CreatePairingCode stores a short-lived pairing code, pruning expired ones
+func (s *SQLiteStore) CreatePairingCode(codeHash string, userID string, expiresAt time.Time) error {
+	if _, err := s.db.Exec("DELETE FROM pairing_codes WHERE expires_at <= ?", time.Now().UnixMilli()); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(
+		"INSERT INTO pairing_codes (code_hash, user_id, expires_at) VALUES (?, ?, ?)",
+		codeHash, userID, expiresAt.UnixMilli(),
+	)
+	return err
+}
+
+// RedeemPairingCode consumes a pairing code and returns its profile
+func (s *SQLiteStore) RedeemPairingCode(codeHash string) (string, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var userID string
+	var expiresAt int64
+	err = tx.QueryRow(
+		"SELECT user_id, expires_at FROM pairing_codes WHERE code_hash = ?",
+		codeHash,
+	).Scan(&userID, &expiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+
+	if _, err := tx.Exec("DELETE FROM pairing_codes WHERE code_hash = ?", codeHash); err != nil {
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+
+	if time.Now().UnixMilli() >= expiresAt {
+		return "", ErrNotFound
+	}
+	return userID, nil
+}
+
+// RecordPairingFailure counts a failed redemption against every live
+// pairing code and burns those that reached maxFailures. A wrong guess could
+// have targeted any of them.
+func (s *SQLiteStore) RecordPairingFailure(maxFailures int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UnixMilli()
+	if _, err := tx.Exec("UPDATE pairing_codes SET failures = failures + 1 WHERE expires_at > ?", now); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM pairing_codes WHERE failures >= ? OR expires_at <= ?", maxFailures, now); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CreateGroup creates a tenant group
+func (s *SQLiteStore) CreateGroup(group *models.Group) error {
+	if group.CreatedAt.IsZero() {
+		group.CreatedAt = time.Now()
+	}
+	_, err := s.db.Exec(
+		"INSERT INTO tenant_groups (id, name, password_hash, created_at) VALUES (?, ?, ?, ?)",
+		group.ID, group.Name, group.PasswordHash, group.CreatedAt,
+	)
+	return err
+}
+
+// ListGroups returns all tenant groups
+func (s *SQLiteStore) ListGroups() ([]models.Group, error) {
+	rows, err := s.db.Query("SELECT id, name, password_hash, created_at FROM tenant_groups ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []models.Group{}
+	for rows.Next() {
+		var g models.Group
+		var createdAtStr string
+		if err := rows.Scan(&g.ID, &g.Name, &g.PasswordHash, &createdAtStr); err != nil {
+			return nil, err
+		}
+		g.CreatedAt, _ = parseTime(createdAtStr)
+		groups = append(groups, g)
+	}
+
+	return groups, rows.Err()
+}
+
+// GetGroupByPasswordHash finds the group a password belongs to
+func (s *SQLiteStore) GetGroupByPasswordHash(passwordHash string) (*models.Group, error) {
+	var g models.Group
+	var createdAtStr string
+	err := s.db.QueryRow(
+		"SELECT id, name, password_hash, created_at FROM tenant_groups WHERE password_hash = ?",
+		passwordHash,
+	).Scan(&g.ID, &g.Name, &g.PasswordHash, &createdAtStr)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	g.CreatedAt, _ = parseTime(createdAtStr)
+	return &g, nil
+}
+
+// DeleteGroup removes a tenant group together with all of its profiles' data
+func (s *SQLiteStore) DeleteGroup(groupID string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("DELETE FROM tenant_groups WHERE id = ?", groupID)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(res); err != nil {
+		return err
+	}
+
+	prefix := GroupUserID(groupID, "")
+	_, err = tx.Exec(`
+		DELETE FROM workout_intervals WHERE workout_id IN (
+			SELECT id FROM workouts WHERE substr(user_id, 1, ?) = ?
+		)
+	`, len(prefix), prefix)
+	if err != nil {
+		return err
+	}
+	for _, table := range groupTables {
+		_, err := tx.Exec("DELETE FROM "+table+" WHERE substr(user_id, 1, ?) = ?", len(prefix), prefix)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ListServerPasswords returns passwords managed through the admin API,
+// including expired ones
+func (s *SQLiteStore) ListServerPasswords() ([]models.ServerPassword, error) {
+	rows, err := s.db.Query(`
+		SELECT password_hash, expires_at, created_at
+		FROM server_passwords
+		ORDER BY created_at ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	passwords := []models.ServerPassword{}
+	for rows.Next() {
+		var p models.ServerPassword
+		var expiresAt sql.NullInt64
+		var createdAtStr string
+		if err := rows.Scan(&p.PasswordHash, &expiresAt, &createdAtStr); err != nil {
+			return nil, err
+		}
+		if expiresAt.Valid {
+			t := time.UnixMilli(expiresAt.Int64)
+			p.ExpiresAt = &t
+		}
+		p.CreatedAt, _ = parseTime(createdAtStr)
+		passwords = append(passwords, p)
+	}
+
+	return passwords, rows.Err()
+}
+
+// PutServerPassword adds a server password or updates its expiry
+func (s *SQLiteStore) PutServerPassword(password *models.ServerPassword) error {
+	if password.CreatedAt.IsZero() {
+		password.CreatedAt = time.Now()
+	}
+	var expiresAt *int64
+	if password.ExpiresAt != nil {
+		ms := password.ExpiresAt.UnixMilli()
+		expiresAt = &ms
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO server_passwords (password_hash, expires_at, created_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(password_hash) DO UPDATE SET
+			expires_at = excluded.expires_at
+	`, password.PasswordHash, expiresAt, password.CreatedAt)
+	return err
+}
+
+// GetLoginFailure returns the failed login record for a key
+func (s *SQLiteStore) GetLoginFailure(key string) (*models.LoginFailure, error) {
+	f := models.LoginFailure{Key: key}
+	var lastFailureAt, lockedUntil int64
+	err := s.db.QueryRow(
+		"SELECT failures, last_failure_at, locked_until FROM login_failures WHERE lockout_key = ?",
+		key,
+	).Scan(&f.Failures, &lastFailureAt, &lockedUntil)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	f.LastFailureAt = time.UnixMilli(lastFailureAt)
+	f.LockedUntil = time.UnixMilli(lockedUntil)
+	return &f, nil
+}
+
+// RecordLoginFailure atomically counts a failed attempt. Counting starts over
+// if the previous failure was before resetBefore.
+func (s *SQLiteStore) RecordLoginFailure(key string, resetBefore time.Time) (*models.LoginFailure, error) {
+	_, err := s.db.Exec(`
+		INSERT INTO login_failures (lockout_key, failures, last_failure_at, locked_until)
+		VALUES (?, 1, ?, 0)
+		ON CONFLICT(lockout_key) DO UPDATE SET
+			failures = CASE WHEN last_failure_at < ? THEN 1 ELSE failures + 1 END,
+			last_failure_at = excluded.last_failure_at
+	`, key, time.Now().UnixMilli(), resetBefore.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	return s.GetLoginFailure(key)
+}
+
+// LockLogin rejects logins for a key until the given time
+func (s *SQLiteStore) LockLogin(key string, until time.Time) error {
+	_, err := s.db.Exec("UPDATE login_failures SET locked_until = ? WHERE lockout_key = ?", until.UnixMilli(), key)
+	return err
+}
+
+// ClearLoginFailure forgets failed attempts for a key after a successful login
+func (s *SQLiteStore) ClearLoginFailure(key string) error {
+	_, err := s.db.Exec("DELETE FROM login_failures WHERE lockout_key = ?", key)
+	return err
+}
+
+// RevokeSessions records a revocation of signed session tokens, pruning
+// revocations whose tokens have expired anyway
+func (s *SQLiteStore) RevokeSessions(revocation *models.SessionRevocation) error {
+	if revocation.RevokedAt.IsZero() {
+		revocation.RevokedAt = time.Now()
+	}
+	if _, err := s.db.Exec("DELETE FROM session_revocations WHERE expires_at <= ?", time.Now().UnixMilli()); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO session_revocations (id, token_id, user_id, revoked_at, expires_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			revoked_at = excluded.revoked_at,
+			expires_at = MAX(expires_at, excluded.expires_at)
+	`, revocationID(revocation), revocation.TokenID, revocation.UserID,
+		revocation.RevokedAt.UnixMilli(), revocation.ExpiresAt.UnixMilli())
+	return err
+}
+
+// ListSessionRevocations returns revocations that still cover unexpired tokens
+func (s *SQLiteStore) ListSessionRevocations() ([]models.SessionRevocation, error) {
+	rows, err := s.db.Query(`
+		SELECT token_id, user_id, revoked_at, expires_at
+		FROM session_revocations
+		WHERE expires_at > ?
+	`, time.Now().UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revocations := []models.SessionRevocation{}
+	for rows.Next() {
+		var r models.SessionRevocation
+		var revokedAt, expiresAt int64
+		if err := rows.Scan(&r.TokenID, &r.UserID, &revokedAt, &expiresAt); err != nil {
+			return nil, err
+		}
+		r.RevokedAt = time.UnixMilli(revokedAt)
+		r.ExpiresAt = time.UnixMilli(expiresAt)
+		revocations = append(revocations, r)
+	}
+
+	return revocations, rows.Err()
+}
+
+// ListProfileSummaries returns per-profile counts and last sync times
+func (s *SQLiteStore) ListProfileSummaries() ([]models.ProfileSummary, error) {
+	rows, err := s.db.Query(`
+		SELECT p.user_id,
+			(SELECT COUNT(*) FROM workouts w WHERE w.user_id = p.user_id AND w.deleted_at IS NULL),
+			(SELECT COUNT(*) FROM completions c WHERE c.user_id = p.user_id AND c.deleted_at IS NULL),
+			(SELECT COUNT(*) FROM sessions s WHERE s.user_id = p.user_id),
+			COALESCE((SELECT last_sync_time FROM sync_metadata m WHERE m.user_id = p.user_id), 0)
+		FROM (
+			SELECT user_id FROM workouts
+			UNION
+			SELECT user_id FROM completions
+			UNION
+			SELECT user_id FROM sync_metadata
+			UNION
+			SELECT user_id FROM sessions
+		) p
+		ORDER BY p.user_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := []models.ProfileSummary{}
+	for rows.Next() {
+		var p models.ProfileSummary
+		if err := rows.Scan(&p.UserID, &p.Workouts, &p.Completions, &p.Sessions, &p.LastSyncTime); err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, p)
+	}
+
+	return summaries, rows.Err()
+}
+
+// ListSessions returns sessions for a user, or all sessions if userID is empty
+func (s *SQLiteStore) ListSessions(userID string) ([]models.Session, error) {
+	rows, err := s.db.Query(`
+		SELECT token, user_id, created_at
+		FROM sessions
+		WHERE ? = '' OR user_id = ?
+		ORDER BY created_at DESC
+	`, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		var session models.Session
+		var createdAtStr string
+		if err := rows.Scan(&session.Token, &session.UserID, &createdAtStr); err != nil {
+			return nil, err
+		}
+		session.CreatedAt, _ = parseTime(createdAtStr)
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// DeleteUserSessions deletes all sessions of a user
+func (s *SQLiteStore) DeleteUserSessions(userID string) (int64, error) {
+	res, err := s.db.Exec("DELETE FROM sessions WHERE user_id = ?", userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// GetStorageUsage returns row counts per table and the database size
+func (s *SQLiteStore) GetStorageUsage() (*models.StorageUsage, error) {
+	usage := &models.StorageUsage{Tables: make(map[string]int64)}
+	for _, table := range storageTables {
+		var count int64
+		if err := s.db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&count); err != nil {
+			return nil, err
+		}
+		usage.Tables[table] = count
+	}
+
+	// Size is best-effort; not every backend exposes page pragmas
+	s.db.QueryRow(
+		"SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()",
+	).Scan(&usage.SizeBytes)
+
+	return usage, nil
+}
+
+// PurgeDeleted permanently removes workouts and completions soft-deleted before a time
+func (s *SQLiteStore) PurgeDeleted(before time.Time) (int64, int64, error) {
 	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		DELETE FROM workout_intervals WHERE workout_id IN (
+			SELECT id FROM workouts WHERE deleted_at IS NOT NULL AND deleted_at < ?
+		)
+	`, before)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM workout_revisions WHERE workout_id IN (
+			SELECT id FROM workouts WHERE deleted_at IS NOT NULL AND deleted_at < ?
+		)
+	`, before)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	res, err := tx.Exec("DELETE FROM workouts WHERE deleted_at IS NOT NULL AND deleted_at < ?", before)
+	if err != nil {
+		return 0, 0, err
+	}
+	workouts, _ := res.RowsAffected()
+
+	res, err = tx.Exec("DELETE FROM completions WHERE deleted_at IS NOT NULL AND deleted_at < ?", before)
+	if err != nil {
+		return 0, 0, err
+	}
+	completions, _ := res.RowsAffected()
+
+	// Drop workout snapshots no longer referenced by any completion
+	_, err = tx.Exec(`
+		DELETE FROM workout_snapshots WHERE NOT EXISTS (
+			SELECT 1 FROM completions
+			WHERE completions.user_id = workout_snapshots.user_id
+			AND completions.snapshot_hash = workout_snapshots.hash
+		)
+	`)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return workouts, completions, tx.Commit()
+}
+
+// Backup writes a consistent copy of the database to path
+func (s *SQLiteStore) Backup(path string) error {
+	_, err := s.db.Exec("VACUUM INTO ?", path)
+	return err
+}
+
+// AddAuditEntry records an administrative action
+func (s *SQLiteStore) AddAuditEntry(entry *models.AuditEntry) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	res, err := s.db.Exec(`
+		INSERT INTO admin_audit (action, target, detail, remote_addr, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, entry.Action, entry.Target, entry.Detail, entry.RemoteAddr, entry.CreatedAt)
 	if err != nil {
 		return err
 	}
+	entry.ID, _ = res.LastInsertId()
+	return nil
+}
+
+// ListAuditEntries returns the most recent administrative actions
+func (s *SQLiteStore) ListAuditEntries(limit int) ([]models.AuditEntry, error) {
+	rows, err := s.db.Query(`
+		SELECT id, action, target, detail, remote_addr, created_at
+		FROM admin_audit
+		ORDER BY id DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var e models.AuditEntry
+		var createdAtStr string
+		if err := rows.Scan(&e.ID, &e.Action, &e.Target, &e.Detail, &e.RemoteAddr, &createdAtStr); err != nil {
+			return nil, err
+		}
+		e.CreatedAt, _ = parseTime(createdAtStr)
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// UpsertWorkout inserts or updates a workout and reports whether it
+// changed. The version is only bumped when the content or deleted state
+// differs from what is stored.
+func (s *SQLiteStore) UpsertWorkout(workout *models.Workout) (bool, error) {
+	blocks, err := encodeBlocks(workout.Blocks)
+	if err != nil {
+		return false, err
+	}
+	pomodoro, err := encodePomodoro(workout.Pomodoro)
+	if err != nil {
+		return false, err
+	}
+	contentHash, err := workoutContentHash(workout)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
 	defer tx.Rollback()
 
+	previous, err := workoutVersion(tx, workout.UserID, workout.ID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return false, err
+	}
+
 	// Ensure timestamps are set
 	now := time.Now()
 	createdAt := workout.CreatedAt
@@ -229,135 +956,400 @@ func (s *SQLiteStore) UpsertWorkout(workout *models.Workout) error {
 	}
 
 	// Upsert workout with soft delete support
-	_, err = tx.Exec(`
-		INSERT INTO workouts (id, user_id, name, rounds, created_at, updated_at, deleted_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+	res, err := tx.Exec(`
+		INSERT INTO workouts (id, user_id, name, rounds, blocks, kind, period, time_cap, pomodoro, content_hash, created_at, updated_at, deleted_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(id) DO UPDATE SET
 			name = excluded.name,
 			rounds = excluded.rounds,
+			blocks = excluded.blocks,
+			kind = excluded.kind,
+			period = excluded.period,
+			time_cap = excluded.time_cap,
+			pomodoro = excluded.pomodoro,
 			updated_at = excluded.updated_at,
-			deleted_at = excluded.deleted_at
-	`, workout.ID, workout.UserID, workout.Name, workout.Rounds,
+			content_hash = excluded.content_hash,
+			deleted_at = excluded.deleted_at,
+			version = CASE
+				WHEN workouts.content_hash IS excluded.content_hash
+					AND (workouts.deleted_at IS NULL) = (excluded.deleted_at IS NULL)
+				THEN workouts.version
+				ELSE workouts.version + 1
+			END
+		WHERE workouts.user_id = excluded.user_id
+	`, workout.ID, workout.UserID, workout.Name, workout.Rounds, blocks, workout.Kind, workout.Period, workout.TimeCap, pomodoro, contentHash,
 		createdAt, updatedAt, workout.DeletedAt)
 	if err != nil {
+		return false, err
+	}
+	if err := requireAffected(res); err != nil {
+		return false, err
+	}
+
+	if err := replaceIntervals(tx, workout.UserID, workout.ID, storedIntervals(workout)); err != nil {
+		return false, err
+	}
+	if workout.Version, err = workoutVersion(tx, workout.UserID, workout.ID); err != nil {
+		return false, err
+	}
+	finishWorkout(workout)
+
+	return workout.Version != previous, tx.Commit()
+}
+
+// UpdateWorkout replaces the fields and intervals of a live workout. A
+// non-zero ifVersion must match the stored version, or ErrVersionConflict is
+// returned and nothing is written.
+func (s *SQLiteStore) UpdateWorkout(workout *models.Workout, ifVersion int64) error {
+	blocks, err := encodeBlocks(workout.Blocks)
+	if err != nil {
+		return err
+	}
+	pomodoro, err := encodePomodoro(workout.Pomodoro)
+	if err != nil {
+		return err
+	}
+	contentHash, err := workoutContentHash(workout)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+		UPDATE workouts
+		SET name = ?, rounds = ?, blocks = ?, kind = ?, period = ?, time_cap = ?, pomodoro = ?, content_hash = ?, updated_at = ?, version = version + 1
+		WHERE id = ? AND user_id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)
+	`, workout.Name, workout.Rounds, blocks, workout.Kind, workout.Period, workout.TimeCap, pomodoro, contentHash, workout.UpdatedAt, workout.ID, workout.UserID, ifVersion, ifVersion)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(res); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return workoutWriteError(tx, workout.UserID, workout.ID)
+		}
+		return err
+	}
+
+	if err := replaceIntervals(tx, workout.UserID, workout.ID, storedIntervals(workout)); err != nil {
+		return err
+	}
+	if workout.Version, err = workoutVersion(tx, workout.UserID, workout.ID); err != nil {
+		return err
+	}
+	finishWorkout(workout)
+
+	return tx.Commit()
+}
+
+// GetWorkoutsModifiedSince returns workouts modified after a timestamp (including soft-deleted)
+func (s *SQLiteStore) GetWorkoutsModifiedSince(userID string, since int64) ([]models.Workout, error) {
+	sinceTime := time.UnixMilli(since)
+	rows, err := s.db.Query(`
+		SELECT id, user_id, name, rounds, version, blocks, kind, period, time_cap, pomodoro, created_at, updated_at, deleted_at
+		FROM workouts
+		WHERE user_id = ? AND updated_at > ?
+		ORDER BY updated_at DESC
+	`, userID, sinceTime)
+	if err != nil {
+		return nil, err
+	}
+
+	// First, collect all workouts without intervals
+	var workouts []models.Workout
+	for rows.Next() {
+		var w models.Workout
+		var createdAtStr, updatedAtStr string
+		var blocks, pomodoro sql.NullString
+		var deletedAtStr sql.NullString
+		err := rows.Scan(&w.ID, &w.UserID, &w.Name, &w.Rounds, &w.Version, &blocks, &w.Kind, &w.Period, &w.TimeCap, &pomodoro, &createdAtStr, &updatedAtStr, &deletedAtStr)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		// Parse timestamp strings
+		w.CreatedAt, _ = parseTime(createdAtStr)
+		w.UpdatedAt, _ = parseTime(updatedAtStr)
+		w.Blocks, _ = decodeBlocks(blocks)
+		w.Pomodoro, _ = decodePomodoro(pomodoro)
+		w.DeletedAt, _ = parseNullTime(deletedAtStr)
+		workouts = append(workouts, w)
+	}
+	rows.Close()
+
+	// Now load intervals for each workout (after closing the first result set)
+	for i := range workouts {
+		intervals, err := s.getIntervals(workouts[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		workouts[i].Intervals = intervals
+		finishWorkout(&workouts[i])
+	}
+
+	return workouts, nil
+}
+
+// GetWorkout returns a single workout by ID (excludes soft-deleted)
+func (s *SQLiteStore) GetWorkout(userID string, workoutID string) (*models.Workout, error) {
+	var w models.Workout
+	var createdAtStr, updatedAtStr string
+	var blocks, pomodoro sql.NullString
+	var deletedAtStr sql.NullString
+	err := s.db.QueryRow(`
+		SELECT id, user_id, name, rounds, version, blocks, kind, period, time_cap, pomodoro, created_at, updated_at, deleted_at
+		FROM workouts
+		WHERE id = ? AND user_id = ? AND deleted_at IS NULL
+	`, workoutID, userID).Scan(&w.ID, &w.UserID, &w.Name, &w.Rounds, &w.Version, &blocks, &w.Kind, &w.Period, &w.TimeCap, &pomodoro, &createdAtStr, &updatedAtStr, &deletedAtStr)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("workout not found")
+		}
+		return nil, err
+	}
+
+	// Parse timestamp strings
+	w.CreatedAt, _ = parseTime(createdAtStr)
+	w.UpdatedAt, _ = parseTime(updatedAtStr)
+	w.Blocks, _ = decodeBlocks(blocks)
+	w.Pomodoro, _ = decodePomodoro(pomodoro)
+	w.DeletedAt, _ = parseNullTime(deletedAtStr)
+
+	intervals, err := s.getIntervals(w.ID)
+	if err != nil {
+		return nil, err
+	}
+	w.Intervals = intervals
+	finishWorkout(&w)
+
+	return &w, nil
+}
+
+// ListWorkouts returns a page of live workouts and the total number matching
+func (s *SQLiteStore) ListWorkouts(userID string, opts models.WorkoutListOptions) ([]models.Workout, int, error) {
+	where := "user_id = ? AND deleted_at IS NULL"
+	args := []interface{}{userID}
+	if opts.Query != "" {
+		where += " AND name LIKE ? ESCAPE '\\'"
+		args = append(args, likePattern(opts.Query))
+	}
+	if !opts.UpdatedAfter.IsZero() {
+		where += " AND updated_at > ?"
+		args = append(args, opts.UpdatedAfter)
+	}
+
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM workouts WHERE "+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT id, user_id, name, rounds, version, blocks, kind, period, time_cap, pomodoro, created_at, updated_at
+		FROM workouts
+		WHERE `+where+`
+		ORDER BY `+workoutOrderBy(opts.Sort)+`
+		LIMIT ? OFFSET ?
+	`, append(args, opts.Limit, opts.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	workouts := []models.Workout{}
+	for rows.Next() {
+		var w models.Workout
+		var createdAtStr, updatedAtStr string
+		var blocks, pomodoro sql.NullString
+		if err := rows.Scan(&w.ID, &w.UserID, &w.Name, &w.Rounds, &w.Version, &blocks, &w.Kind, &w.Period, &w.TimeCap, &pomodoro, &createdAtStr, &updatedAtStr); err != nil {
+			rows.Close()
+			return nil, 0, err
+		}
+		w.CreatedAt, _ = parseTime(createdAtStr)
+		w.UpdatedAt, _ = parseTime(updatedAtStr)
+		w.Blocks, _ = decodeBlocks(blocks)
+		w.Pomodoro, _ = decodePomodoro(pomodoro)
+		workouts = append(workouts, w)
+	}
+	rows.Close()
+
+	// Load intervals after closing the first result set
+	for i := range workouts {
+		intervals, err := s.getIntervals(workouts[i].ID)
+		if err != nil {
+			return nil, 0, err
+		}
+		workouts[i].Intervals = intervals
+		finishWorkout(&workouts[i])
+	}
+
+	return workouts, total, nil
+}
+
+// DeleteWorkout soft-deletes a workout. A non-zero ifVersion must match the
+// stored version, or ErrVersionConflict is returned and nothing is deleted.
+func (s *SQLiteStore) DeleteWorkout(userID string, workoutID string, ifVersion int64) error {
+	now := time.Now()
+	if ifVersion == 0 {
+		_, err := s.db.Exec(`
+			UPDATE workouts
+			SET deleted_at = ?, updated_at = ?, version = version + 1
+			WHERE id = ? AND user_id = ?
+		`, now, now, workoutID, userID)
 		return err
 	}
 
-	// Delete existing intervals
-	_, err = tx.Exec("DELETE FROM workout_intervals WHERE workout_id = ?", workout.ID)
+	tx, err := s.db.Begin()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	// Insert new intervals with position
-	for i, interval := range workout.Intervals {
-		position := interval.Position
-		if position == 0 && i > 0 {
-			position = i // Use index as position if not set
-		}
-		_, err = tx.Exec(`
-			INSERT INTO workout_intervals (id, workout_id, name, duration, color, position)
-			VALUES (?, ?, ?, ?, ?, ?)
-		`, interval.ID, workout.ID, interval.Name, interval.Duration, interval.Color, position)
-		if err != nil {
-			return err
+	res, err := tx.Exec(`
+		UPDATE workouts
+		SET deleted_at = ?, updated_at = ?, version = version + 1
+		WHERE id = ? AND user_id = ? AND deleted_at IS NULL AND version = ?
+	`, now, now, workoutID, userID, ifVersion)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(res); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return workoutWriteError(tx, userID, workoutID)
 		}
+		return err
 	}
-
 	return tx.Commit()
 }
 
-// GetWorkoutsModifiedSince returns workouts modified after a timestamp (including soft-deleted)
-func (s *SQLiteStore) GetWorkoutsModifiedSince(userID string, since int64) ([]models.Workout, error) {
-	sinceTime := time.UnixMilli(since)
+// ListDeletedWorkouts returns a profile's soft-deleted workouts that have not
+// been purged yet, most recently deleted first
+func (s *SQLiteStore) ListDeletedWorkouts(userID string) ([]models.Workout, error) {
 	rows, err := s.db.Query(`
-		SELECT id, user_id, name, rounds, created_at, updated_at, deleted_at
+		SELECT id, user_id, name, rounds, version, blocks, kind, period, time_cap, pomodoro, created_at, updated_at, deleted_at
 		FROM workouts
-		WHERE user_id = ? AND updated_at > ?
-		ORDER BY updated_at DESC
-	`, userID, sinceTime)
+		WHERE user_id = ? AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id
+	`, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	// First, collect all workouts without intervals
-	var workouts []models.Workout
+	workouts := []models.Workout{}
 	for rows.Next() {
 		var w models.Workout
 		var createdAtStr, updatedAtStr string
+		var blocks, pomodoro sql.NullString
 		var deletedAtStr sql.NullString
-		err := rows.Scan(&w.ID, &w.UserID, &w.Name, &w.Rounds, &createdAtStr, &updatedAtStr, &deletedAtStr)
-		if err != nil {
+		if err := rows.Scan(&w.ID, &w.UserID, &w.Name, &w.Rounds, &w.Version, &blocks, &w.Kind, &w.Period, &w.TimeCap, &pomodoro, &createdAtStr, &updatedAtStr, &deletedAtStr); err != nil {
 			rows.Close()
 			return nil, err
 		}
-		// Parse timestamp strings
 		w.CreatedAt, _ = parseTime(createdAtStr)
 		w.UpdatedAt, _ = parseTime(updatedAtStr)
+		w.Blocks, _ = decodeBlocks(blocks)
+		w.Pomodoro, _ = decodePomodoro(pomodoro)
 		w.DeletedAt, _ = parseNullTime(deletedAtStr)
 		workouts = append(workouts, w)
 	}
 	rows.Close()
 
-	// Now load intervals for each workout (after closing the first result set)
+	// Load intervals after closing the first result set
 	for i := range workouts {
 		intervals, err := s.getIntervals(workouts[i].ID)
 		if err != nil {
 			return nil, err
 		}
 		workouts[i].Intervals = intervals
+		finishWorkout(&workouts[i])
 	}
 
 	return workouts, nil
 }
 
-// GetWorkout returns a single workout by ID (excludes soft-deleted)
-func (s *SQLiteStore) GetWorkout(userID string, workoutID string) (*models.Workout, error) {
-	var w models.Workout
-	var createdAtStr, updatedAtStr string
-	var deletedAtStr sql.NullString
-	err := s.db.QueryRow(`
-		SELECT id, user_id, name, rounds, created_at, updated_at, deleted_at
-		FROM workouts
-		WHERE id = ? AND user_id = ? AND deleted_at IS NULL
-	`, workoutID, userID).Scan(&w.ID, &w.UserID, &w.Name, &w.Rounds, &createdAtStr, &updatedAtStr, &deletedAtStr)
+// RestoreWorkout undeletes a soft-deleted workout. updated_at is bumped so the
+// restore reaches other devices on their next sync.
+func (s *SQLiteStore) RestoreWorkout(userID string, workoutID string) error {
+	res, err := s.db.Exec(`
+		UPDATE workouts
+		SET deleted_at = NULL, updated_at = ?, version = version + 1
+		WHERE id = ? AND user_id = ? AND deleted_at IS NOT NULL
+	`, time.Now(), workoutID, userID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// AddWorkoutRevision records a workout's content after a change
+func (s *SQLiteStore) AddWorkoutRevision(rev *models.WorkoutRevision) error {
+	if rev.CreatedAt.IsZero() {
+		rev.CreatedAt = time.Now()
+	}
+	return addWorkoutRevision(s.db, rev, rev.CreatedAt)
+}
 
+// ListWorkoutRevisions returns a workout's revisions, newest first
+func (s *SQLiteStore) ListWorkoutRevisions(userID string, workoutID string) ([]models.WorkoutRevision, error) {
+	rows, err := s.db.Query(`
+		SELECT `+workoutRevisionColumns+`
+		FROM workout_revisions
+		WHERE user_id = ? AND workout_id = ?
+		ORDER BY version DESC
+	`, userID, workoutID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("workout not found")
-		}
 		return nil, err
 	}
+	defer rows.Close()
 
-	// Parse timestamp strings
-	w.CreatedAt, _ = parseTime(createdAtStr)
-	w.UpdatedAt, _ = parseTime(updatedAtStr)
-	w.DeletedAt, _ = parseNullTime(deletedAtStr)
+	revisions := []models.WorkoutRevision{}
+	for rows.Next() {
+		rev, err := scanWorkoutRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+	return revisions, rows.Err()
+}
 
-	intervals, err := s.getIntervals(w.ID)
+// GetWorkoutRevision returns the revision saved as a workout version
+func (s *SQLiteStore) GetWorkoutRevision(userID string, workoutID string, version int64) (*models.WorkoutRevision, error) {
+	row := s.db.QueryRow(`
+		SELECT `+workoutRevisionColumns+`
+		FROM workout_revisions
+		WHERE user_id = ? AND workout_id = ? AND version = ?
+	`, userID, workoutID, version)
+	rev, err := scanWorkoutRevision(row)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
 		return nil, err
 	}
-	w.Intervals = intervals
-
-	return &w, nil
+	return &rev, nil
 }
 
-// DeleteWorkout soft-deletes a workout
-func (s *SQLiteStore) DeleteWorkout(userID string, workoutID string) error {
-	now := time.Now()
-	_, err := s.db.Exec(`
-		UPDATE workouts
-		SET deleted_at = ?, updated_at = ?
-		WHERE id = ? AND user_id = ?
-	`, now, now, workoutID, userID)
-	return err
+func scanWorkoutRevision(row rowScanner) (models.WorkoutRevision, error) {
+	var rev models.WorkoutRevision
+	var snapshot, createdAtStr string
+	err := row.Scan(&rev.WorkoutID, &rev.UserID, &rev.Version, &rev.Device, &rev.Source, &snapshot, &createdAtStr)
+	if err != nil {
+		return rev, err
+	}
+	rev.CreatedAt, _ = parseTime(createdAtStr)
+	return rev, json.Unmarshal([]byte(snapshot), &rev.Snapshot)
 }
 
 // getIntervals helper to load intervals for a workout
 func (s *SQLiteStore) getIntervals(workoutID string) ([]models.Interval, error) {
 	rows, err := s.db.Query(`
-		SELECT id, name, duration, color, position
+		SELECT id, name, duration, color, position,
+			type, notes, target_reps, target_hr_zone, exercise_ref, progression, condition,
+			manual, time_cap
 		FROM workout_intervals
 		WHERE workout_id = ?
 		ORDER BY position ASC
@@ -370,17 +1362,27 @@ func (s *SQLiteStore) getIntervals(workoutID string) ([]models.Interval, error)
 	var intervals []models.Interval
 	for rows.Next() {
 		var i models.Interval
-		err := rows.Scan(&i.ID, &i.Name, &i.Duration, &i.Color, &i.Position)
+		var progression, condition sql.NullString
+		err := rows.Scan(&i.ID, &i.Name, &i.Duration, &i.Color, &i.Position,
+			&i.Type, &i.Notes, &i.TargetReps, &i.TargetHRZone, &i.ExerciseRef, &progression, &condition,
+			&i.Manual, &i.TimeCap)
 		if err != nil {
 			return nil, err
 		}
+		if i.Progression, err = decodeProgression(progression); err != nil {
+			return nil, err
+		}
+		if i.Condition, err = decodeCondition(condition); err != nil {
+			return nil, err
+		}
 		intervals = append(intervals, i)
 	}
 
 	return intervals, rows.Err()
 }
 
-// UpsertCompletion inserts or updates a completion record
+// UpsertCompletion inserts or updates a completion. A snapshot is stored the
+// first time one is given and kept unchanged afterwards.
 func (s *SQLiteStore) UpsertCompletion(completion *models.Completion) error {
 	// Ensure timestamps are set
 	now := time.Now()
@@ -393,28 +1395,67 @@ func (s *SQLiteStore) UpsertCompletion(completion *models.Completion) error {
 		updatedAt = now
 	}
 
-	_, err := s.db.Exec(`
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var snapshotHash sql.NullString
+	if completion.Snapshot != nil {
+		hash, err := saveWorkoutSnapshot(tx, completion.UserID, completion.Snapshot, now)
+		if err != nil {
+			return err
+		}
+		snapshotHash = sql.NullString{String: hash, Valid: true}
+	}
+
+	timings, err := encodeTimings(completion.IntervalTimings)
+	if err != nil {
+		return err
+	}
+	score, err := encodeScore(completion.Score)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(`
 		INSERT INTO completions
-		(id, user_id, workout_id, workout_name, total_duration, elapsed_duration, completed, started_at, completed_at, updated_at, deleted_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		(id, user_id, workout_id, workout_name, total_duration, elapsed_duration, completed, started_at, completed_at, updated_at, deleted_at, snapshot_hash, interval_timings, score, focus_seconds, interruptions, project_id, task_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(id) DO UPDATE SET
 			elapsed_duration = excluded.elapsed_duration,
 			completed = excluded.completed,
 			completed_at = excluded.completed_at,
 			updated_at = excluded.updated_at,
-			deleted_at = excluded.deleted_at
+			deleted_at = excluded.deleted_at,
+			snapshot_hash = COALESCE(completions.snapshot_hash, excluded.snapshot_hash),
+			interval_timings = COALESCE(excluded.interval_timings, completions.interval_timings),
+			score = COALESCE(excluded.score, completions.score),
+			focus_seconds = excluded.focus_seconds,
+			interruptions = excluded.interruptions,
+			project_id = COALESCE(NULLIF(excluded.project_id, ''), completions.project_id),
+			task_id = COALESCE(NULLIF(excluded.task_id, ''), completions.task_id)
+		WHERE completions.user_id = excluded.user_id
 	`, completion.ID, completion.UserID, completion.WorkoutID, completion.WorkoutName,
 		completion.TotalDuration, completion.ElapsedDuration, completion.Completed,
-		startedAt, completion.CompletedAt, updatedAt, completion.DeletedAt)
-	return err
+		startedAt.UTC().Format(sqliteStartedAtLayout), completion.CompletedAt, updatedAt, completion.DeletedAt, snapshotHash, timings, score,
+		completion.FocusSeconds, completion.Interruptions, completion.ProjectID, completion.TaskID)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(res); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 // GetCompletionsModifiedSince returns completions modified after a timestamp (including soft-deleted)
 func (s *SQLiteStore) GetCompletionsModifiedSince(userID string, since int64) ([]models.Completion, error) {
 	sinceTime := time.UnixMilli(since)
 	rows, err := s.db.Query(`
-		SELECT id, user_id, workout_id, workout_name, total_duration, elapsed_duration,
-		       completed, started_at, completed_at, updated_at, deleted_at
+		SELECT `+completionColumns+`
 		FROM completions
 		WHERE user_id = ? AND updated_at > ?
 		ORDER BY updated_at DESC
@@ -426,26 +1467,99 @@ func (s *SQLiteStore) GetCompletionsModifiedSince(userID string, since int64) ([
 
 	var completions []models.Completion
 	for rows.Next() {
-		var c models.Completion
-		var startedAtStr, updatedAtStr string
-		var completedAtStr, deletedAtStr sql.NullString
-		err := rows.Scan(&c.ID, &c.UserID, &c.WorkoutID, &c.WorkoutName,
-			&c.TotalDuration, &c.ElapsedDuration, &c.Completed,
-			&startedAtStr, &completedAtStr, &updatedAtStr, &deletedAtStr)
+		c, _, err := scanCompletion(rows)
 		if err != nil {
 			return nil, err
 		}
-		// Parse timestamp strings
-		c.StartedAt, _ = parseTime(startedAtStr)
-		c.UpdatedAt, _ = parseTime(updatedAtStr)
-		c.CompletedAt, _ = parseNullTime(completedAtStr)
-		c.DeletedAt, _ = parseNullTime(deletedAtStr)
 		completions = append(completions, c)
 	}
 
 	return completions, rows.Err()
 }
 
+// GetCompletion returns a single completion by ID (excludes soft-deleted)
+func (s *SQLiteStore) GetCompletion(userID string, completionID string) (*models.Completion, error) {
+	row := s.db.QueryRow(`
+		SELECT `+completionColumns+`
+		FROM completions
+		WHERE id = ? AND user_id = ? AND deleted_at IS NULL
+	`, completionID, userID)
+	c, _, err := scanCompletion(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+// ListCompletions returns a page of live completions ordered by start time,
+// with a cursor for the next page ("" on the last page)
+func (s *SQLiteStore) ListCompletions(userID string, opts models.CompletionListOptions) ([]models.Completion, string, error) {
+	where, args, err := completionFilter(userID, opts, func(t time.Time) interface{} { return t.UTC().Format(sqliteStartedAtLayout) })
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Fetch one extra row to learn whether there is another page
+	rows, err := s.db.Query(`
+		SELECT `+completionColumns+`
+		FROM completions
+		WHERE `+where+`
+		ORDER BY `+completionOrderBy(opts.Ascending)+`
+		LIMIT ?
+	`, append(args, opts.Limit+1)...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	completions := []models.Completion{}
+	var cursor, lastStartedAt string
+	for rows.Next() {
+		c, startedAt, err := scanCompletion(rows)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(completions) == opts.Limit {
+			last := completions[len(completions)-1]
+			cursor = encodeCursor(lastStartedAt, last.ID)
+			break
+		}
+		completions = append(completions, c)
+		lastStartedAt = startedAt
+	}
+
+	return completions, cursor, rows.Err()
+}
+
+// scanCompletion reads a row selected with completionColumns, also returning
+// the raw started_at value for pagination cursors
+func scanCompletion(row rowScanner) (models.Completion, string, error) {
+	var c models.Completion
+	var startedAtStr, updatedAtStr string
+	var completedAtStr, deletedAtStr, snapshotHash, timings, score, snapshot sql.NullString
+	err := row.Scan(&c.ID, &c.UserID, &c.WorkoutID, &c.WorkoutName,
+		&c.TotalDuration, &c.ElapsedDuration, &c.Completed,
+		&startedAtStr, &completedAtStr, &updatedAtStr, &deletedAtStr, &snapshotHash, &timings, &score,
+		&c.FocusSeconds, &c.Interruptions, &c.ProjectID, &c.TaskID, &snapshot)
+	if err != nil {
+		return c, "", err
+	}
+	if c.IntervalTimings, err = decodeTimings(timings); err != nil {
+		return c, "", err
+	}
+	if c.Score, err = decodeScore(score); err != nil {
+		return c, "", err
+	}
+	c.StartedAt, _ = parseTime(startedAtStr)
+	c.UpdatedAt, _ = parseTime(updatedAtStr)
+	c.CompletedAt, _ = parseNullTime(completedAtStr)
+	c.DeletedAt, _ = parseNullTime(deletedAtStr)
+	return c, startedAtStr, decodeSnapshot(&c, snapshotHash, snapshot)
+}
+
 // DeleteCompletion soft-deletes a completion record
 func (s *SQLiteStore) DeleteCompletion(userID string, completionID string) error {
 	now := time.Now()
@@ -457,6 +1571,239 @@ func (s *SQLiteStore) DeleteCompletion(userID string, completionID string) error
 	return err
 }
 
+// ListDeletedCompletions returns a profile's soft-deleted completions that
+// have not been purged yet, most recently deleted first
+func (s *SQLiteStore) ListDeletedCompletions(userID string) ([]models.Completion, error) {
+	rows, err := s.db.Query(`
+		SELECT `+completionColumns+`
+		FROM completions
+		WHERE user_id = ? AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	completions := []models.Completion{}
+	for rows.Next() {
+		c, _, err := scanCompletion(rows)
+		if err != nil {
+			return nil, err
+		}
+		completions = append(completions, c)
+	}
+	return completions, rows.Err()
+}
+
+// RestoreCompletion undeletes a soft-deleted completion. updated_at is bumped
+// so the restore reaches other devices on their next sync.
+func (s *SQLiteStore) RestoreCompletion(userID string, completionID string) error {
+	res, err := s.db.Exec(`
+		UPDATE completions
+		SET deleted_at = NULL, updated_at = ?
+		WHERE id = ? AND user_id = ? AND deleted_at IS NOT NULL
+	`, time.Now(), completionID, userID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// TagCompletion sets the project and task of a completion; empty values
+// clear them, which syncing a completion without tags never does
+func (s *SQLiteStore) TagCompletion(userID string, completionID string, projectID string, taskID string, updatedAt time.Time) error {
+	res, err := s.db.Exec(`
+		UPDATE completions
+		SET project_id = ?, task_id = ?, updated_at = ?
+		WHERE id = ? AND user_id = ? AND deleted_at IS NULL
+	`, projectID, taskID, updatedAt, completionID, userID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// CreateProject stores a new project
+func (s *SQLiteStore) CreateProject(project *models.Project) error {
+	_, err := s.db.Exec(`
+		INSERT INTO projects (id, user_id, name, color, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, project.ID, project.UserID, project.Name, project.Color, project.CreatedAt, project.UpdatedAt)
+	return err
+}
+
+// UpdateProject saves a project's name and color
+func (s *SQLiteStore) UpdateProject(project *models.Project) error {
+	res, err := s.db.Exec(`
+		UPDATE projects SET name = ?, color = ?, updated_at = ?
+		WHERE id = ? AND user_id = ?
+	`, project.Name, project.Color, project.UpdatedAt, project.ID, project.UserID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// GetProject returns a single project
+func (s *SQLiteStore) GetProject(userID string, projectID string) (*models.Project, error) {
+	p, err := scanProject(s.db.QueryRow(`
+		SELECT id, user_id, name, color, created_at, updated_at
+		FROM projects
+		WHERE id = ? AND user_id = ?
+	`, projectID, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ListProjects returns a profile's projects by name
+func (s *SQLiteStore) ListProjects(userID string) ([]models.Project, error) {
+	rows, err := s.db.Query(`
+		SELECT id, user_id, name, color, created_at, updated_at
+		FROM projects
+		WHERE user_id = ?
+		ORDER BY name COLLATE NOCASE, id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	projects := []models.Project{}
+	for rows.Next() {
+		p, err := scanProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, p)
+	}
+	return projects, rows.Err()
+}
+
+// DeleteProject removes a project. Its tasks are kept without a project;
+// completions keep referring to it.
+func (s *SQLiteStore) DeleteProject(userID string, projectID string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("DELETE FROM projects WHERE id = ? AND user_id = ?", projectID, userID)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(res); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		UPDATE tasks SET project_id = '', updated_at = ?
+		WHERE project_id = ? AND user_id = ?
+	`, time.Now(), projectID, userID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func scanProject(row rowScanner) (models.Project, error) {
+	var p models.Project
+	var createdAtStr, updatedAtStr string
+	if err := row.Scan(&p.ID, &p.UserID, &p.Name, &p.Color, &createdAtStr, &updatedAtStr); err != nil {
+		return p, err
+	}
+	p.CreatedAt, _ = parseTime(createdAtStr)
+	p.UpdatedAt, _ = parseTime(updatedAtStr)
+	return p, nil
+}
+
+// CreateTask stores a new task
+func (s *SQLiteStore) CreateTask(task *models.Task) error {
+	_, err := s.db.Exec(`
+		INSERT INTO tasks (id, user_id, project_id, name, done, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, task.ID, task.UserID, task.ProjectID, task.Name, task.Done, task.CreatedAt, task.UpdatedAt)
+	return err
+}
+
+// UpdateTask saves a task's project, name and state
+func (s *SQLiteStore) UpdateTask(task *models.Task) error {
+	res, err := s.db.Exec(`
+		UPDATE tasks SET project_id = ?, name = ?, done = ?, updated_at = ?
+		WHERE id = ? AND user_id = ?
+	`, task.ProjectID, task.Name, task.Done, task.UpdatedAt, task.ID, task.UserID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// GetTask returns a single task
+func (s *SQLiteStore) GetTask(userID string, taskID string) (*models.Task, error) {
+	t, err := scanTask(s.db.QueryRow(`
+		SELECT id, user_id, project_id, name, done, created_at, updated_at
+		FROM tasks
+		WHERE id = ? AND user_id = ?
+	`, taskID, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+// ListTasks returns a profile's tasks, oldest first, optionally only those
+// of one project
+func (s *SQLiteStore) ListTasks(userID string, projectID string) ([]models.Task, error) {
+	rows, err := s.db.Query(`
+		SELECT id, user_id, project_id, name, done, created_at, updated_at
+		FROM tasks
+		WHERE user_id = ? AND (? = '' OR project_id = ?)
+		ORDER BY created_at, id
+	`, userID, projectID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := []models.Task{}
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
+
+// DeleteTask removes a task; completions keep referring to it
+func (s *SQLiteStore) DeleteTask(userID string, taskID string) error {
+	res, err := s.db.Exec("DELETE FROM tasks WHERE id = ? AND user_id = ?", taskID, userID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+func scanTask(row rowScanner) (models.Task, error) {
+	var t models.Task
+	var createdAtStr, updatedAtStr string
+	if err := row.Scan(&t.ID, &t.UserID, &t.ProjectID, &t.Name, &t.Done, &createdAtStr, &updatedAtStr); err != nil {
+		return t, err
+	}
+	t.CreatedAt, _ = parseTime(createdAtStr)
+	t.UpdatedAt, _ = parseTime(updatedAtStr)
+	return t, nil
+}
+
 // GetLastSyncTime returns the last sync timestamp for a user
 func (s *SQLiteStore) GetLastSyncTime(userID string) (int64, error) {
 	var syncTime int64
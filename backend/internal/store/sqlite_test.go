@@ -1,6 +1,8 @@
 package store
 
 import (
+	"database/sql"
+	"errors"
 	"intervals-sync/internal/models"
 	"testing"
 	"time"
@@ -93,7 +95,7 @@ func TestWorkoutCRUD(t *testing.T) {
 	}
 
 	// Upsert (create)
-	err := store.UpsertWorkout(workout)
+	_, err := store.UpsertWorkout(workout)
 	if err != nil {
 		t.Fatalf("failed to upsert workout: %v", err)
 	}
@@ -116,7 +118,7 @@ func TestWorkoutCRUD(t *testing.T) {
 	// Update workout
 	workout.Name = "Updated Workout"
 	workout.UpdatedAt = time.Now()
-	err = store.UpsertWorkout(workout)
+	_, err = store.UpsertWorkout(workout)
 	if err != nil {
 		t.Fatalf("failed to update workout: %v", err)
 	}
@@ -127,7 +129,7 @@ func TestWorkoutCRUD(t *testing.T) {
 	}
 
 	// Soft delete
-	err = store.DeleteWorkout("user-123", "workout-1")
+	err = store.DeleteWorkout("user-123", "workout-1", 0)
 	if err != nil {
 		t.Fatalf("failed to delete workout: %v", err)
 	}
@@ -139,6 +141,163 @@ func TestWorkoutCRUD(t *testing.T) {
 	}
 }
 
+func TestWorkoutVersions(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	workout := &models.Workout{ID: "w1", UserID: "user-123", Name: "Tabata", Rounds: 8}
+	if _, err := store.UpsertWorkout(workout); err != nil {
+		t.Fatal(err)
+	}
+	if workout.Version != 1 {
+		t.Fatalf("expected version 1 after insert, got %d", workout.Version)
+	}
+
+	workout.Name = "Tabata x2"
+	if err := store.UpdateWorkout(workout, 1); err != nil || workout.Version != 2 {
+		t.Fatalf("expected conditional update to version 2, got %d %v", workout.Version, err)
+	}
+	if err := store.UpdateWorkout(workout, 1); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("expected ErrVersionConflict for a stale version, got %v", err)
+	}
+	if err := store.DeleteWorkout("user-123", "w1", 1); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("expected ErrVersionConflict deleting a stale version, got %v", err)
+	}
+	if err := store.DeleteWorkout("user-123", "w1", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.UpdateWorkout(workout, 0); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound updating a deleted workout, got %v", err)
+	}
+}
+
+func TestUpsertWorkoutVersionOnlyOnChange(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	workout := &models.Workout{
+		ID: "w1", UserID: "user-123", Name: "Tabata", Rounds: 8,
+		Intervals: []models.Interval{{ID: "i1", Name: "Work", Duration: 20, Color: "#ff0000"}},
+	}
+	if changed, err := store.UpsertWorkout(workout); err != nil || !changed {
+		t.Fatalf("expected the insert to count as a change, got %v %v", changed, err)
+	}
+
+	// Syncing the same content again keeps the version
+	workout.UpdatedAt = time.Now().Add(time.Minute)
+	if changed, err := store.UpsertWorkout(workout); err != nil || changed || workout.Version != 1 {
+		t.Errorf("expected an unchanged upsert to keep version 1, got %v %d %v", changed, workout.Version, err)
+	}
+
+	// A changed interval bumps it
+	workout.Intervals[0].Duration = 30
+	if changed, err := store.UpsertWorkout(workout); err != nil || !changed || workout.Version != 2 {
+		t.Errorf("expected a changed interval to bump to version 2, got %v %d %v", changed, workout.Version, err)
+	}
+
+	// So does deleting it
+	deletedAt := time.Now()
+	workout.DeletedAt = &deletedAt
+	if changed, err := store.UpsertWorkout(workout); err != nil || !changed || workout.Version != 3 {
+		t.Errorf("expected a deletion to bump to version 3, got %v %d %v", changed, workout.Version, err)
+	}
+}
+
+func TestWorkoutRevisionPruning(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	for v := int64(1); v <= maxWorkoutRevisions+5; v++ {
+		rev := &models.WorkoutRevision{
+			WorkoutID: "w1",
+			UserID:    "user-123",
+			Version:   v,
+			Source:    "api",
+			Snapshot:  models.WorkoutSnapshot{Name: "Tabata", Rounds: int(v), Intervals: []models.Interval{}},
+		}
+		if err := store.AddWorkoutRevision(rev); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Unchanged content is not recorded again
+	store.AddWorkoutRevision(&models.WorkoutRevision{
+		WorkoutID: "w1", UserID: "user-123", Version: maxWorkoutRevisions + 6,
+		Snapshot: models.WorkoutSnapshot{Name: "Tabata", Rounds: maxWorkoutRevisions + 5, Intervals: []models.Interval{}},
+	})
+
+	revisions, err := store.ListWorkoutRevisions("user-123", "w1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != maxWorkoutRevisions || revisions[0].Version != maxWorkoutRevisions+5 || revisions[len(revisions)-1].Version != 6 {
+		t.Errorf("expected the newest %d revisions, got %d from %d to %d", maxWorkoutRevisions,
+			len(revisions), revisions[0].Version, revisions[len(revisions)-1].Version)
+	}
+	if _, err := store.GetWorkoutRevision("user-123", "w1", 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected pruned revision to be gone, got %v", err)
+	}
+}
+
+func TestMigrateAddsColumns(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	// Recreate the original workouts table, without a version column
+	store.db.Exec("DROP TABLE workouts")
+	_, err := store.db.Exec(`CREATE TABLE workouts (
+		id TEXT PRIMARY KEY, user_id TEXT NOT NULL, name TEXT NOT NULL, rounds INTEGER NOT NULL,
+		created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL, deleted_at DATETIME
+	)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.db.Exec("INSERT INTO workouts VALUES ('w1', 'user-123', 'Old', 1, ?, ?, NULL)", time.Now(), time.Now())
+
+	// Migrating twice must not fail on the existing column
+	for i := 0; i < 2; i++ {
+		if err := store.Migrate(); err != nil {
+			t.Fatalf("migration %d: %v", i+1, err)
+		}
+	}
+	workout, err := store.GetWorkout("user-123", "w1")
+	if err != nil || workout.Version != 1 {
+		t.Errorf("expected existing workout at version 1, got %+v %v", workout, err)
+	}
+}
+
+func TestWorkoutBlocks(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	now := time.Now()
+	workout := &models.Workout{
+		ID: "w1", UserID: "user-123", Name: "Blocks", Rounds: 1, CreatedAt: now, UpdatedAt: now,
+		Blocks: []models.Block{{ID: "b1", Repeat: 2, Items: []models.BlockItem{
+			{Interval: &models.Interval{ID: "i1", Name: "Work", Duration: 20}},
+			{Block: &models.Block{ID: "b2", Repeat: 3, Items: []models.BlockItem{
+				{Interval: &models.Interval{ID: "i2", Name: "Rest", Duration: 10}},
+			}}},
+		}}},
+	}
+	if _, err := store.UpsertWorkout(workout); err != nil {
+		t.Fatal(err)
+	}
+
+	// Sync reads return the blocks and the flattened intervals
+	workouts, err := store.GetWorkoutsModifiedSince("user-123", 0)
+	if err != nil || len(workouts) != 1 {
+		t.Fatalf("expected 1 workout, got %d %v", len(workouts), err)
+	}
+	got := workouts[0]
+	if len(got.Blocks) != 1 || got.Blocks[0].Items[1].Block.Repeat != 3 {
+		t.Errorf("blocks not round-tripped: %+v", got.Blocks)
+	}
+	if len(got.Intervals) != 8 || got.Intervals[3].ID != "i2#3" || got.TotalDuration != 2*(20+3*10) {
+		t.Errorf("unexpected flattened workout: %+v", got)
+	}
+}
+
 func TestGetWorkoutsModifiedSince(t *testing.T) {
 	store, cleanup := setupTestStore(t)
 	defer cleanup()
@@ -235,6 +394,101 @@ func TestCompletionCRUD(t *testing.T) {
 	}
 }
 
+func TestCompletionStartedAtUTC(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	berlin := time.FixedZone("CEST", 2*60*60)
+	for _, c := range []models.Completion{
+		{ID: "c1", StartedAt: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)},
+		{ID: "c2", StartedAt: time.Date(2026, 3, 2, 10, 0, 0, 0, berlin)}, // 08:00Z
+		{ID: "c3", StartedAt: time.Date(2026, 3, 2, 9, 0, 0, 500, time.UTC)},
+	} {
+		c.UserID = "user-123"
+		c.WorkoutID = "w1"
+		if err := store.UpsertCompletion(&c); err != nil {
+			t.Fatalf("failed to save completion: %v", err)
+		}
+	}
+
+	list := func(opts models.CompletionListOptions) []string {
+		t.Helper()
+		opts.Ascending = true
+		opts.Limit = 10
+		completions, _, err := store.ListCompletions("user-123", opts)
+		if err != nil {
+			t.Fatalf("failed to list completions: %v", err)
+		}
+		var ids []string
+		for _, c := range completions {
+			ids = append(ids, c.ID)
+		}
+		return ids
+	}
+	if ids := list(models.CompletionListOptions{}); len(ids) != 3 || ids[0] != "c2" || ids[1] != "c1" || ids[2] != "c3" {
+		t.Errorf("expected c2, c1, c3 in time order, got %v", ids)
+	}
+	from := time.Date(2026, 3, 2, 8, 30, 0, 0, time.UTC)
+	if ids := list(models.CompletionListOptions{From: from}); len(ids) != 2 || ids[0] != "c1" {
+		t.Errorf("expected c1 and c3 from 08:30Z, got %v", ids)
+	}
+
+	// Rows saved with the client's offset are normalized on migration
+	store.db.Exec("UPDATE completions SET started_at = '2026-03-02T10:00:00+02:00' WHERE id = 'c2'")
+	if err := store.Migrate(); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	if ids := list(models.CompletionListOptions{From: from}); len(ids) != 2 || ids[0] != "c1" {
+		t.Errorf("expected migrated c2 before 08:30Z, got %v", ids)
+	}
+}
+
+func TestCompletionSnapshots(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	snapshot := func(rounds int) *models.WorkoutSnapshot {
+		return &models.WorkoutSnapshot{Name: "Tabata", Rounds: rounds, Intervals: []models.Interval{{ID: "i1", Name: "Work", Duration: 20}}}
+	}
+	for _, id := range []string{"c1", "c2"} {
+		c := &models.Completion{ID: id, UserID: "user-123", WorkoutID: "w1", StartedAt: time.Now(), Snapshot: snapshot(8)}
+		if err := store.UpsertCompletion(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Identical snapshots are stored once
+	var count int
+	store.db.QueryRow("SELECT COUNT(*) FROM workout_snapshots").Scan(&count)
+	if count != 1 {
+		t.Errorf("expected 1 deduplicated snapshot, got %d", count)
+	}
+
+	// A later upsert cannot replace the snapshot
+	store.UpsertCompletion(&models.Completion{ID: "c1", UserID: "user-123", WorkoutID: "w1", StartedAt: time.Now(), Completed: true, Snapshot: snapshot(3)})
+	c, err := store.GetCompletion("user-123", "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Completed || c.Snapshot == nil || c.Snapshot.Rounds != 8 || len(c.Snapshot.Intervals) != 1 || c.SnapshotHash == "" {
+		t.Errorf("expected the original snapshot to be kept, got %+v %+v", c, c.Snapshot)
+	}
+
+	changed, _ := store.GetCompletionsModifiedSince("user-123", 0)
+	if len(changed) != 2 || changed[0].Snapshot == nil || changed[1].Snapshot == nil {
+		t.Errorf("expected snapshots in sync results, got %+v", changed)
+	}
+
+	// Purging the completions drops snapshots nothing refers to any more
+	store.DeleteCompletion("user-123", "c1")
+	store.DeleteCompletion("user-123", "c2")
+	store.PurgeDeleted(time.Now().Add(time.Second))
+	store.db.QueryRow("SELECT COUNT(*) FROM workout_snapshots").Scan(&count)
+	if count != 0 {
+		t.Errorf("expected orphaned snapshots to be purged, got %d", count)
+	}
+}
+
 func TestSyncMetadata(t *testing.T) {
 	store, cleanup := setupTestStore(t)
 	defer cleanup()
@@ -264,3 +518,367 @@ func TestSyncMetadata(t *testing.T) {
 		t.Errorf("expected %d, got %d", now, syncTime)
 	}
 }
+
+func TestAPITokens(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	token := &models.APIToken{
+		ID:        "tok-1",
+		UserID:    "user-123",
+		Name:      "script",
+		TokenHash: "hash-1",
+		Scopes:    []string{"workouts:read", "completions:read"},
+	}
+	if err := store.CreateAPIToken(token); err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+
+	found, err := store.GetAPIToken("hash-1")
+	if err != nil {
+		t.Fatalf("failed to get token: %v", err)
+	}
+	if found.UserID != "user-123" || len(found.Scopes) != 2 {
+		t.Errorf("unexpected token: %+v", found)
+	}
+
+	tokens, err := store.ListAPITokens("user-123")
+	if err != nil {
+		t.Fatalf("failed to list tokens: %v", err)
+	}
+	if len(tokens) != 1 {
+		t.Fatalf("expected 1 token, got %d", len(tokens))
+	}
+
+	// Other users cannot revoke the token
+	if err := store.DeleteAPIToken("user-456", "tok-1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := store.DeleteAPIToken("user-123", "tok-1"); err != nil {
+		t.Fatalf("failed to delete token: %v", err)
+	}
+	if _, err := store.GetAPIToken("hash-1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+func TestServerPasswords(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	if err := store.PutServerPassword(&models.ServerPassword{PasswordHash: "hash-1"}); err != nil {
+		t.Fatalf("failed to add password: %v", err)
+	}
+
+	// Putting an existing password updates its expiry
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	if err := store.PutServerPassword(&models.ServerPassword{PasswordHash: "hash-1", ExpiresAt: &expiresAt}); err != nil {
+		t.Fatalf("failed to update password: %v", err)
+	}
+
+	passwords, err := store.ListServerPasswords()
+	if err != nil {
+		t.Fatalf("failed to list passwords: %v", err)
+	}
+	if len(passwords) != 1 {
+		t.Fatalf("expected 1 password, got %d", len(passwords))
+	}
+	if passwords[0].ExpiresAt == nil || !passwords[0].ExpiresAt.Equal(expiresAt) {
+		t.Errorf("expected expiry %v, got %v", expiresAt, passwords[0].ExpiresAt)
+	}
+}
+
+func TestSessionRevocations(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	now := time.Now()
+	revocations := []*models.SessionRevocation{
+		{TokenID: "jti-1", ExpiresAt: now.Add(time.Hour)},
+		{UserID: "alice", ExpiresAt: now.Add(time.Hour)},
+		{TokenID: "jti-old", ExpiresAt: now.Add(-time.Hour)},
+	}
+	for _, r := range revocations {
+		if err := store.RevokeSessions(r); err != nil {
+			t.Fatalf("failed to revoke: %v", err)
+		}
+	}
+
+	// Revoking a profile again moves its cutoff forward
+	later := now.Add(time.Minute)
+	if err := store.RevokeSessions(&models.SessionRevocation{UserID: "alice", RevokedAt: later, ExpiresAt: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("failed to revoke: %v", err)
+	}
+
+	listed, err := store.ListSessionRevocations()
+	if err != nil {
+		t.Fatalf("failed to list revocations: %v", err)
+	}
+	if len(listed) != 2 {
+		t.Fatalf("expected 2 unexpired revocations, got %d", len(listed))
+	}
+	for _, r := range listed {
+		if r.UserID == "alice" && r.RevokedAt.UnixMilli() != later.UnixMilli() {
+			t.Errorf("expected alice's cutoff to move to %v, got %v", later, r.RevokedAt)
+		}
+	}
+}
+
+func TestLoginFailures(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	if _, err := store.GetLoginFailure("ip:1.2.3.4"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		f, err := store.RecordLoginFailure("ip:1.2.3.4", time.Now().Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("failed to record failure: %v", err)
+		}
+		if f.Failures != i {
+			t.Errorf("expected %d failures, got %d", i, f.Failures)
+		}
+	}
+
+	until := time.Now().Add(time.Minute).Truncate(time.Millisecond)
+	if err := store.LockLogin("ip:1.2.3.4", until); err != nil {
+		t.Fatal(err)
+	}
+	f, _ := store.GetLoginFailure("ip:1.2.3.4")
+	if !f.LockedUntil.Equal(until) {
+		t.Errorf("expected lock until %v, got %v", until, f.LockedUntil)
+	}
+
+	// Failures older than the window start a new count
+	f, _ = store.RecordLoginFailure("ip:1.2.3.4", time.Now().Add(time.Second))
+	if f.Failures != 1 {
+		t.Errorf("expected count to restart, got %d", f.Failures)
+	}
+
+	if err := store.ClearLoginFailure("ip:1.2.3.4"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GetLoginFailure("ip:1.2.3.4"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after clear, got %v", err)
+	}
+}
+
+func TestPairingCodes(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	if err := store.CreatePairingCode("code-1", "user-123", time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("failed to create pairing code: %v", err)
+	}
+	if err := store.CreatePairingCode("code-2", "user-123", time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("failed to create pairing code: %v", err)
+	}
+
+	userID, err := store.RedeemPairingCode("code-1")
+	if err != nil {
+		t.Fatalf("failed to redeem pairing code: %v", err)
+	}
+	if userID != "user-123" {
+		t.Errorf("expected user ID 'user-123', got '%s'", userID)
+	}
+
+	// Single use
+	if _, err := store.RedeemPairingCode("code-1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound on reuse, got %v", err)
+	}
+
+	// Expired codes cannot be redeemed
+	if _, err := store.RedeemPairingCode("code-2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for expired code, got %v", err)
+	}
+}
+
+func TestGroupUserID(t *testing.T) {
+	userID := GroupUserID("g1", "alice:smith")
+	group, profile := SplitGroupUserID(userID)
+	if group != "g1" || profile != "alice:smith" {
+		t.Errorf("expected (g1, alice:smith), got (%s, %s)", group, profile)
+	}
+
+	group, profile = SplitGroupUserID(GroupUserID("", "alice"))
+	if group != "" || profile != "alice" {
+		t.Errorf("expected default group profile 'alice', got (%s, %s)", group, profile)
+	}
+}
+
+func TestDeleteGroup(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	group := &models.Group{ID: "g1", Name: "gym", PasswordHash: "hash"}
+	if err := store.CreateGroup(group); err != nil {
+		t.Fatalf("failed to create group: %v", err)
+	}
+	found, err := store.GetGroupByPasswordHash("hash")
+	if err != nil || found.ID != "g1" {
+		t.Fatalf("expected group g1, got %+v (%v)", found, err)
+	}
+
+	member := GroupUserID("g1", "alice")
+	store.CreateSession("group-token", member)
+	store.CreateSession("default-token", "alice")
+	store.UpsertWorkout(&models.Workout{ID: "w1", UserID: member, Name: "Gym", Rounds: 1})
+
+	if err := store.DeleteGroup("g1"); err != nil {
+		t.Fatalf("failed to delete group: %v", err)
+	}
+	if _, err := store.VerifySession("group-token"); err == nil {
+		t.Error("expected group session to be deleted")
+	}
+	if _, err := store.VerifySession("default-token"); err != nil {
+		t.Errorf("expected default group session to survive: %v", err)
+	}
+	if _, err := store.GetWorkout(member, "w1"); err == nil {
+		t.Error("expected group workout to be deleted")
+	}
+	if err := store.DeleteGroup("g1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for missing group, got %v", err)
+	}
+}
+
+func TestUpsertKeepsOtherGroupsRows(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	member := GroupUserID("g1", "alice")
+	owned := &models.Workout{
+		ID: "w1", UserID: member, Name: "Gym", Rounds: 1,
+		Intervals: []models.Interval{{ID: "i1", Name: "Work", Duration: 30, Color: "#ff0000"}},
+	}
+	if _, err := store.UpsertWorkout(owned); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.UpsertCompletion(&models.Completion{ID: "c1", UserID: member, WorkoutID: "w1", WorkoutName: "Gym", ElapsedDuration: 30}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another group's alice reuses the IDs
+	_, err := store.UpsertWorkout(&models.Workout{ID: "w1", UserID: "alice", Name: "Taken", Rounds: 5})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for another user's workout, got %v", err)
+	}
+	err = store.UpsertCompletion(&models.Completion{ID: "c1", UserID: "alice", WorkoutID: "w1", WorkoutName: "Taken", ElapsedDuration: 1})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for another user's completion, got %v", err)
+	}
+
+	workout, err := store.GetWorkout(member, "w1")
+	if err != nil || workout.Name != "Gym" || workout.Rounds != 1 || len(workout.Intervals) != 1 || workout.Version != 1 {
+		t.Errorf("expected the workout to be untouched, got %+v (%v)", workout, err)
+	}
+	completion, err := store.GetCompletion(member, "c1")
+	if err != nil || completion.ElapsedDuration != 30 || completion.WorkoutName != "Gym" {
+		t.Errorf("expected the completion to be untouched, got %+v (%v)", completion, err)
+	}
+	if _, err := store.GetWorkout("alice", "w1"); err == nil {
+		t.Error("expected no workout for the other alice")
+	}
+}
+
+func TestPurgeDeletedAndBackup(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	store.UpsertWorkout(&models.Workout{ID: "w1", UserID: "user-123", Name: "Keep", Rounds: 1})
+	store.UpsertWorkout(&models.Workout{
+		ID: "w2", UserID: "user-123", Name: "Trash", Rounds: 1,
+		Intervals: []models.Interval{{ID: "int-1", Name: "Work", Duration: 30, Color: "#ff0000"}},
+	})
+	store.DeleteWorkout("user-123", "w2", 0)
+
+	// Recent tombstones are kept
+	workouts, _, err := store.PurgeDeleted(time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("failed to purge: %v", err)
+	}
+	if workouts != 0 {
+		t.Errorf("expected no purged workouts, got %d", workouts)
+	}
+
+	workouts, _, err = store.PurgeDeleted(time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatalf("failed to purge: %v", err)
+	}
+	if workouts != 1 {
+		t.Errorf("expected 1 purged workout, got %d", workouts)
+	}
+
+	usage, err := store.GetStorageUsage()
+	if err != nil {
+		t.Fatalf("failed to get storage usage: %v", err)
+	}
+	if usage.Tables["workouts"] != 1 || usage.Tables["workout_intervals"] != 0 {
+		t.Errorf("unexpected storage usage: %+v", usage.Tables)
+	}
+
+	path := t.TempDir() + "/backup.db"
+	if err := store.Backup(path); err != nil {
+		t.Fatalf("failed to back up: %v", err)
+	}
+	backup, err := sql.Open("libsql", "file:"+path)
+	if err != nil {
+		t.Fatalf("failed to open backup: %v", err)
+	}
+	defer backup.Close()
+	var count int
+	if err := backup.QueryRow("SELECT COUNT(*) FROM workouts").Scan(&count); err != nil {
+		t.Fatalf("failed to read backup: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 workout in backup, got %d", count)
+	}
+}
+
+func TestProjectsAndTasks(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	now := time.Now()
+	project := &models.Project{ID: "p1", UserID: "user-123", Name: "Thesis", CreatedAt: now, UpdatedAt: now}
+	if err := store.CreateProject(project); err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	task := &models.Task{ID: "t1", UserID: "user-123", ProjectID: "p1", Name: "Chapter 1", CreatedAt: now, UpdatedAt: now}
+	if err := store.CreateTask(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	completion := &models.Completion{ID: "c1", UserID: "user-123", WorkoutID: "w1", ProjectID: "p1", TaskID: "t1"}
+	if err := store.UpsertCompletion(completion); err != nil {
+		t.Fatalf("failed to save completion: %v", err)
+	}
+	completions, _, err := store.ListCompletions("user-123", models.CompletionListOptions{TaskID: "t1", Limit: 10})
+	if err != nil {
+		t.Fatalf("failed to list completions: %v", err)
+	}
+	if len(completions) != 1 || completions[0].ProjectID != "p1" {
+		t.Errorf("expected the tagged completion, got %+v", completions)
+	}
+
+	if _, err := store.GetProject("other-user", "p1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for another profile, got %v", err)
+	}
+
+	// Deleting a project keeps its tasks without a project
+	if err := store.DeleteProject("user-123", "p1"); err != nil {
+		t.Fatalf("failed to delete project: %v", err)
+	}
+	found, err := store.GetTask("user-123", "t1")
+	if err != nil {
+		t.Fatalf("failed to get task: %v", err)
+	}
+	if found.ProjectID != "" {
+		t.Errorf("expected the task to leave the deleted project, got %q", found.ProjectID)
+	}
+	if err := store.DeleteProject("user-123", "p1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound deleting twice, got %v", err)
+	}
+}
@@ -1,9 +1,30 @@
 package store
 
 import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"intervals-sync/internal/models"
+	"strings"
+	"time"
 )
 
+// ErrNotFound is returned when a requested record does not exist
+var ErrNotFound = errors.New("not found")
+
+// ErrInvalidCursor is returned for a malformed pagination cursor
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrVersionConflict is returned when a conditional write finds a different
+// version than expected
+var ErrVersionConflict = errors.New("version conflict")
+
+// ErrNotSupported is returned when a backend cannot perform an operation
+var ErrNotSupported = errors.New("not supported by this store")
+
 // Store defines the database abstraction interface
 type Store interface {
 	// Lifecycle
@@ -15,16 +36,88 @@ type Store interface {
 	VerifySession(token string) (*models.Session, error)
 	DeleteSession(token string) error
 
+	// Personal API tokens
+	CreateAPIToken(token *models.APIToken) error
+	GetAPIToken(tokenHash string) (*models.APIToken, error)
+	ListAPITokens(userID string) ([]models.APIToken, error)
+	DeleteAPIToken(userID string, tokenID string) error
+
+	// External identities (OIDC subject -> profile)
+	GetIdentityProfile(issuer string, subject string) (string, error)
+	LinkIdentity(issuer string, subject string, userID string) error
+	ProfileInUse(userID string) (bool, error)
+
+	// Device pairing codes (single-use)
+	CreatePairingCode(codeHash string, userID string, expiresAt time.Time) error
+	RedeemPairingCode(codeHash string) (string, error)
+	RecordPairingFailure(maxFailures int) error
+
+	// Tenant groups
+	CreateGroup(group *models.Group) error
+	ListGroups() ([]models.Group, error)
+	GetGroupByPasswordHash(passwordHash string) (*models.Group, error)
+	DeleteGroup(groupID string) error
+
+	// Server passwords managed at runtime
+	ListServerPasswords() ([]models.ServerPassword, error)
+	PutServerPassword(password *models.ServerPassword) error
+
+	// Failed login tracking for brute-force lockouts
+	GetLoginFailure(key string) (*models.LoginFailure, error)
+	RecordLoginFailure(key string, resetBefore time.Time) (*models.LoginFailure, error)
+	LockLogin(key string, until time.Time) error
+	ClearLoginFailure(key string) error
+
+	// Revocations of signed session tokens
+	RevokeSessions(revocation *models.SessionRevocation) error
+	ListSessionRevocations() ([]models.SessionRevocation, error)
+
+	// Administration
+	ListProfileSummaries() ([]models.ProfileSummary, error)
+	ListSessions(userID string) ([]models.Session, error)
+	DeleteUserSessions(userID string) (int64, error)
+	GetStorageUsage() (*models.StorageUsage, error)
+	PurgeDeleted(before time.Time) (workouts int64, completions int64, err error)
+	Backup(path string) error
+	AddAuditEntry(entry *models.AuditEntry) error
+	ListAuditEntries(limit int) ([]models.AuditEntry, error)
+
 	// Workout operations
-	UpsertWorkout(workout *models.Workout) error
+	UpsertWorkout(workout *models.Workout) (changed bool, err error)
+	UpdateWorkout(workout *models.Workout, ifVersion int64) error
 	GetWorkoutsModifiedSince(userID string, since int64) ([]models.Workout, error)
 	GetWorkout(userID string, workoutID string) (*models.Workout, error)
-	DeleteWorkout(userID string, workoutID string) error
+	ListWorkouts(userID string, opts models.WorkoutListOptions) ([]models.Workout, int, error)
+	DeleteWorkout(userID string, workoutID string, ifVersion int64) error
+	ListDeletedWorkouts(userID string) ([]models.Workout, error)
+	RestoreWorkout(userID string, workoutID string) error
+
+	// Workout revision history
+	AddWorkoutRevision(revision *models.WorkoutRevision) error
+	ListWorkoutRevisions(userID string, workoutID string) ([]models.WorkoutRevision, error)
+	GetWorkoutRevision(userID string, workoutID string, version int64) (*models.WorkoutRevision, error)
 
 	// Completion operations
 	UpsertCompletion(completion *models.Completion) error
 	GetCompletionsModifiedSince(userID string, since int64) ([]models.Completion, error)
+	GetCompletion(userID string, completionID string) (*models.Completion, error)
+	ListCompletions(userID string, opts models.CompletionListOptions) ([]models.Completion, string, error)
 	DeleteCompletion(userID string, completionID string) error
+	ListDeletedCompletions(userID string) ([]models.Completion, error)
+	RestoreCompletion(userID string, completionID string) error
+	TagCompletion(userID string, completionID string, projectID string, taskID string, updatedAt time.Time) error
+
+	// Projects and tasks completions can be tagged with
+	CreateProject(project *models.Project) error
+	UpdateProject(project *models.Project) error
+	GetProject(userID string, projectID string) (*models.Project, error)
+	ListProjects(userID string) ([]models.Project, error)
+	DeleteProject(userID string, projectID string) error
+	CreateTask(task *models.Task) error
+	UpdateTask(task *models.Task) error
+	GetTask(userID string, taskID string) (*models.Task, error)
+	ListTasks(userID string, projectID string) ([]models.Task, error)
+	DeleteTask(userID string, taskID string) error
 
 	// Utility
 	GetLastSyncTime(userID string) (int64, error)
@@ -51,3 +144,634 @@ func NewStore(cfg *Config) (Store, error) {
 	}
 	return NewSQLiteStore(cfg)
 }
+
+// groupPrefix marks user IDs that belong to a tenant group rather than the
+// default group, e.g. "group:<id>:alice"
+const groupPrefix = "group:"
+
+// GroupUserID namespaces a profile name within a group. Profiles in the
+// default group (empty groupID) keep their plain name.
+func GroupUserID(groupID string, profile string) string {
+	if groupID == "" {
+		return profile
+	}
+	return groupPrefix + groupID + ":" + profile
+}
+
+// SplitGroupUserID returns the group and profile name of a user ID
+func SplitGroupUserID(userID string) (groupID string, profile string) {
+	if !strings.HasPrefix(userID, groupPrefix) {
+		return "", userID
+	}
+	rest := strings.TrimPrefix(userID, groupPrefix)
+	if i := strings.Index(rest, ":"); i >= 0 {
+		return rest[:i], rest[i+1:]
+	}
+	return "", userID
+}
+
+// IsReservedProfileName reports whether a profile name would collide with
+// the group namespace
+func IsReservedProfileName(profile string) bool {
+	return strings.HasPrefix(profile, groupPrefix)
+}
+
+// groupTables lists every table holding per-profile data, removed with a group
+var groupTables = []string{
+	"sessions", "api_tokens", "pairing_codes", "external_identities",
+	"workouts", "workout_revisions", "completions", "workout_snapshots", "sync_metadata",
+	"projects", "tasks",
+}
+
+// storageTables lists the tables reported by GetStorageUsage
+var storageTables = []string{
+	"sessions", "api_tokens", "pairing_codes", "external_identities", "tenant_groups", "server_passwords",
+	"session_revocations", "login_failures",
+	"workouts", "workout_intervals", "workout_revisions", "completions", "workout_snapshots", "sync_metadata", "admin_audit",
+	"projects", "tasks",
+}
+
+// revocationID is the primary key of a revocation: the token ID, or the user
+// ID for revocations covering every token of a profile
+func revocationID(r *models.SessionRevocation) string {
+	if r.TokenID != "" {
+		return "token:" + r.TokenID
+	}
+	return "user:" + r.UserID
+}
+
+// workoutSortColumns maps ListWorkouts sort keys to ORDER BY expressions
+var workoutSortColumns = map[string]string{
+	"name":       "name COLLATE NOCASE",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+// ValidWorkoutSort reports whether a sort key is accepted by ListWorkouts
+func ValidWorkoutSort(sort string) bool {
+	_, ok := workoutSortColumns[strings.TrimPrefix(sort, "-")]
+	return ok
+}
+
+// workoutOrderBy returns the ORDER BY clause for a sort key, defaulting to
+// most recently updated first. The id tiebreak keeps pages stable.
+func workoutOrderBy(sort string) string {
+	column, ok := workoutSortColumns[strings.TrimPrefix(sort, "-")]
+	if !ok {
+		return "updated_at DESC, id"
+	}
+	if strings.HasPrefix(sort, "-") {
+		return column + " DESC, id"
+	}
+	return column + " ASC, id"
+}
+
+// likePattern escapes a user-supplied substring for LIKE ... ESCAPE '\'
+func likePattern(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	s = strings.ReplaceAll(s, "%", "\\%")
+	s = strings.ReplaceAll(s, "_", "\\_")
+	return "%" + s + "%"
+}
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// completionColumns is the column list read by scanCompletion. The workout
+// snapshot is looked up by a subquery so list queries stay a single statement.
+const completionColumns = `id, user_id, workout_id, workout_name, total_duration, elapsed_duration,
+	completed, started_at, completed_at, updated_at, deleted_at, snapshot_hash, interval_timings, score,
+	focus_seconds, interruptions, project_id, task_id,
+	(SELECT workout_snapshots.snapshot FROM workout_snapshots
+		WHERE workout_snapshots.user_id = completions.user_id
+		AND workout_snapshots.hash = completions.snapshot_hash)`
+
+// saveWorkoutSnapshot stores a snapshot under its content hash, once per
+// profile, and returns the hash. createdAt is encoded for the store's
+// column type.
+func saveWorkoutSnapshot(tx *sql.Tx, userID string, snapshot *models.WorkoutSnapshot, createdAt interface{}) (string, error) {
+	if snapshot.Intervals == nil {
+		snapshot.Intervals = []models.Interval{}
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	_, err = tx.Exec(`
+		INSERT INTO workout_snapshots (user_id, hash, snapshot, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id, hash) DO NOTHING
+	`, userID, hash, string(data), createdAt)
+	return hash, err
+}
+
+// decodeSnapshot fills a completion's snapshot from the columns read by
+// completionColumns
+func decodeSnapshot(c *models.Completion, hash sql.NullString, snapshot sql.NullString) error {
+	c.SnapshotHash = hash.String
+	if !snapshot.Valid {
+		return nil
+	}
+	c.Snapshot = &models.WorkoutSnapshot{}
+	return json.Unmarshal([]byte(snapshot.String), c.Snapshot)
+}
+
+// encodeTimings returns the interval_timings column value: JSON, or NULL when
+// the completion has none
+func encodeTimings(timings []models.IntervalTiming) (interface{}, error) {
+	if len(timings) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(timings)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func decodeTimings(timings sql.NullString) ([]models.IntervalTiming, error) {
+	if !timings.Valid {
+		return nil, nil
+	}
+	var t []models.IntervalTiming
+	err := json.Unmarshal([]byte(timings.String), &t)
+	return t, err
+}
+
+// encodeScore returns the score column value: JSON, or NULL when the
+// completion has none
+func encodeScore(score *models.Score) (interface{}, error) {
+	if score == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(score)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func decodeScore(score sql.NullString) (*models.Score, error) {
+	if !score.Valid {
+		return nil, nil
+	}
+	var s models.Score
+	if err := json.Unmarshal([]byte(score.String), &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Completions are filtered, ordered and paged by comparing started_at as
+// text, so it is stored in UTC at a fixed width. SQLite keeps nanoseconds;
+// Turso stores whole seconds like its other time columns.
+const (
+	sqliteStartedAtLayout = "2006-01-02T15:04:05.000000000Z07:00"
+	tursoStartedAtLayout  = time.RFC3339
+)
+
+// normalizeStartedAt rewrites started_at values saved before they were
+// normalized to layout in UTC
+func normalizeStartedAt(db *sql.DB, layout string) error {
+	width := len(time.Time{}.Format(layout))
+	rows, err := db.Query(
+		"SELECT id, started_at FROM completions WHERE length(started_at) != ? OR substr(started_at, -1) != 'Z'",
+		width,
+	)
+	if err != nil {
+		return err
+	}
+	updates := map[string]string{}
+	for rows.Next() {
+		var id, startedAt string
+		if err := rows.Scan(&id, &startedAt); err != nil {
+			rows.Close()
+			return err
+		}
+		if t, err := parseTime(startedAt); err == nil {
+			updates[id] = t.UTC().Format(layout)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, startedAt := range updates {
+		if _, err := db.Exec("UPDATE completions SET started_at = ? WHERE id = ?", startedAt, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// completionFilter builds the WHERE clause shared by ListCompletions in both
+// stores. Times are passed through formatTime to match the column encoding.
+func completionFilter(userID string, opts models.CompletionListOptions, formatTime func(time.Time) interface{}) (string, []interface{}, error) {
+	where := "user_id = ? AND deleted_at IS NULL"
+	args := []interface{}{userID}
+	if !opts.From.IsZero() {
+		where += " AND started_at >= ?"
+		args = append(args, formatTime(opts.From))
+	}
+	if !opts.To.IsZero() {
+		where += " AND started_at < ?"
+		args = append(args, formatTime(opts.To))
+	}
+	if opts.WorkoutID != "" {
+		where += " AND workout_id = ?"
+		args = append(args, opts.WorkoutID)
+	}
+	if opts.ProjectID != "" {
+		where += " AND project_id = ?"
+		args = append(args, opts.ProjectID)
+	}
+	if opts.TaskID != "" {
+		where += " AND task_id = ?"
+		args = append(args, opts.TaskID)
+	}
+	if opts.Completed != nil {
+		where += " AND completed = ?"
+		args = append(args, *opts.Completed)
+	}
+	if opts.Cursor != "" {
+		startedAt, id, err := decodeCursor(opts.Cursor)
+		if err != nil {
+			return "", nil, err
+		}
+		op := "<"
+		if opts.Ascending {
+			op = ">"
+		}
+		where += " AND (started_at " + op + " ? OR (started_at = ? AND id " + op + " ?))"
+		args = append(args, startedAt, startedAt, id)
+	}
+	return where, args, nil
+}
+
+// completionOrderBy returns the ORDER BY clause matching completionFilter's cursor
+func completionOrderBy(ascending bool) string {
+	if ascending {
+		return "started_at ASC, id ASC"
+	}
+	return "started_at DESC, id DESC"
+}
+
+// encodeCursor makes an opaque keyset cursor from the raw started_at column
+// value and ID of the last row on a page
+func encodeCursor(startedAt string, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(startedAt + "\x00" + id))
+}
+
+func decodeCursor(cursor string) (string, string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", "", ErrInvalidCursor
+	}
+	startedAt, id, ok := strings.Cut(string(b), "\x00")
+	if !ok {
+		return "", "", ErrInvalidCursor
+	}
+	return startedAt, id, nil
+}
+
+// splitScopes parses a comma-separated scope list as stored in the database
+func splitScopes(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// maxWorkoutRevisions is how many revisions are kept per workout; older ones
+// are pruned as new ones are added
+const maxWorkoutRevisions = 100
+
+// workoutRevisionColumns is the column list read by the revision scanners
+const workoutRevisionColumns = "workout_id, user_id, version, device, source, snapshot, created_at"
+
+// addWorkoutRevision stores a revision unless its snapshot is the same as the
+// latest one (sync re-sends unchanged workouts), then prunes the oldest
+// revisions beyond maxWorkoutRevisions. createdAt is already encoded for the
+// store's column type.
+func addWorkoutRevision(db *sql.DB, rev *models.WorkoutRevision, createdAt interface{}) error {
+	snapshot, err := json.Marshal(rev.Snapshot)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var latest string
+	err = tx.QueryRow(`
+		SELECT snapshot FROM workout_revisions
+		WHERE user_id = ? AND workout_id = ?
+		ORDER BY version DESC LIMIT 1
+	`, rev.UserID, rev.WorkoutID).Scan(&latest)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if err == nil && latest == string(snapshot) {
+		return nil
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO workout_revisions (workout_id, user_id, version, device, source, snapshot, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, workout_id, version) DO NOTHING
+	`, rev.WorkoutID, rev.UserID, rev.Version, rev.Device, rev.Source, string(snapshot), createdAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM workout_revisions
+		WHERE user_id = ? AND workout_id = ? AND version <= (
+			SELECT version FROM workout_revisions
+			WHERE user_id = ? AND workout_id = ?
+			ORDER BY version DESC LIMIT 1 OFFSET ?
+		)
+	`, rev.UserID, rev.WorkoutID, rev.UserID, rev.WorkoutID, maxWorkoutRevisions)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// addedColumns are columns added to existing tables after the first release,
+// in the order they were introduced
+var addedColumns = []struct {
+	table      string
+	column     string
+	definition string
+}{
+	{"workouts", "version", "INTEGER NOT NULL DEFAULT 1"},
+	{"completions", "snapshot_hash", "TEXT"},
+	{"workouts", "blocks", "TEXT"},
+	{"workout_intervals", "type", "TEXT NOT NULL DEFAULT ''"},
+	{"workout_intervals", "notes", "TEXT NOT NULL DEFAULT ''"},
+	{"workout_intervals", "target_reps", "INTEGER NOT NULL DEFAULT 0"},
+	{"workout_intervals", "target_hr_zone", "INTEGER NOT NULL DEFAULT 0"},
+	{"workout_intervals", "exercise_ref", "TEXT NOT NULL DEFAULT ''"},
+	{"workout_intervals", "progression", "TEXT"},
+	{"workout_intervals", "condition", "TEXT"},
+	{"workout_intervals", "manual", "INTEGER NOT NULL DEFAULT 0"},
+	{"workout_intervals", "time_cap", "INTEGER NOT NULL DEFAULT 0"},
+	{"completions", "interval_timings", "TEXT"},
+	{"workouts", "kind", "TEXT NOT NULL DEFAULT ''"},
+	{"workouts", "period", "INTEGER NOT NULL DEFAULT 0"},
+	{"workouts", "time_cap", "INTEGER NOT NULL DEFAULT 0"},
+	{"completions", "score", "TEXT"},
+	{"workouts", "pomodoro", "TEXT"},
+	{"completions", "focus_seconds", "INTEGER NOT NULL DEFAULT 0"},
+	{"completions", "interruptions", "INTEGER NOT NULL DEFAULT 0"},
+	{"completions", "project_id", "TEXT NOT NULL DEFAULT ''"},
+	{"completions", "task_id", "TEXT NOT NULL DEFAULT ''"},
+	{"pairing_codes", "failures", "INTEGER NOT NULL DEFAULT 0"},
+	{"workouts", "content_hash", "TEXT"},
+}
+
+// migrateColumns adds any of addedColumns that are missing
+func migrateColumns(db *sql.DB) error {
+	for _, c := range addedColumns {
+		if err := ensureColumn(db, c.table, c.column, c.definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureColumn adds a column to an existing table if it is missing, for
+// schema changes that CREATE TABLE IF NOT EXISTS cannot make
+func ensureColumn(db *sql.DB, table string, column string, definition string) error {
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", table, column).Scan(&n)
+	if err != nil || n > 0 {
+		return err
+	}
+	_, err = db.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition)
+	return err
+}
+
+// replaceIntervals replaces the intervals of a workout inside a transaction.
+// Returns ErrNotFound if the workout doesn't belong to the user.
+func replaceIntervals(tx *sql.Tx, userID string, workoutID string, intervals []models.Interval) error {
+	if _, err := workoutVersion(tx, userID, workoutID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM workout_intervals WHERE workout_id = ?", workoutID); err != nil {
+		return err
+	}
+	for i, interval := range intervals {
+		position := interval.Position
+		if position == 0 && i > 0 {
+			position = i // Use index as position if not set
+		}
+		progression, err := encodeProgression(interval.Progression)
+		if err != nil {
+			return err
+		}
+		condition, err := encodeCondition(interval.Condition)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`
+			INSERT INTO workout_intervals (
+				id, workout_id, name, duration, color, position,
+				type, notes, target_reps, target_hr_zone, exercise_ref, progression, condition,
+				manual, time_cap
+			)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, interval.ID, workoutID, interval.Name, interval.Duration, interval.Color, position,
+			interval.Type, interval.Notes, interval.TargetReps, interval.TargetHRZone, interval.ExerciseRef, progression, condition,
+			interval.Manual, interval.TimeCap)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeBlocks returns the blocks column value: JSON, or NULL for flat workouts
+func encodeBlocks(blocks []models.Block) (interface{}, error) {
+	if len(blocks) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(blocks)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func decodeBlocks(blocks sql.NullString) ([]models.Block, error) {
+	if !blocks.Valid {
+		return nil, nil
+	}
+	var b []models.Block
+	err := json.Unmarshal([]byte(blocks.String), &b)
+	return b, err
+}
+
+// encodeProgression returns the progression column value: JSON, or NULL for
+// intervals with a fixed duration
+func encodeProgression(p *models.Progression) (interface{}, error) {
+	if p == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func decodeProgression(p sql.NullString) (*models.Progression, error) {
+	if !p.Valid {
+		return nil, nil
+	}
+	var progression models.Progression
+	if err := json.Unmarshal([]byte(p.String), &progression); err != nil {
+		return nil, err
+	}
+	return &progression, nil
+}
+
+// encodeCondition returns the condition column value: JSON, or NULL for
+// intervals performed in every round
+func encodeCondition(c *models.Condition) (interface{}, error) {
+	if c == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func decodeCondition(c sql.NullString) (*models.Condition, error) {
+	if !c.Valid {
+		return nil, nil
+	}
+	var condition models.Condition
+	if err := json.Unmarshal([]byte(c.String), &condition); err != nil {
+		return nil, err
+	}
+	return &condition, nil
+}
+
+// encodePomodoro returns the pomodoro column value: JSON, or NULL for other
+// kinds of workout
+func encodePomodoro(p *models.Pomodoro) (interface{}, error) {
+	if p == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func decodePomodoro(p sql.NullString) (*models.Pomodoro, error) {
+	if !p.Valid {
+		return nil, nil
+	}
+	var pomodoro models.Pomodoro
+	if err := json.Unmarshal([]byte(p.String), &pomodoro); err != nil {
+		return nil, err
+	}
+	return &pomodoro, nil
+}
+
+// storedIntervals returns the intervals kept in workout_intervals. Workouts
+// with blocks keep none; their intervals are derived from the blocks.
+func storedIntervals(workout *models.Workout) []models.Interval {
+	if len(workout.Blocks) > 0 {
+		return nil
+	}
+	return workout.Intervals
+}
+
+// workoutContentHash hashes the stored content of a workout, so upserts can
+// tell whether a synced copy changed it
+func workoutContentHash(workout *models.Workout) (string, error) {
+	intervals := storedIntervals(workout)
+	if intervals == nil {
+		intervals = []models.Interval{}
+	}
+	data, err := json.Marshal(models.WorkoutSnapshot{
+		Name:      workout.Name,
+		Rounds:    workout.Rounds,
+		Intervals: intervals,
+		Blocks:    workout.Blocks,
+		Kind:      workout.Kind,
+		Period:    workout.Period,
+		TimeCap:   workout.TimeCap,
+		Pomodoro:  workout.Pomodoro,
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// finishWorkout fills the fields derived from a workout's structure
+func finishWorkout(workout *models.Workout) {
+	if len(workout.Blocks) > 0 {
+		workout.Intervals = models.FlattenBlocks(workout.Blocks)
+	}
+	workout.TotalDuration = workout.Duration()
+	workout.DurationByType = workout.TypeDurations()
+	workout.DurationRange = workout.Bounds()
+}
+
+// workoutVersion reads the version of a user's workout inside a transaction
+func workoutVersion(tx *sql.Tx, userID string, workoutID string) (int64, error) {
+	var version int64
+	err := tx.QueryRow("SELECT version FROM workouts WHERE id = ? AND user_id = ?", workoutID, userID).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	return version, err
+}
+
+// workoutWriteError explains why a conditional workout write touched no
+// rows: ErrNotFound if the workout is gone, ErrVersionConflict otherwise
+func workoutWriteError(tx *sql.Tx, userID string, workoutID string) error {
+	var version int64
+	err := tx.QueryRow(
+		"SELECT version FROM workouts WHERE id = ? AND user_id = ? AND deleted_at IS NULL",
+		workoutID, userID,
+	).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return ErrVersionConflict
+}
+
+// requireAffected returns ErrNotFound if a statement touched no rows
+func requireAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
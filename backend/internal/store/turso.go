@@ -2,8 +2,10 @@ package store
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"intervals-sync/internal/models"
+	"strings"
 	"time"
 
 	_ "github.com/tursodatabase/go-libsql"
@@ -100,15 +102,129 @@ func (s *TursoStore) Migrate() error {
 	CREATE INDEX IF NOT EXISTS idx_completions_user_id ON completions(user_id);
 	CREATE INDEX IF NOT EXISTS idx_completions_updated_at ON completions(updated_at);
 	CREATE INDEX IF NOT EXISTS idx_completions_deleted_at ON completions(deleted_at);
+	CREATE INDEX IF NOT EXISTS idx_completions_user_started_at ON completions(user_id, started_at);
+
+	CREATE TABLE IF NOT EXISTS workout_revisions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		workout_id TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		version INTEGER NOT NULL,
+		device TEXT NOT NULL,
+		source TEXT NOT NULL,
+		snapshot TEXT NOT NULL,
+		created_at TEXT NOT NULL
+	);
+
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_workout_revisions_workout ON workout_revisions(user_id, workout_id, version);
+
+	CREATE TABLE IF NOT EXISTS workout_snapshots (
+		user_id TEXT NOT NULL,
+		hash TEXT NOT NULL,
+		snapshot TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		PRIMARY KEY (user_id, hash)
+	);
+
+	CREATE TABLE IF NOT EXISTS projects (
+		id TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		color TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		updated_at TEXT NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_projects_user_id ON projects(user_id);
+
+	CREATE TABLE IF NOT EXISTS tasks (
+		id TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		project_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		done INTEGER NOT NULL,
+		created_at TEXT NOT NULL,
+		updated_at TEXT NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_tasks_user_id ON tasks(user_id);
 
 	CREATE TABLE IF NOT EXISTS sync_metadata (
 		user_id TEXT PRIMARY KEY,
 		last_sync_time INTEGER NOT NULL
 	);
+
+	CREATE TABLE IF NOT EXISTS api_tokens (
+		id TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		token_hash TEXT NOT NULL UNIQUE,
+		scopes TEXT NOT NULL,
+		created_at TEXT NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_api_tokens_user_id ON api_tokens(user_id);
+
+	CREATE TABLE IF NOT EXISTS external_identities (
+		issuer TEXT NOT NULL,
+		subject TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		PRIMARY KEY (issuer, subject)
+	);
+
+	CREATE TABLE IF NOT EXISTS pairing_codes (
+		code_hash TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		expires_at INTEGER NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS tenant_groups (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		password_hash TEXT NOT NULL UNIQUE,
+		created_at TEXT NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS server_passwords (
+		password_hash TEXT PRIMARY KEY,
+		expires_at INTEGER,
+		created_at TEXT NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS login_failures (
+		lockout_key TEXT PRIMARY KEY,
+		failures INTEGER NOT NULL,
+		last_failure_at INTEGER NOT NULL,
+		locked_until INTEGER NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS session_revocations (
+		id TEXT PRIMARY KEY,
+		token_id TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		revoked_at INTEGER NOT NULL,
+		expires_at INTEGER NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS admin_audit (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		action TEXT NOT NULL,
+		target TEXT NOT NULL,
+		detail TEXT NOT NULL,
+		remote_addr TEXT NOT NULL,
+		created_at TEXT NOT NULL
+	);
 	`
 
-	_, err := s.db.Exec(schema)
-	return err
+	if _, err := s.db.Exec(schema); err != nil {
+		return err
+	}
+
+	// Columns added after the first release
+	if err := migrateColumns(s.db); err != nil {
+		return err
+	}
+	return normalizeStartedAt(s.db, tursoStartedAtLayout)
 }
 
 // Close closes the database connection
@@ -162,14 +278,644 @@ func (s *TursoStore) DeleteSession(token string) error {
 	return err
 }
 
-// UpsertWorkout inserts or updates a workout
-func (s *TursoStore) UpsertWorkout(workout *models.Workout) error {
+// CreateAPIToken stores a new personal API token
+func (s *TursoStore) CreateAPIToken(token *models.APIToken) error {
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO api_tokens (id, user_id, name, token_hash, scopes, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, token.ID, token.UserID, token.Name, token.TokenHash,
+		strings.Join(token.Scopes, ","), token.CreatedAt.Format(time.RFC3339))
+	return err
+}
+
+// GetAPIToken looks up a personal API token by its hash
+func (s *TursoStore) GetAPIToken(tokenHash string) (*models.APIToken, error) {
+	var t models.APIToken
+	var scopes, createdAtStr string
+	err := s.db.QueryRow(`
+		SELECT id, user_id, name, token_hash, scopes, created_at
+		FROM api_tokens
+		WHERE token_hash = ?
+	`, tokenHash).Scan(&t.ID, &t.UserID, &t.Name, &t.TokenHash, &scopes, &createdAtStr)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	t.Scopes = splitScopes(scopes)
+	t.CreatedAt, _ = time.Parse(time.RFC3339, createdAtStr)
+	return &t, nil
+}
+
+// ListAPITokens returns all personal API tokens for a user
+func (s *TursoStore) ListAPITokens(userID string) ([]models.APIToken, error) {
+	rows, err := s.db.Query(`
+		SELECT id, user_id, name, token_hash, scopes, created_at
+		FROM api_tokens
+		WHERE user_id = ?
+		ORDER BY created_at ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []models.APIToken{}
+	for rows.Next() {
+		var t models.APIToken
+		var scopes, createdAtStr string
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Name, &t.TokenHash, &scopes, &createdAtStr); err != nil {
+			return nil, err
+		}
+		t.Scopes = splitScopes(scopes)
+		t.CreatedAt, _ = time.Parse(time.RFC3339, createdAtStr)
+		tokens = append(tokens, t)
+	}
+
+	return tokens, rows.Err()
+}
+
+// DeleteAPIToken revokes a personal API token
+func (s *TursoStore) DeleteAPIToken(userID string, tokenID string) error {
+	res, err := s.db.Exec("DELETE FROM api_tokens WHERE id = ? AND user_id = ?", tokenID, userID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// GetIdentityProfile returns the profile linked to an external identity
+func (s *TursoStore) GetIdentityProfile(issuer string, subject string) (string, error) {
+	var userID string
+	err := s.db.QueryRow(
+		"SELECT user_id FROM external_identities WHERE issuer = ? AND subject = ?",
+		issuer, subject,
+	).Scan(&userID)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+
+	return userID, nil
+}
+
+// LinkIdentity links an external identity to a profile
+func (s *TursoStore) LinkIdentity(issuer string, subject string, userID string) error {
+	_, err := s.db.Exec(`
+		INSERT INTO external_identities (issuer, subject, user_id, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(issuer, subject) DO UPDATE SET
+			user_id = excluded.user_id
+	`, issuer, subject, userID, time.Now().Format(time.RFC3339))
+	return err
+}
+
+// ProfileInUse reports whether any data, session or identity belongs to a
+// profile
+func (s *TursoStore) ProfileInUse(userID string) (bool, error) {
+	for _, table := range groupTables {
+		var found int
+		err := s.db.QueryRow("SELECT 1 FROM "+table+" WHERE user_id = ? LIMIT 1", userID).Scan(&found)
+		if err == nil {
+			return true, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// CreatePairingCode stores a short-lived pairing code, pruning expired ones
+func (s *TursoStore) CreatePairingCode(codeHash string, userID string, expiresAt time.Time) error {
+	if _, err := s.db.Exec("DELETE FROM pairing_codes WHERE expires_at <= ?", time.Now().UnixMilli()); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(
+		"INSERT INTO pairing_codes (code_hash, user_id, expires_at) VALUES (?, ?, ?)",
+		codeHash, userID, expiresAt.UnixMilli(),
+	)
+	return err
+}
+
+// RedeemPairingCode consumes a pairing code and returns its profile
+func (s *TursoStore) RedeemPairingCode(codeHash string) (string, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var userID string
+	var expiresAt int64
+	err = tx.QueryRow(
+		"SELECT user_id, expires_at FROM pairing_codes WHERE code_hash = ?",
+		codeHash,
+	).Scan(&userID, &expiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+
+	if _, err := tx.Exec("DELETE FROM pairing_codes WHERE code_hash = ?", codeHash); err != nil {
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+
+	if time.Now().UnixMilli() >= expiresAt {
+		return "", ErrNotFound
+	}
+	return userID, nil
+}
+
+// RecordPairingFailure counts a failed redemption against every live
+// pairing code and burns those that reached maxFailures. A wrong guess could
+// have targeted any of them.
+func (s *TursoStore) RecordPairingFailure(maxFailures int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UnixMilli()
+	if _, err := tx.Exec("UPDATE pairing_codes SET failures = failures + 1 WHERE expires_at > ?", now); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM pairing_codes WHERE failures >= ? OR expires_at <= ?", maxFailures, now); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CreateGroup creates a tenant group
+func (s *TursoStore) CreateGroup(group *models.Group) error {
+	if group.CreatedAt.IsZero() {
+		group.CreatedAt = time.Now()
+	}
+	_, err := s.db.Exec(
+		"INSERT INTO tenant_groups (id, name, password_hash, created_at) VALUES (?, ?, ?, ?)",
+		group.ID, group.Name, group.PasswordHash, group.CreatedAt.Format(time.RFC3339),
+	)
+	return err
+}
+
+// ListGroups returns all tenant groups
+func (s *TursoStore) ListGroups() ([]models.Group, error) {
+	rows, err := s.db.Query("SELECT id, name, password_hash, created_at FROM tenant_groups ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []models.Group{}
+	for rows.Next() {
+		var g models.Group
+		var createdAtStr string
+		if err := rows.Scan(&g.ID, &g.Name, &g.PasswordHash, &createdAtStr); err != nil {
+			return nil, err
+		}
+		g.CreatedAt, _ = time.Parse(time.RFC3339, createdAtStr)
+		groups = append(groups, g)
+	}
+
+	return groups, rows.Err()
+}
+
+// GetGroupByPasswordHash finds the group a password belongs to
+func (s *TursoStore) GetGroupByPasswordHash(passwordHash string) (*models.Group, error) {
+	var g models.Group
+	var createdAtStr string
+	err := s.db.QueryRow(
+		"SELECT id, name, password_hash, created_at FROM tenant_groups WHERE password_hash = ?",
+		passwordHash,
+	).Scan(&g.ID, &g.Name, &g.PasswordHash, &createdAtStr)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	g.CreatedAt, _ = time.Parse(time.RFC3339, createdAtStr)
+	return &g, nil
+}
+
+// DeleteGroup removes a tenant group together with all of its profiles' data
+func (s *TursoStore) DeleteGroup(groupID string) error {
 	tx, err := s.db.Begin()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
+	res, err := tx.Exec("DELETE FROM tenant_groups WHERE id = ?", groupID)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(res); err != nil {
+		return err
+	}
+
+	prefix := GroupUserID(groupID, "")
+	_, err = tx.Exec(`
+		DELETE FROM workout_intervals WHERE workout_id IN (
+			SELECT id FROM workouts WHERE substr(user_id, 1, ?) = ?
+		)
+	`, len(prefix), prefix)
+	if err != nil {
+		return err
+	}
+	for _, table := range groupTables {
+		_, err := tx.Exec("DELETE FROM "+table+" WHERE substr(user_id, 1, ?) = ?", len(prefix), prefix)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ListServerPasswords returns passwords managed through the admin API,
+// including expired ones
+func (s *TursoStore) ListServerPasswords() ([]models.ServerPassword, error) {
+	rows, err := s.db.Query(`
+		SELECT password_hash, expires_at, created_at
+		FROM server_passwords
+		ORDER BY created_at ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	passwords := []models.ServerPassword{}
+	for rows.Next() {
+		var p models.ServerPassword
+		var expiresAt sql.NullInt64
+		var createdAtStr string
+		if err := rows.Scan(&p.PasswordHash, &expiresAt, &createdAtStr); err != nil {
+			return nil, err
+		}
+		if expiresAt.Valid {
+			t := time.UnixMilli(expiresAt.Int64)
+			p.ExpiresAt = &t
+		}
+		p.CreatedAt, _ = time.Parse(time.RFC3339, createdAtStr)
+		passwords = append(passwords, p)
+	}
+
+	return passwords, rows.Err()
+}
+
+// PutServerPassword adds a server password or updates its expiry
+func (s *TursoStore) PutServerPassword(password *models.ServerPassword) error {
+	if password.CreatedAt.IsZero() {
+		password.CreatedAt = time.Now()
+	}
+	var expiresAt *int64
+	if password.ExpiresAt != nil {
+		ms := password.ExpiresAt.UnixMilli()
+		expiresAt = &ms
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO server_passwords (password_hash, expires_at, created_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(password_hash) DO UPDATE SET
+			expires_at = excluded.expires_at
+	`, password.PasswordHash, expiresAt, password.CreatedAt.Format(time.RFC3339))
+	return err
+}
+
+// GetLoginFailure returns the failed login record for a key
+func (s *TursoStore) GetLoginFailure(key string) (*models.LoginFailure, error) {
+	f := models.LoginFailure{Key: key}
+	var lastFailureAt, lockedUntil int64
+	err := s.db.QueryRow(
+		"SELECT failures, last_failure_at, locked_until FROM login_failures WHERE lockout_key = ?",
+		key,
+	).Scan(&f.Failures, &lastFailureAt, &lockedUntil)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	f.LastFailureAt = time.UnixMilli(lastFailureAt)
+	f.LockedUntil = time.UnixMilli(lockedUntil)
+	return &f, nil
+}
+
+// RecordLoginFailure atomically counts a failed attempt. Counting starts over
+// if the previous failure was before resetBefore.
+func (s *TursoStore) RecordLoginFailure(key string, resetBefore time.Time) (*models.LoginFailure, error) {
+	_, err := s.db.Exec(`
+		INSERT INTO login_failures (lockout_key, failures, last_failure_at, locked_until)
+		VALUES (?, 1, ?, 0)
+		ON CONFLICT(lockout_key) DO UPDATE SET
+			failures = CASE WHEN last_failure_at < ? THEN 1 ELSE failures + 1 END,
+			last_failure_at = excluded.last_failure_at
+	`, key, time.Now().UnixMilli(), resetBefore.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	return s.GetLoginFailure(key)
+}
+
+// LockLogin rejects logins for a key until the given time
+func (s *TursoStore) LockLogin(key string, until time.Time) error {
+	_, err := s.db.Exec("UPDATE login_failures SET locked_until = ? WHERE lockout_key = ?", until.UnixMilli(), key)
+	return err
+}
+
+// ClearLoginFailure forgets failed attempts for a key after a successful login
+func (s *TursoStore) ClearLoginFailure(key string) error {
+	_, err := s.db.Exec("DELETE FROM login_failures WHERE lockout_key = ?", key)
+	return err
+}
+
+// RevokeSessions records a revocation of signed session tokens, pruning
+// revocations whose tokens have expired anyway
+func (s *TursoStore) RevokeSessions(revocation *models.SessionRevocation) error {
+	if revocation.RevokedAt.IsZero() {
+		revocation.RevokedAt = time.Now()
+	}
+	if _, err := s.db.Exec("DELETE FROM session_revocations WHERE expires_at <= ?", time.Now().UnixMilli()); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO session_revocations (id, token_id, user_id, revoked_at, expires_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			revoked_at = excluded.revoked_at,
+			expires_at = MAX(expires_at, excluded.expires_at)
+	`, revocationID(revocation), revocation.TokenID, revocation.UserID,
+		revocation.RevokedAt.UnixMilli(), revocation.ExpiresAt.UnixMilli())
+	return err
+}
+
+// ListSessionRevocations returns revocations that still cover unexpired tokens
+func (s *TursoStore) ListSessionRevocations() ([]models.SessionRevocation, error) {
+	rows, err := s.db.Query(`
+		SELECT token_id, user_id, revoked_at, expires_at
+		FROM session_revocations
+		WHERE expires_at > ?
+	`, time.Now().UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revocations := []models.SessionRevocation{}
+	for rows.Next() {
+		var r models.SessionRevocation
+		var revokedAt, expiresAt int64
+		if err := rows.Scan(&r.TokenID, &r.UserID, &revokedAt, &expiresAt); err != nil {
+			return nil, err
+		}
+		r.RevokedAt = time.UnixMilli(revokedAt)
+		r.ExpiresAt = time.UnixMilli(expiresAt)
+		revocations = append(revocations, r)
+	}
+
+	return revocations, rows.Err()
+}
+
+// ListProfileSummaries returns per-profile counts and last sync times
+func (s *TursoStore) ListProfileSummaries() ([]models.ProfileSummary, error) {
+	rows, err := s.db.Query(`
+		SELECT p.user_id,
+			(SELECT COUNT(*) FROM workouts w WHERE w.user_id = p.user_id AND w.deleted_at IS NULL),
+			(SELECT COUNT(*) FROM completions c WHERE c.user_id = p.user_id AND c.deleted_at IS NULL),
+			(SELECT COUNT(*) FROM sessions s WHERE s.user_id = p.user_id),
+			COALESCE((SELECT last_sync_time FROM sync_metadata m WHERE m.user_id = p.user_id), 0)
+		FROM (
+			SELECT user_id FROM workouts
+			UNION
+			SELECT user_id FROM completions
+			UNION
+			SELECT user_id FROM sync_metadata
+			UNION
+			SELECT user_id FROM sessions
+		) p
+		ORDER BY p.user_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := []models.ProfileSummary{}
+	for rows.Next() {
+		var p models.ProfileSummary
+		if err := rows.Scan(&p.UserID, &p.Workouts, &p.Completions, &p.Sessions, &p.LastSyncTime); err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, p)
+	}
+
+	return summaries, rows.Err()
+}
+
+// ListSessions returns sessions for a user, or all sessions if userID is empty
+func (s *TursoStore) ListSessions(userID string) ([]models.Session, error) {
+	rows, err := s.db.Query(`
+		SELECT token, user_id, created_at
+		FROM sessions
+		WHERE ? = '' OR user_id = ?
+		ORDER BY created_at DESC
+	`, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		var session models.Session
+		var createdAtStr string
+		if err := rows.Scan(&session.Token, &session.UserID, &createdAtStr); err != nil {
+			return nil, err
+		}
+		session.CreatedAt, _ = time.Parse(time.RFC3339, createdAtStr)
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// DeleteUserSessions deletes all sessions of a user
+func (s *TursoStore) DeleteUserSessions(userID string) (int64, error) {
+	res, err := s.db.Exec("DELETE FROM sessions WHERE user_id = ?", userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// GetStorageUsage returns row counts per table and the database size
+func (s *TursoStore) GetStorageUsage() (*models.StorageUsage, error) {
+	usage := &models.StorageUsage{Tables: make(map[string]int64)}
+	for _, table := range storageTables {
+		var count int64
+		if err := s.db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&count); err != nil {
+			return nil, err
+		}
+		usage.Tables[table] = count
+	}
+
+	// Size is best-effort; not every backend exposes page pragmas
+	s.db.QueryRow(
+		"SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()",
+	).Scan(&usage.SizeBytes)
+
+	return usage, nil
+}
+
+// PurgeDeleted permanently removes workouts and completions soft-deleted before a time
+func (s *TursoStore) PurgeDeleted(before time.Time) (int64, int64, error) {
+	cutoff := before.Format(time.RFC3339)
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		DELETE FROM workout_intervals WHERE workout_id IN (
+			SELECT id FROM workouts WHERE deleted_at IS NOT NULL AND deleted_at < ?
+		)
+	`, cutoff)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM workout_revisions WHERE workout_id IN (
+			SELECT id FROM workouts WHERE deleted_at IS NOT NULL AND deleted_at < ?
+		)
+	`, cutoff)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	res, err := tx.Exec("DELETE FROM workouts WHERE deleted_at IS NOT NULL AND deleted_at < ?", cutoff)
+	if err != nil {
+		return 0, 0, err
+	}
+	workouts, _ := res.RowsAffected()
+
+	res, err = tx.Exec("DELETE FROM completions WHERE deleted_at IS NOT NULL AND deleted_at < ?", cutoff)
+	if err != nil {
+		return 0, 0, err
+	}
+	completions, _ := res.RowsAffected()
+
+	// Drop workout snapshots no longer referenced by any completion
+	_, err = tx.Exec(`
+		DELETE FROM workout_snapshots WHERE NOT EXISTS (
+			SELECT 1 FROM completions
+			WHERE completions.user_id = workout_snapshots.user_id
+			AND completions.snapshot_hash = workout_snapshots.hash
+		)
+	`)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return workouts, completions, tx.Commit()
+}
+
+// Backup is not supported; Turso manages backups and point-in-time recovery
+func (s *TursoStore) Backup(path string) error {
+	return ErrNotSupported
+}
+
+// AddAuditEntry records an administrative action
+func (s *TursoStore) AddAuditEntry(entry *models.AuditEntry) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	res, err := s.db.Exec(`
+		INSERT INTO admin_audit (action, target, detail, remote_addr, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, entry.Action, entry.Target, entry.Detail, entry.RemoteAddr, entry.CreatedAt.Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	entry.ID, _ = res.LastInsertId()
+	return nil
+}
+
+// ListAuditEntries returns the most recent administrative actions
+func (s *TursoStore) ListAuditEntries(limit int) ([]models.AuditEntry, error) {
+	rows, err := s.db.Query(`
+		SELECT id, action, target, detail, remote_addr, created_at
+		FROM admin_audit
+		ORDER BY id DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var e models.AuditEntry
+		var createdAtStr string
+		if err := rows.Scan(&e.ID, &e.Action, &e.Target, &e.Detail, &e.RemoteAddr, &createdAtStr); err != nil {
+			return nil, err
+		}
+		e.CreatedAt, _ = time.Parse(time.RFC3339, createdAtStr)
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// UpsertWorkout inserts or updates a workout and reports whether it
+// changed. The version is only bumped when the content or deleted state
+// differs from what is stored.
+func (s *TursoStore) UpsertWorkout(workout *models.Workout) (bool, error) {
+	blocks, err := encodeBlocks(workout.Blocks)
+	if err != nil {
+		return false, err
+	}
+	pomodoro, err := encodePomodoro(workout.Pomodoro)
+	if err != nil {
+		return false, err
+	}
+	contentHash, err := workoutContentHash(workout)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	previous, err := workoutVersion(tx, workout.UserID, workout.ID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return false, err
+	}
+
 	var deletedAtStr *string
 	if workout.DeletedAt != nil {
 		s := workout.DeletedAt.Format(time.RFC3339)
@@ -177,139 +923,408 @@ func (s *TursoStore) UpsertWorkout(workout *models.Workout) error {
 	}
 
 	// Upsert workout
-	_, err = tx.Exec(`
-		INSERT INTO workouts (id, user_id, name, rounds, created_at, updated_at, deleted_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+	res, err := tx.Exec(`
+		INSERT INTO workouts (id, user_id, name, rounds, blocks, kind, period, time_cap, pomodoro, content_hash, created_at, updated_at, deleted_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(id) DO UPDATE SET
 			name = excluded.name,
 			rounds = excluded.rounds,
+			blocks = excluded.blocks,
+			kind = excluded.kind,
+			period = excluded.period,
+			time_cap = excluded.time_cap,
+			pomodoro = excluded.pomodoro,
 			updated_at = excluded.updated_at,
-			deleted_at = excluded.deleted_at
-	`, workout.ID, workout.UserID, workout.Name, workout.Rounds,
+			content_hash = excluded.content_hash,
+			deleted_at = excluded.deleted_at,
+			version = CASE
+				WHEN workouts.content_hash IS excluded.content_hash
+					AND (workouts.deleted_at IS NULL) = (excluded.deleted_at IS NULL)
+				THEN workouts.version
+				ELSE workouts.version + 1
+			END
+		WHERE workouts.user_id = excluded.user_id
+	`, workout.ID, workout.UserID, workout.Name, workout.Rounds, blocks, workout.Kind, workout.Period, workout.TimeCap, pomodoro, contentHash,
 		workout.CreatedAt.Format(time.RFC3339),
 		workout.UpdatedAt.Format(time.RFC3339),
 		deletedAtStr)
 	if err != nil {
+		return false, err
+	}
+	if err := requireAffected(res); err != nil {
+		return false, err
+	}
+
+	if err := replaceIntervals(tx, workout.UserID, workout.ID, storedIntervals(workout)); err != nil {
+		return false, err
+	}
+	if workout.Version, err = workoutVersion(tx, workout.UserID, workout.ID); err != nil {
+		return false, err
+	}
+	finishWorkout(workout)
+
+	return workout.Version != previous, tx.Commit()
+}
+
+// UpdateWorkout replaces the fields and intervals of a live workout. A
+// non-zero ifVersion must match the stored version, or ErrVersionConflict is
+// returned and nothing is written.
+func (s *TursoStore) UpdateWorkout(workout *models.Workout, ifVersion int64) error {
+	blocks, err := encodeBlocks(workout.Blocks)
+	if err != nil {
+		return err
+	}
+	pomodoro, err := encodePomodoro(workout.Pomodoro)
+	if err != nil {
+		return err
+	}
+	contentHash, err := workoutContentHash(workout)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+		UPDATE workouts
+		SET name = ?, rounds = ?, blocks = ?, kind = ?, period = ?, time_cap = ?, pomodoro = ?, content_hash = ?, updated_at = ?, version = version + 1
+		WHERE id = ? AND user_id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)
+	`, workout.Name, workout.Rounds, blocks, workout.Kind, workout.Period, workout.TimeCap, pomodoro, contentHash, workout.UpdatedAt.Format(time.RFC3339),
+		workout.ID, workout.UserID, ifVersion, ifVersion)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(res); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return workoutWriteError(tx, workout.UserID, workout.ID)
+		}
+		return err
+	}
+
+	if err := replaceIntervals(tx, workout.UserID, workout.ID, storedIntervals(workout)); err != nil {
+		return err
+	}
+	if workout.Version, err = workoutVersion(tx, workout.UserID, workout.ID); err != nil {
+		return err
+	}
+	finishWorkout(workout)
+
+	return tx.Commit()
+}
+
+// GetWorkoutsModifiedSince returns workouts modified after a timestamp
+func (s *TursoStore) GetWorkoutsModifiedSince(userID string, since int64) ([]models.Workout, error) {
+	sinceTime := time.UnixMilli(since).Format(time.RFC3339)
+	rows, err := s.db.Query(`
+		SELECT id, user_id, name, rounds, version, blocks, kind, period, time_cap, pomodoro, created_at, updated_at, deleted_at
+		FROM workouts
+		WHERE user_id = ? AND updated_at > ?
+		ORDER BY updated_at DESC
+	`, userID, sinceTime)
+	if err != nil {
+		return nil, err
+	}
+
+	// First, collect all workouts without intervals
+	var workouts []models.Workout
+	for rows.Next() {
+		var w models.Workout
+		var createdAtStr, updatedAtStr string
+		var blocks, pomodoro sql.NullString
+		var deletedAtStr *string
+		err := rows.Scan(&w.ID, &w.UserID, &w.Name, &w.Rounds, &w.Version, &blocks, &w.Kind, &w.Period, &w.TimeCap, &pomodoro, &createdAtStr, &updatedAtStr, &deletedAtStr)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		w.CreatedAt, _ = time.Parse(time.RFC3339, createdAtStr)
+		w.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAtStr)
+		w.Blocks, _ = decodeBlocks(blocks)
+		w.Pomodoro, _ = decodePomodoro(pomodoro)
+		if deletedAtStr != nil {
+			deletedAt, _ := time.Parse(time.RFC3339, *deletedAtStr)
+			w.DeletedAt = &deletedAt
+		}
+
+		workouts = append(workouts, w)
+	}
+	rows.Close()
+
+	// Now load intervals for each workout (after closing the first result set)
+	for i := range workouts {
+		intervals, err := s.getIntervals(workouts[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		workouts[i].Intervals = intervals
+		finishWorkout(&workouts[i])
+	}
+
+	return workouts, nil
+}
+
+// GetWorkout returns a single workout by ID
+func (s *TursoStore) GetWorkout(userID string, workoutID string) (*models.Workout, error) {
+	var w models.Workout
+	var createdAtStr, updatedAtStr string
+	var blocks, pomodoro sql.NullString
+	var deletedAtStr *string
+	err := s.db.QueryRow(`
+		SELECT id, user_id, name, rounds, version, blocks, kind, period, time_cap, pomodoro, created_at, updated_at, deleted_at
+		FROM workouts
+		WHERE id = ? AND user_id = ? AND deleted_at IS NULL
+	`, workoutID, userID).Scan(&w.ID, &w.UserID, &w.Name, &w.Rounds, &w.Version, &blocks, &w.Kind, &w.Period, &w.TimeCap, &pomodoro, &createdAtStr, &updatedAtStr, &deletedAtStr)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("workout not found")
+		}
+		return nil, err
+	}
+
+	w.CreatedAt, _ = time.Parse(time.RFC3339, createdAtStr)
+	w.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAtStr)
+	w.Blocks, _ = decodeBlocks(blocks)
+	w.Pomodoro, _ = decodePomodoro(pomodoro)
+
+	intervals, err := s.getIntervals(w.ID)
+	if err != nil {
+		return nil, err
+	}
+	w.Intervals = intervals
+	finishWorkout(&w)
+
+	return &w, nil
+}
+
+// ListWorkouts returns a page of live workouts and the total number matching
+func (s *TursoStore) ListWorkouts(userID string, opts models.WorkoutListOptions) ([]models.Workout, int, error) {
+	where := "user_id = ? AND deleted_at IS NULL"
+	args := []interface{}{userID}
+	if opts.Query != "" {
+		where += " AND name LIKE ? ESCAPE '\\'"
+		args = append(args, likePattern(opts.Query))
+	}
+	if !opts.UpdatedAfter.IsZero() {
+		where += " AND updated_at > ?"
+		args = append(args, opts.UpdatedAfter.Format(time.RFC3339))
+	}
+
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM workouts WHERE "+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT id, user_id, name, rounds, version, blocks, kind, period, time_cap, pomodoro, created_at, updated_at
+		FROM workouts
+		WHERE `+where+`
+		ORDER BY `+workoutOrderBy(opts.Sort)+`
+		LIMIT ? OFFSET ?
+	`, append(args, opts.Limit, opts.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	workouts := []models.Workout{}
+	for rows.Next() {
+		var w models.Workout
+		var createdAtStr, updatedAtStr string
+		var blocks, pomodoro sql.NullString
+		if err := rows.Scan(&w.ID, &w.UserID, &w.Name, &w.Rounds, &w.Version, &blocks, &w.Kind, &w.Period, &w.TimeCap, &pomodoro, &createdAtStr, &updatedAtStr); err != nil {
+			rows.Close()
+			return nil, 0, err
+		}
+		w.CreatedAt, _ = time.Parse(time.RFC3339, createdAtStr)
+		w.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAtStr)
+		w.Blocks, _ = decodeBlocks(blocks)
+		w.Pomodoro, _ = decodePomodoro(pomodoro)
+		workouts = append(workouts, w)
+	}
+	rows.Close()
+
+	// Load intervals after closing the first result set
+	for i := range workouts {
+		intervals, err := s.getIntervals(workouts[i].ID)
+		if err != nil {
+			return nil, 0, err
+		}
+		workouts[i].Intervals = intervals
+		finishWorkout(&workouts[i])
+	}
+
+	return workouts, total, nil
+}
+
+// DeleteWorkout soft-deletes a workout. A non-zero ifVersion must match the
+// stored version, or ErrVersionConflict is returned and nothing is deleted.
+func (s *TursoStore) DeleteWorkout(userID string, workoutID string, ifVersion int64) error {
+	now := time.Now().Format(time.RFC3339)
+	if ifVersion == 0 {
+		_, err := s.db.Exec(`
+			UPDATE workouts
+			SET deleted_at = ?, updated_at = ?, version = version + 1
+			WHERE id = ? AND user_id = ?
+		`, now, now, workoutID, userID)
 		return err
 	}
 
-	// Delete existing intervals
-	_, err = tx.Exec("DELETE FROM workout_intervals WHERE workout_id = ?", workout.ID)
+	tx, err := s.db.Begin()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	// Insert new intervals with position
-	for i, interval := range workout.Intervals {
-		position := interval.Position
-		if position == 0 && i > 0 {
-			position = i
-		}
-		_, err = tx.Exec(`
-			INSERT INTO workout_intervals (id, workout_id, name, duration, color, position)
-			VALUES (?, ?, ?, ?, ?, ?)
-		`, interval.ID, workout.ID, interval.Name, interval.Duration, interval.Color, position)
-		if err != nil {
-			return err
+	res, err := tx.Exec(`
+		UPDATE workouts
+		SET deleted_at = ?, updated_at = ?, version = version + 1
+		WHERE id = ? AND user_id = ? AND deleted_at IS NULL AND version = ?
+	`, now, now, workoutID, userID, ifVersion)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(res); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return workoutWriteError(tx, userID, workoutID)
 		}
+		return err
 	}
-
 	return tx.Commit()
 }
 
-// GetWorkoutsModifiedSince returns workouts modified after a timestamp
-func (s *TursoStore) GetWorkoutsModifiedSince(userID string, since int64) ([]models.Workout, error) {
-	sinceTime := time.UnixMilli(since).Format(time.RFC3339)
+// ListDeletedWorkouts returns a profile's soft-deleted workouts that have not
+// been purged yet, most recently deleted first
+func (s *TursoStore) ListDeletedWorkouts(userID string) ([]models.Workout, error) {
 	rows, err := s.db.Query(`
-		SELECT id, user_id, name, rounds, created_at, updated_at, deleted_at
+		SELECT id, user_id, name, rounds, version, blocks, kind, period, time_cap, pomodoro, created_at, updated_at, deleted_at
 		FROM workouts
-		WHERE user_id = ? AND updated_at > ?
-		ORDER BY updated_at DESC
-	`, userID, sinceTime)
+		WHERE user_id = ? AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id
+	`, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	// First, collect all workouts without intervals
-	var workouts []models.Workout
+	workouts := []models.Workout{}
 	for rows.Next() {
 		var w models.Workout
 		var createdAtStr, updatedAtStr string
+		var blocks, pomodoro sql.NullString
 		var deletedAtStr *string
-		err := rows.Scan(&w.ID, &w.UserID, &w.Name, &w.Rounds, &createdAtStr, &updatedAtStr, &deletedAtStr)
-		if err != nil {
+		if err := rows.Scan(&w.ID, &w.UserID, &w.Name, &w.Rounds, &w.Version, &blocks, &w.Kind, &w.Period, &w.TimeCap, &pomodoro, &createdAtStr, &updatedAtStr, &deletedAtStr); err != nil {
 			rows.Close()
 			return nil, err
 		}
-
 		w.CreatedAt, _ = time.Parse(time.RFC3339, createdAtStr)
 		w.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAtStr)
+		w.Blocks, _ = decodeBlocks(blocks)
+		w.Pomodoro, _ = decodePomodoro(pomodoro)
 		if deletedAtStr != nil {
 			deletedAt, _ := time.Parse(time.RFC3339, *deletedAtStr)
 			w.DeletedAt = &deletedAt
 		}
-
 		workouts = append(workouts, w)
 	}
 	rows.Close()
 
-	// Now load intervals for each workout (after closing the first result set)
+	// Load intervals after closing the first result set
 	for i := range workouts {
 		intervals, err := s.getIntervals(workouts[i].ID)
 		if err != nil {
 			return nil, err
 		}
 		workouts[i].Intervals = intervals
+		finishWorkout(&workouts[i])
 	}
 
 	return workouts, nil
 }
 
-// GetWorkout returns a single workout by ID
-func (s *TursoStore) GetWorkout(userID string, workoutID string) (*models.Workout, error) {
-	var w models.Workout
-	var createdAtStr, updatedAtStr string
-	var deletedAtStr *string
-	err := s.db.QueryRow(`
-		SELECT id, user_id, name, rounds, created_at, updated_at, deleted_at
-		FROM workouts
-		WHERE id = ? AND user_id = ? AND deleted_at IS NULL
-	`, workoutID, userID).Scan(&w.ID, &w.UserID, &w.Name, &w.Rounds, &createdAtStr, &updatedAtStr, &deletedAtStr)
+// RestoreWorkout undeletes a soft-deleted workout. updated_at is bumped so the
+// restore reaches other devices on their next sync.
+func (s *TursoStore) RestoreWorkout(userID string, workoutID string) error {
+	res, err := s.db.Exec(`
+		UPDATE workouts
+		SET deleted_at = NULL, updated_at = ?, version = version + 1
+		WHERE id = ? AND user_id = ? AND deleted_at IS NOT NULL
+	`, time.Now().Format(time.RFC3339), workoutID, userID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
 
+// AddWorkoutRevision records a workout's content after a change
+func (s *TursoStore) AddWorkoutRevision(rev *models.WorkoutRevision) error {
+	if rev.CreatedAt.IsZero() {
+		rev.CreatedAt = time.Now()
+	}
+	return addWorkoutRevision(s.db, rev, rev.CreatedAt.Format(time.RFC3339))
+}
+
+// ListWorkoutRevisions returns a workout's revisions, newest first
+func (s *TursoStore) ListWorkoutRevisions(userID string, workoutID string) ([]models.WorkoutRevision, error) {
+	rows, err := s.db.Query(`
+		SELECT `+workoutRevisionColumns+`
+		FROM workout_revisions
+		WHERE user_id = ? AND workout_id = ?
+		ORDER BY version DESC
+	`, userID, workoutID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("workout not found")
-		}
 		return nil, err
 	}
+	defer rows.Close()
 
-	w.CreatedAt, _ = time.Parse(time.RFC3339, createdAtStr)
-	w.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAtStr)
+	revisions := []models.WorkoutRevision{}
+	for rows.Next() {
+		rev, err := scanTursoWorkoutRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+	return revisions, rows.Err()
+}
 
-	intervals, err := s.getIntervals(w.ID)
+// GetWorkoutRevision returns the revision saved as a workout version
+func (s *TursoStore) GetWorkoutRevision(userID string, workoutID string, version int64) (*models.WorkoutRevision, error) {
+	row := s.db.QueryRow(`
+		SELECT `+workoutRevisionColumns+`
+		FROM workout_revisions
+		WHERE user_id = ? AND workout_id = ? AND version = ?
+	`, userID, workoutID, version)
+	rev, err := scanTursoWorkoutRevision(row)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
 		return nil, err
 	}
-	w.Intervals = intervals
-
-	return &w, nil
+	return &rev, nil
 }
 
-// DeleteWorkout soft-deletes a workout
-func (s *TursoStore) DeleteWorkout(userID string, workoutID string) error {
-	now := time.Now().Format(time.RFC3339)
-	_, err := s.db.Exec(`
-		UPDATE workouts
-		SET deleted_at = ?, updated_at = ?
-		WHERE id = ? AND user_id = ?
-	`, now, now, workoutID, userID)
-	return err
+func scanTursoWorkoutRevision(row rowScanner) (models.WorkoutRevision, error) {
+	var rev models.WorkoutRevision
+	var snapshot, createdAtStr string
+	err := row.Scan(&rev.WorkoutID, &rev.UserID, &rev.Version, &rev.Device, &rev.Source, &snapshot, &createdAtStr)
+	if err != nil {
+		return rev, err
+	}
+	rev.CreatedAt, _ = time.Parse(time.RFC3339, createdAtStr)
+	return rev, json.Unmarshal([]byte(snapshot), &rev.Snapshot)
 }
 
 // getIntervals helper to load intervals for a workout
 func (s *TursoStore) getIntervals(workoutID string) ([]models.Interval, error) {
 	rows, err := s.db.Query(`
-		SELECT id, name, duration, color, position
+		SELECT id, name, duration, color, position,
+			type, notes, target_reps, target_hr_zone, exercise_ref, progression, condition,
+			manual, time_cap
 		FROM workout_intervals
 		WHERE workout_id = ?
 		ORDER BY position ASC
@@ -322,17 +1337,27 @@ func (s *TursoStore) getIntervals(workoutID string) ([]models.Interval, error) {
 	var intervals []models.Interval
 	for rows.Next() {
 		var i models.Interval
-		err := rows.Scan(&i.ID, &i.Name, &i.Duration, &i.Color, &i.Position)
+		var progression, condition sql.NullString
+		err := rows.Scan(&i.ID, &i.Name, &i.Duration, &i.Color, &i.Position,
+			&i.Type, &i.Notes, &i.TargetReps, &i.TargetHRZone, &i.ExerciseRef, &progression, &condition,
+			&i.Manual, &i.TimeCap)
 		if err != nil {
 			return nil, err
 		}
+		if i.Progression, err = decodeProgression(progression); err != nil {
+			return nil, err
+		}
+		if i.Condition, err = decodeCondition(condition); err != nil {
+			return nil, err
+		}
 		intervals = append(intervals, i)
 	}
 
 	return intervals, rows.Err()
 }
 
-// UpsertCompletion inserts or updates a completion record
+// UpsertCompletion inserts or updates a completion. A snapshot is stored the
+// first time one is given and kept unchanged afterwards.
 func (s *TursoStore) UpsertCompletion(completion *models.Completion) error {
 	var completedAtStr, deletedAtStr *string
 	if completion.CompletedAt != nil {
@@ -344,29 +1369,68 @@ func (s *TursoStore) UpsertCompletion(completion *models.Completion) error {
 		deletedAtStr = &s
 	}
 
-	_, err := s.db.Exec(`
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var snapshotHash *string
+	if completion.Snapshot != nil {
+		hash, err := saveWorkoutSnapshot(tx, completion.UserID, completion.Snapshot, time.Now().Format(time.RFC3339))
+		if err != nil {
+			return err
+		}
+		snapshotHash = &hash
+	}
+
+	timings, err := encodeTimings(completion.IntervalTimings)
+	if err != nil {
+		return err
+	}
+	score, err := encodeScore(completion.Score)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(`
 		INSERT INTO completions
-		(id, user_id, workout_id, workout_name, total_duration, elapsed_duration, completed, started_at, completed_at, updated_at, deleted_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		(id, user_id, workout_id, workout_name, total_duration, elapsed_duration, completed, started_at, completed_at, updated_at, deleted_at, snapshot_hash, interval_timings, score, focus_seconds, interruptions, project_id, task_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(id) DO UPDATE SET
 			elapsed_duration = excluded.elapsed_duration,
 			completed = excluded.completed,
 			completed_at = excluded.completed_at,
 			updated_at = excluded.updated_at,
-			deleted_at = excluded.deleted_at
+			deleted_at = excluded.deleted_at,
+			snapshot_hash = COALESCE(completions.snapshot_hash, excluded.snapshot_hash),
+			interval_timings = COALESCE(excluded.interval_timings, completions.interval_timings),
+			score = COALESCE(excluded.score, completions.score),
+			focus_seconds = excluded.focus_seconds,
+			interruptions = excluded.interruptions,
+			project_id = COALESCE(NULLIF(excluded.project_id, ''), completions.project_id),
+			task_id = COALESCE(NULLIF(excluded.task_id, ''), completions.task_id)
+		WHERE completions.user_id = excluded.user_id
 	`, completion.ID, completion.UserID, completion.WorkoutID, completion.WorkoutName,
 		completion.TotalDuration, completion.ElapsedDuration, completion.Completed,
-		completion.StartedAt.Format(time.RFC3339), completedAtStr,
-		completion.UpdatedAt.Format(time.RFC3339), deletedAtStr)
-	return err
+		completion.StartedAt.UTC().Format(tursoStartedAtLayout), completedAtStr,
+		completion.UpdatedAt.Format(time.RFC3339), deletedAtStr, snapshotHash, timings, score,
+		completion.FocusSeconds, completion.Interruptions, completion.ProjectID, completion.TaskID)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(res); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 // GetCompletionsModifiedSince returns completions modified after a timestamp
 func (s *TursoStore) GetCompletionsModifiedSince(userID string, since int64) ([]models.Completion, error) {
 	sinceTime := time.UnixMilli(since).Format(time.RFC3339)
 	rows, err := s.db.Query(`
-		SELECT id, user_id, workout_id, workout_name, total_duration, elapsed_duration,
-		       completed, started_at, completed_at, updated_at, deleted_at
+		SELECT `+completionColumns+`
 		FROM completions
 		WHERE user_id = ? AND updated_at > ?
 		ORDER BY updated_at DESC
@@ -378,31 +1442,97 @@ func (s *TursoStore) GetCompletionsModifiedSince(userID string, since int64) ([]
 
 	var completions []models.Completion
 	for rows.Next() {
-		var c models.Completion
-		var startedAtStr, updatedAtStr string
-		var completedAtStr, deletedAtStr *string
-		err := rows.Scan(&c.ID, &c.UserID, &c.WorkoutID, &c.WorkoutName,
-			&c.TotalDuration, &c.ElapsedDuration, &c.Completed,
-			&startedAtStr, &completedAtStr, &updatedAtStr, &deletedAtStr)
+		c, _, err := scanTursoCompletion(rows)
 		if err != nil {
 			return nil, err
 		}
+		completions = append(completions, c)
+	}
 
-		c.StartedAt, _ = time.Parse(time.RFC3339, startedAtStr)
-		c.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAtStr)
-		if completedAtStr != nil {
-			completedAt, _ := time.Parse(time.RFC3339, *completedAtStr)
-			c.CompletedAt = &completedAt
-		}
-		if deletedAtStr != nil {
-			deletedAt, _ := time.Parse(time.RFC3339, *deletedAtStr)
-			c.DeletedAt = &deletedAt
+	return completions, rows.Err()
+}
+
+// GetCompletion returns a single completion by ID (excludes soft-deleted)
+func (s *TursoStore) GetCompletion(userID string, completionID string) (*models.Completion, error) {
+	row := s.db.QueryRow(`
+		SELECT `+completionColumns+`
+		FROM completions
+		WHERE id = ? AND user_id = ? AND deleted_at IS NULL
+	`, completionID, userID)
+	c, _, err := scanTursoCompletion(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
 		}
+		return nil, err
+	}
+	return &c, nil
+}
 
+// ListCompletions returns a page of live completions ordered by start time,
+// with a cursor for the next page ("" on the last page)
+func (s *TursoStore) ListCompletions(userID string, opts models.CompletionListOptions) ([]models.Completion, string, error) {
+	where, args, err := completionFilter(userID, opts, func(t time.Time) interface{} { return t.UTC().Format(tursoStartedAtLayout) })
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Fetch one extra row to learn whether there is another page
+	rows, err := s.db.Query(`
+		SELECT `+completionColumns+`
+		FROM completions
+		WHERE `+where+`
+		ORDER BY `+completionOrderBy(opts.Ascending)+`
+		LIMIT ?
+	`, append(args, opts.Limit+1)...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	completions := []models.Completion{}
+	var cursor, lastStartedAt string
+	for rows.Next() {
+		c, startedAt, err := scanTursoCompletion(rows)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(completions) == opts.Limit {
+			last := completions[len(completions)-1]
+			cursor = encodeCursor(lastStartedAt, last.ID)
+			break
+		}
 		completions = append(completions, c)
+		lastStartedAt = startedAt
 	}
 
-	return completions, rows.Err()
+	return completions, cursor, rows.Err()
+}
+
+// scanTursoCompletion reads a row selected with completionColumns, also
+// returning the raw started_at value for pagination cursors
+func scanTursoCompletion(row rowScanner) (models.Completion, string, error) {
+	var c models.Completion
+	var startedAtStr, updatedAtStr string
+	var completedAtStr, deletedAtStr *string
+	var snapshotHash, snapshot sql.NullString
+	err := row.Scan(&c.ID, &c.UserID, &c.WorkoutID, &c.WorkoutName,
+		&c.TotalDuration, &c.ElapsedDuration, &c.Completed,
+		&startedAtStr, &completedAtStr, &updatedAtStr, &deletedAtStr, &snapshotHash, &snapshot)
+	if err != nil {
+		return c, "", err
+	}
+	c.StartedAt, _ = time.Parse(time.RFC3339, startedAtStr)
+	c.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAtStr)
+	if completedAtStr != nil {
+		completedAt, _ := time.Parse(time.RFC3339, *completedAtStr)
+		c.CompletedAt = &completedAt
+	}
+	if deletedAtStr != nil {
+		deletedAt, _ := time.Parse(time.RFC3339, *deletedAtStr)
+		c.DeletedAt = &deletedAt
+	}
+	return c, startedAtStr, decodeSnapshot(&c, snapshotHash, snapshot)
 }
 
 // DeleteCompletion soft-deletes a completion record
@@ -416,6 +1546,241 @@ func (s *TursoStore) DeleteCompletion(userID string, completionID string) error
 	return err
 }
 
+// ListDeletedCompletions returns a profile's soft-deleted completions that
+// have not been purged yet, most recently deleted first
+func (s *TursoStore) ListDeletedCompletions(userID string) ([]models.Completion, error) {
+	rows, err := s.db.Query(`
+		SELECT `+completionColumns+`
+		FROM completions
+		WHERE user_id = ? AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	completions := []models.Completion{}
+	for rows.Next() {
+		c, _, err := scanTursoCompletion(rows)
+		if err != nil {
+			return nil, err
+		}
+		completions = append(completions, c)
+	}
+	return completions, rows.Err()
+}
+
+// RestoreCompletion undeletes a soft-deleted completion. updated_at is bumped
+// so the restore reaches other devices on their next sync.
+func (s *TursoStore) RestoreCompletion(userID string, completionID string) error {
+	res, err := s.db.Exec(`
+		UPDATE completions
+		SET deleted_at = NULL, updated_at = ?
+		WHERE id = ? AND user_id = ? AND deleted_at IS NOT NULL
+	`, time.Now().Format(time.RFC3339), completionID, userID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// TagCompletion sets the project and task of a completion; empty values
+// clear them, which syncing a completion without tags never does
+func (s *TursoStore) TagCompletion(userID string, completionID string, projectID string, taskID string, updatedAt time.Time) error {
+	res, err := s.db.Exec(`
+		UPDATE completions
+		SET project_id = ?, task_id = ?, updated_at = ?
+		WHERE id = ? AND user_id = ? AND deleted_at IS NULL
+	`, projectID, taskID, updatedAt.Format(time.RFC3339), completionID, userID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// CreateProject stores a new project
+func (s *TursoStore) CreateProject(project *models.Project) error {
+	_, err := s.db.Exec(`
+		INSERT INTO projects (id, user_id, name, color, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, project.ID, project.UserID, project.Name, project.Color,
+		project.CreatedAt.Format(time.RFC3339), project.UpdatedAt.Format(time.RFC3339))
+	return err
+}
+
+// UpdateProject saves a project's name and color
+func (s *TursoStore) UpdateProject(project *models.Project) error {
+	res, err := s.db.Exec(`
+		UPDATE projects SET name = ?, color = ?, updated_at = ?
+		WHERE id = ? AND user_id = ?
+	`, project.Name, project.Color, project.UpdatedAt.Format(time.RFC3339), project.ID, project.UserID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// GetProject returns a single project
+func (s *TursoStore) GetProject(userID string, projectID string) (*models.Project, error) {
+	p, err := scanTursoProject(s.db.QueryRow(`
+		SELECT id, user_id, name, color, created_at, updated_at
+		FROM projects
+		WHERE id = ? AND user_id = ?
+	`, projectID, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ListProjects returns a profile's projects by name
+func (s *TursoStore) ListProjects(userID string) ([]models.Project, error) {
+	rows, err := s.db.Query(`
+		SELECT id, user_id, name, color, created_at, updated_at
+		FROM projects
+		WHERE user_id = ?
+		ORDER BY name COLLATE NOCASE, id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	projects := []models.Project{}
+	for rows.Next() {
+		p, err := scanTursoProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, p)
+	}
+	return projects, rows.Err()
+}
+
+// DeleteProject removes a project. Its tasks are kept without a project;
+// completions keep referring to it.
+func (s *TursoStore) DeleteProject(userID string, projectID string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("DELETE FROM projects WHERE id = ? AND user_id = ?", projectID, userID)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(res); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		UPDATE tasks SET project_id = '', updated_at = ?
+		WHERE project_id = ? AND user_id = ?
+	`, time.Now().Format(time.RFC3339), projectID, userID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func scanTursoProject(row rowScanner) (models.Project, error) {
+	var p models.Project
+	var createdAtStr, updatedAtStr string
+	if err := row.Scan(&p.ID, &p.UserID, &p.Name, &p.Color, &createdAtStr, &updatedAtStr); err != nil {
+		return p, err
+	}
+	p.CreatedAt, _ = time.Parse(time.RFC3339, createdAtStr)
+	p.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAtStr)
+	return p, nil
+}
+
+// CreateTask stores a new task
+func (s *TursoStore) CreateTask(task *models.Task) error {
+	_, err := s.db.Exec(`
+		INSERT INTO tasks (id, user_id, project_id, name, done, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, task.ID, task.UserID, task.ProjectID, task.Name, task.Done,
+		task.CreatedAt.Format(time.RFC3339), task.UpdatedAt.Format(time.RFC3339))
+	return err
+}
+
+// UpdateTask saves a task's project, name and state
+func (s *TursoStore) UpdateTask(task *models.Task) error {
+	res, err := s.db.Exec(`
+		UPDATE tasks SET project_id = ?, name = ?, done = ?, updated_at = ?
+		WHERE id = ? AND user_id = ?
+	`, task.ProjectID, task.Name, task.Done, task.UpdatedAt.Format(time.RFC3339), task.ID, task.UserID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// GetTask returns a single task
+func (s *TursoStore) GetTask(userID string, taskID string) (*models.Task, error) {
+	t, err := scanTursoTask(s.db.QueryRow(`
+		SELECT id, user_id, project_id, name, done, created_at, updated_at
+		FROM tasks
+		WHERE id = ? AND user_id = ?
+	`, taskID, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+// ListTasks returns a profile's tasks, oldest first, optionally only those
+// of one project
+func (s *TursoStore) ListTasks(userID string, projectID string) ([]models.Task, error) {
+	rows, err := s.db.Query(`
+		SELECT id, user_id, project_id, name, done, created_at, updated_at
+		FROM tasks
+		WHERE user_id = ? AND (? = '' OR project_id = ?)
+		ORDER BY created_at, id
+	`, userID, projectID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := []models.Task{}
+	for rows.Next() {
+		t, err := scanTursoTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
+
+// DeleteTask removes a task; completions keep referring to it
+func (s *TursoStore) DeleteTask(userID string, taskID string) error {
+	res, err := s.db.Exec("DELETE FROM tasks WHERE id = ? AND user_id = ?", taskID, userID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+func scanTursoTask(row rowScanner) (models.Task, error) {
+	var t models.Task
+	var createdAtStr, updatedAtStr string
+	if err := row.Scan(&t.ID, &t.UserID, &t.ProjectID, &t.Name, &t.Done, &createdAtStr, &updatedAtStr); err != nil {
+		return t, err
+	}
+	t.CreatedAt, _ = time.Parse(time.RFC3339, createdAtStr)
+	t.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAtStr)
+	return t, nil
+}
+
 // GetLastSyncTime returns the last sync timestamp for a user
 func (s *TursoStore) GetLastSyncTime(userID string) (int64, error) {
 	var syncTime int64
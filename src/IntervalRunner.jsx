@@ -38,6 +38,12 @@ const IntervalRunner = () => {
         completed: false,
         startedAt: Date.now(),
         completedAt: null,
+        // What was actually performed, kept even if the workout changes later
+        snapshot: {
+          name: workout.name,
+          rounds: workout.rounds,
+          intervals: workout.intervals,
+        },
       };
       setCompletions(prev => [completion, ...prev]);
 
@@ -14,6 +14,23 @@ class SyncService {
     this.lastSyncTime = 0;
     this.syncTimeout = null;
     this.onAuthExpired = null; // Callback for auth expiry
+    // Shown in workout revision history; override with localStorage.syncDeviceName
+    this.deviceName = localStorage.getItem('syncDeviceName') || this.guessDeviceName();
+  }
+
+  // Best-effort device description from the user agent
+  guessDeviceName() {
+    const ua = navigator.userAgent || '';
+    const devices = [
+      [/iPhone/, 'iPhone'],
+      [/iPad/, 'iPad'],
+      [/Android/, 'Android'],
+      [/Macintosh/, 'Mac'],
+      [/Windows/, 'Windows'],
+      [/Linux/, 'Linux'],
+    ];
+    const match = devices.find(([pattern]) => pattern.test(ua));
+    return match ? match[1] : '';
   }
 
   // Hash a string using SHA-256
@@ -181,6 +198,7 @@ class SyncService {
         headers: {
           'Content-Type': 'application/json',
           'Authorization': `Bearer ${this.token}`,
+          ...(this.deviceName && { 'X-Device-Name': this.deviceName }),
         },
         body: JSON.stringify({
           last_synced_at: this.lastSyncTime,